@@ -0,0 +1,142 @@
+package main
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/internal/job"
+	"basic-go/mybook/pkg/health"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"gorm.io/gorm"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// App 整个应用需要启动的东西
+type App struct {
+	Server *gin.Engine
+	Health *health.Handler
+	Jobs   []*job.Runner
+	// 退出之前要 Shutdown，把还没上报的 span 发出去
+	TracerProvider *sdktrace.TracerProvider
+	DB             *gorm.DB
+	Redis          redis.Cmdable
+	L              logger.Logger
+}
+
+// Run 启动后台任务和 HTTP 服务，收到 SIGINT 或者 SIGTERM 之后优雅退出
+func (a *App) Run(cfg config.ServerConfig) error {
+	for _, j := range a.Jobs {
+		j.Start()
+	}
+	srv := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      a.Server,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	a.L.Info("启动 HTTP 服务", logger.String("addr", cfg.Addr))
+	metricsSrv := a.startMetrics(cfg, errCh)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case err := <-errCh:
+		// 端口被占用之类的，没启动起来
+		return errors.Join(err, srv.Close(), closeServer(metricsSrv), a.closeWithTimeout(cfg.CloseTimeout))
+	case sig := <-quit:
+		a.L.Info("收到退出信号", logger.String("signal", sig.String()))
+	}
+	return a.shutdown(srv, metricsSrv, cfg)
+}
+
+// startMetrics /metrics 单独监听一个内部端口，不和业务接口放在一起对外暴露
+func (a *App) startMetrics(cfg config.ServerConfig, errCh chan<- error) *http.Server {
+	if cfg.MetricsAddr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{
+		Addr:              cfg.MetricsAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 5,
+	}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		}
+	}()
+	a.L.Info("启动 metrics 服务", logger.String("addr", cfg.MetricsAddr))
+	return srv
+}
+
+func closeServer(srv *http.Server) error {
+	if srv == nil {
+		return nil
+	}
+	return srv.Close()
+}
+
+func (a *App) shutdown(srv, metricsSrv *http.Server, cfg config.ServerConfig) error {
+	// 先让 /readyz 失败，等 k8s 把这个 pod 从 Service 里摘掉，不然还会有新请求进来
+	a.Health.ShutDown()
+	time.Sleep(cfg.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	// 不再接新的连接，等已经收到的请求处理完
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		a.L.Error("没能在超时之前处理完请求", logger.Error(err))
+	}
+	// 业务请求处理完了再停 metrics，退出过程中还能被抓到
+	return errors.Join(err, closeServer(metricsSrv), a.closeWithTimeout(cfg.CloseTimeout))
+}
+
+// closeWithTimeout close 单独算超时，srv.Shutdown 可能已经把它的时间用完了
+func (a *App) closeWithTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return a.close(ctx)
+}
+
+// close 后台任务还在用 DB 和 Redis，要先停；DB 最后关
+func (a *App) close(ctx context.Context) error {
+	for _, j := range a.Jobs {
+		j.Stop()
+	}
+	var errs []error
+	if err := a.TracerProvider.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if c, ok := a.Redis.(interface{ Close() error }); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if sqlDB, err := a.DB.DB(); err == nil {
+		if err = sqlDB.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	a.L.Info("退出完成")
+	return errors.Join(errs...)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// commands 子命令，例如 mybook migrate up
+// 不带子命令的时候就是启动 web 服务
+var commands = map[string]func(args []string) error{
+	"migrate": migrateCommand,
+	"export":  exportCommand,
+	"import":  importCommand,
+}
+
+func runCommand(args []string) {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的子命令 %s\n", args[0])
+		os.Exit(2)
+	}
+	if err := cmd(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
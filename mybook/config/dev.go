@@ -3,11 +3,104 @@
 // Package config 本地启动的就是去启动docker-compose.yaml这个文件，去里面看配置
 package config
 
+import "time"
+
 var Config = config{
+	Server: ServerConfig{
+		// /metrics 单独一个端口，不经过 ingress 和 Service，只给 Prometheus 抓
+		MetricsAddr: ":9090",
+		Addr:        ":8080",
+		// 上传头像的请求比较大，读写的超时给得宽一点
+		ReadTimeout:   time.Second * 30,
+		WriteTimeout:  time.Second * 30,
+		IdleTimeout:   time.Minute * 2,
+		HealthTimeout: time.Second,
+		// 本地没有 k8s 摘流量，直接开始退出
+		ShutdownDelay:   0,
+		ShutdownTimeout: time.Second * 20,
+		CloseTimeout:    time.Second * 3,
+	},
 	DB: DBConfig{
 		DSN: "root:root@tcp(localhost:13317)/webook",
 	},
 	Redis: RedisConfig{
 		Addr: "localhost:6379",
 	},
+	Storage: StorageConfig{
+		Type: "local",
+		Local: LocalStorageConfig{
+			Dir:     "./data/files",
+			BaseURL: "http://localhost:8080/files",
+			Secret:  "bBx7rYbD5nzTz3PZUz8yDq4FmDHhZ2Wq",
+		},
+		// 换成 s3 就用 docker-compose 里的 MinIO
+		S3: S3StorageConfig{
+			Endpoint:  "localhost:9000",
+			AccessKey: "minioadmin",
+			SecretKey: "minioadmin",
+			Bucket:    "mybook",
+		},
+	},
+	Auth: AuthConfig{
+		Type: "jwt",
+		JWT: JWTConfig{
+			Primary: "dev-ed25519",
+			Keys: []JWTKeyConfig{
+				{ID: "dev-ed25519", Alg: "EdDSA", PrivateKeyFile: "./config/keys/jwt-dev-ed25519.pem"},
+				// 轮换下来的旧 key，还能校验，不会再用来签名
+				{ID: "dev-hs512", Alg: "HS512", Secret: "iF9BZyZtFYktKQtS9bsJAByiT1aVyt06"},
+			},
+			// token 有效期是 30 分钟，再加上滚动发布的时间
+			Legacy:       "dev-hs512",
+			LegacyWindow: time.Hour,
+		},
+		// 浏览器自动升级不会被踢下线
+		Binding:           BindingConfig{Policy: "ua_family"},
+		SessionAuthKey:    "WbeWraNhhon7NxWP7w9WSKMLzZ8cTiwM",
+		SessionEncryptKey: "YHgJ7VQuszth64EuHphVSYVN9SY9NA76",
+	},
+	User: UserConfig{
+		DeactivateGracePeriod: 15 * 24 * time.Hour,
+	},
+	RateLimit: RateLimitConfig{
+		Rules: []RateLimitRule{
+			{Name: "ip", Key: "ip", Algorithm: "sliding_window", Interval: time.Second, Rate: 100},
+			// NAT 后面的用户共用一个 IP，登录之后按用户再限一次
+			{Name: "user", Key: "uid", Algorithm: "token_bucket", Interval: time.Second, Rate: 20, Capacity: 50},
+			{
+				Name: "sms", Paths: []string{"POST /users/login_sms/code/send"}, Key: "ip",
+				Algorithm: "fixed_window", Interval: time.Minute, Rate: 5,
+			},
+		},
+		Degrade: RateLimitDegrade{
+			Policy: "local",
+			// 本地只起一个实例
+			Instances:        1,
+			BreakerThreshold: 5,
+			BreakerCooldown:  time.Second * 10,
+		},
+	},
+	Shedding: SheddingConfig{
+		MaxInFlight:  200,
+		MaxP99:       time.Second,
+		Window:       time.Second * 10,
+		DegradeRatio: 0.8,
+		// 登录是核心链路，过载的时候也要能登录，只是不再自动注册新用户
+		HighPriority: []string{"POST /users/login", "POST /users/login_sms"},
+	},
+	Log: LogConfig{Level: "debug", Development: true},
+	AccessLog: AccessLogConfig{
+		Enabled:     true,
+		ReqBody:     true,
+		RespBody:    true,
+		MaxBodySize: 4096,
+	},
+	Trace: TraceConfig{
+		ServiceName: "mybook",
+		// docker-compose 里的 jaeger，http://localhost:16686 看链路
+		Exporter:    "otlp",
+		Endpoint:    "localhost:4317",
+		Insecure:    true,
+		SampleRatio: 1,
+	},
 }
@@ -2,11 +2,100 @@
 
 package config
 
+import "time"
+
 var Config = config{
+	Server: ServerConfig{
+		// /metrics 单独一个端口，不经过 ingress 和 Service，只给 Prometheus 抓
+		MetricsAddr: ":9090",
+		Addr:        ":8083",
+		// 上传头像的请求比较大，读写的超时给得宽一点
+		ReadTimeout:   time.Second * 30,
+		WriteTimeout:  time.Second * 30,
+		IdleTimeout:   time.Minute * 2,
+		HealthTimeout: time.Second,
+		// 比 readinessProbe 的 periodSeconds * failureThreshold 长一点
+		ShutdownDelay:   time.Second * 5,
+		ShutdownTimeout: time.Second * 20,
+		CloseTimeout:    time.Second * 3,
+	},
 	DB: DBConfig{
 		DSN: "root:root@tcp(10.102.156.176:3308)/mysql",
 	},
 	Redis: RedisConfig{
 		Addr: "mybook-live-redis:6380",
 	},
+	Storage: StorageConfig{
+		Type: "s3",
+		S3: S3StorageConfig{
+			Endpoint:  "mybook-live-minio:9000",
+			AccessKey: "minioadmin",
+			SecretKey: "minioadmin",
+			Bucket:    "mybook",
+		},
+	},
+	Auth: AuthConfig{
+		Type: "jwt",
+		// 私钥在 mybook-jwt-keys 这个 Secret 里，挂载到 /etc/mybook/jwt
+		JWT: JWTConfig{
+			Primary: "k8s-ed25519-1",
+			Keys: []JWTKeyConfig{
+				{ID: "k8s-ed25519-1", Alg: "EdDSA", PrivateKeyFile: "/etc/mybook/jwt/ed25519-1.pem"},
+				// 换成 EdDSA 之前的 key，只用来校验升级之前签的 token
+				{ID: "k8s-hs512-legacy", Alg: "HS512", Secret: "iF9BZyZtFYktKQtS9bsJAByiT1aVyt06"},
+			},
+			// token 有效期是 30 分钟，再加上滚动发布的时间
+			Legacy:       "k8s-hs512-legacy",
+			LegacyWindow: time.Hour,
+		},
+		// 浏览器自动升级不会被踢下线
+		Binding:           BindingConfig{Policy: "ua_family"},
+		SessionAuthKey:    "WbeWraNhhon7NxWP7w9WSKMLzZ8cTiwM",
+		SessionEncryptKey: "YHgJ7VQuszth64EuHphVSYVN9SY9NA76",
+	},
+	User: UserConfig{
+		DeactivateGracePeriod: 15 * 24 * time.Hour,
+	},
+	RateLimit: RateLimitConfig{
+		Rules: []RateLimitRule{
+			{Name: "ip", Key: "ip", Algorithm: "sliding_window", Interval: time.Second, Rate: 100},
+			// NAT 后面的用户共用一个 IP，登录之后按用户再限一次
+			{Name: "user", Key: "uid", Algorithm: "token_bucket", Interval: time.Second, Rate: 20, Capacity: 50},
+			{
+				Name: "sms", Paths: []string{"POST /users/login_sms/code/send"}, Key: "ip",
+				Algorithm: "fixed_window", Interval: time.Minute, Rate: 5,
+			},
+		},
+		Degrade: RateLimitDegrade{
+			Policy: "local",
+			// 和 k8s-mybook-deployment.yaml 里的 replicas 一致
+			Instances:        2,
+			BreakerThreshold: 5,
+			BreakerCooldown:  time.Second * 10,
+		},
+	},
+	Shedding: SheddingConfig{
+		MaxInFlight:  1000,
+		MaxP99:       time.Second,
+		Window:       time.Second * 10,
+		DegradeRatio: 0.8,
+		// 登录是核心链路，过载的时候也要能登录，只是不再自动注册新用户
+		HighPriority: []string{"POST /users/login", "POST /users/login_sms"},
+	},
+	Log: LogConfig{Level: "info"},
+	AccessLog: AccessLogConfig{
+		Enabled: true,
+		// 线上默认只记短信登录的，要看别的接口在后台临时打开
+		ReqBody:     true,
+		RespBody:    true,
+		MaxBodySize: 1024,
+		BodyPaths:   []string{"POST /users/login_sms", "POST /users/login_sms/code/send"},
+	},
+	Trace: TraceConfig{
+		ServiceName: "mybook",
+		Exporter:    "otlp",
+		Endpoint:    "otel-collector:4317",
+		Insecure:    true,
+		SampleRatio: 0.1,
+	},
 }
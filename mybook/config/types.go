@@ -1,8 +1,35 @@
 package config
 
+import "time"
+
 type config struct {
-	DB    DBConfig
-	Redis RedisConfig
+	Server    ServerConfig
+	DB        DBConfig
+	Redis     RedisConfig
+	Storage   StorageConfig
+	Auth      AuthConfig
+	User      UserConfig
+	RateLimit RateLimitConfig
+	Shedding  SheddingConfig
+	Log       LogConfig
+	AccessLog AccessLogConfig
+	Trace     TraceConfig
+}
+
+// ServerConfig 收到 SIGTERM 之后先让 /readyz 返回 503，等 ShutdownDelay 让 k8s 把流量摘掉，
+// 再在 ShutdownTimeout 之内处理完已经收到的请求，最后在 CloseTimeout 之内停后台任务、上报 span、关连接
+type ServerConfig struct {
+	Addr string
+	// 为空的时候不暴露 /metrics
+	MetricsAddr  string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// 就绪检查里每个依赖的超时时间
+	HealthTimeout   time.Duration
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+	CloseTimeout    time.Duration
 }
 
 type DBConfig struct {
@@ -10,5 +37,156 @@ type DBConfig struct {
 }
 
 type RedisConfig struct {
+	// 用 session 的时候可以不配，为空的时候健康检查不看 Redis
 	Addr string
 }
+
+// StorageConfig 对象存储，Type 是 local 或者 s3
+type StorageConfig struct {
+	Type  string
+	Local LocalStorageConfig
+	S3    S3StorageConfig
+}
+
+type LocalStorageConfig struct {
+	// 文件存放的目录
+	Dir string
+	// 签名 URL 的前缀，路径部分会作为下载文件的路由
+	BaseURL string
+	// 签名用的密钥
+	Secret string
+}
+
+type S3StorageConfig struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	UseSSL    bool
+}
+
+type UserConfig struct {
+	// 注销之后的宽限期，宽限期内可以恢复账号，过了之后后台任务会清理掉个人信息
+	DeactivateGracePeriod time.Duration
+}
+
+// AuthConfig 登录态，Type 是 jwt 或者 session
+type AuthConfig struct {
+	Type    string
+	JWT     JWTConfig
+	Binding BindingConfig
+	// session 的 cookie 签名和加密用的密钥，加密的要 32 位
+	SessionAuthKey    string
+	SessionEncryptKey string
+}
+
+// JWTConfig 用 Primary 签名，Keys 里面的都能用来校验
+// 轮换的时候先加新的 key，所有实例都更新之后再换 Primary，旧 key 签的 token 都过期了再删
+type JWTConfig struct {
+	Primary string
+	Keys    []JWTKeyConfig
+	// 加 kid 之前签的 token 没有 kid，用 Keys 里面 Legacy 这个 HS512 的 key 校验，
+	// 只认启动之后的 LegacyWindow，所有实例都升级完、老 token 都过期之后把这两项和 key 一起删掉
+	Legacy       string
+	LegacyWindow time.Duration
+}
+
+type JWTKeyConfig struct {
+	// 放在 token 头部的 kid 里
+	ID string
+	// HS512、RS256 或者 EdDSA
+	Alg string
+	// HS512 的密钥
+	Secret string
+	// RS256 和 EdDSA 的 PEM 私钥文件
+	PrivateKeyFile string
+	// 只用来校验的时候可以只给公钥
+	PublicKeyFile string
+}
+
+// BindingConfig 登录态和客户端怎么绑定，Policy 是 none、ua_family、device 或者 ip_subnet
+type BindingConfig struct {
+	Policy string
+	// device 用，默认是 X-Device-Id
+	DeviceHeader string
+	// ip_subnet 用，默认是 24 和 64
+	IPv4Prefix int
+	IPv6Prefix int
+}
+
+type RateLimitConfig struct {
+	// 一个请求要匹配到的每条规则都放行才能过
+	Rules   []RateLimitRule
+	Degrade RateLimitDegrade
+}
+
+// RateLimitDegrade Redis 出问题的时候怎么办
+type RateLimitDegrade struct {
+	// open 直接放行，closed 全部限流，local 换成单机限流
+	Policy string
+	// local 的时候每个实例的额度是原来的 1/Instances
+	Instances int
+	// 连续失败多少次熔断，熔断多久之后再试探
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type RateLimitRule struct {
+	// 会拼到 Redis 的 key 里面，不要重名
+	Name string
+	// 格式见 ginx.PathPattern，空的表示所有路由
+	Paths []string
+	// ip、uid、route 或者 header:X-Api-Key，多个用 + 连起来，例如 ip+route
+	Key string
+	// fixed_window、sliding_window、token_bucket 或者 leaky_bucket
+	Algorithm string
+	// 每个 Interval 放行 Rate 个
+	Interval time.Duration
+	Rate     int
+	// 令牌桶最多突发多少个，漏桶最多排队多少个
+	Capacity int
+}
+
+// SheddingConfig 过载保护，超过阈值的时候低优先级的请求返回 503
+type SheddingConfig struct {
+	// 这个实例同时处理的请求数，0 表示不看
+	MaxInFlight int
+	// 每个路由最近 Window 之内的 p99，0 表示不看
+	MaxP99 time.Duration
+	Window time.Duration
+	// 到了阈值的这个比例就开始打降级标记，service 会跳过慢路径
+	DegradeRatio float64
+	// 过载的时候也不拒的路由，格式见 ginx.PathPattern
+	HighPriority []string
+}
+
+type LogConfig struct {
+	// debug、info、warn 或者 error
+	Level string
+	// true 的时候输出 console 格式，带调用栈，本地看着方便
+	Development bool
+}
+
+// AccessLogConfig 运行时可以在后台 /admin/system/access-log 改，只对当前实例生效
+type AccessLogConfig struct {
+	Enabled  bool
+	ReqBody  bool
+	RespBody bool
+	// body 超过这么多字节就截断
+	MaxBodySize int
+	// 不打访问日志的路由，格式见 ginx.PathPattern
+	SkipPaths []string
+	// 只有这些路由记录 body，空的表示所有路由
+	BodyPaths []string
+}
+
+// TraceConfig Exporter 是 otlp 或者 stdout，为空的时候不上报，但是日志里还是有 trace_id
+type TraceConfig struct {
+	ServiceName string
+	Exporter    string
+	// OTLP gRPC 的地址，例如 localhost:4317
+	Endpoint string
+	Insecure bool
+	// 采样比例，上游已经决定了采不采样的按上游的来
+	SampleRatio float64
+}
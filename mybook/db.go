@@ -3,11 +3,23 @@ package main
 import (
 	"basic-go/mybook/config"
 	"basic-go/mybook/internal/repository/dao"
+	"basic-go/mybook/pkg/gormx"
+	"go.opentelemetry.io/otel"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
 func InitDB() *gorm.DB {
+	db := openDB()
+	err := dao.InitTables(db)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// openDB 只连接数据库，不执行迁移
+func openDB() *gorm.DB {
 	db, err := gorm.Open(mysql.Open(config.Config.DB.DSN))
 	if err != nil {
 		//只会在初始化的过程中panic
@@ -15,9 +27,17 @@ func InitDB() *gorm.DB {
 		//一旦初始化出错，应用就不要再启动了
 		panic(err)
 	}
-	err = dao.InitTables(db)
-	if err != nil {
+	if err = usePlugins(db); err != nil {
 		panic(err)
 	}
 	return db
 }
+
+// usePlugins 监控和链路追踪
+// 同一个插件注册两次 gorm 会返回 ErrRegistered，所以只在 openDB 里调
+func usePlugins(db *gorm.DB) error {
+	if err := db.Use(gormx.NewMetricsPlugin("mybook", "")); err != nil {
+		return err
+	}
+	return db.Use(gormx.NewTracingPlugin(otel.GetTracerProvider()))
+}
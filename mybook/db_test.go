@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestUsePlugins(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+		Conn:                      mockDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, usePlugins(db))
+	assert.Contains(t, db.Config.Plugins, "prometheus_metrics")
+	assert.Contains(t, db.Config.Plugins, "opentelemetry_tracing")
+	// 注册了插件之后查询还是正常的
+	var val int
+	require.NoError(t, db.Raw("SELECT 1").Scan(&val).Error)
+	assert.Equal(t, 1, val)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// 再注册一次会出错，所以 InitDB 不能在 openDB 之后再注册
+	assert.ErrorIs(t, usePlugins(db), gorm.ErrRegistered)
+}
@@ -0,0 +1,54 @@
+package domain
+
+type Role string
+
+const (
+	RoleUser     Role = "user"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+type Permission string
+
+const (
+	// PermissionUserRead 后台查询用户
+	PermissionUserRead Permission = "user:read"
+	// PermissionUserManage 禁用、启用、强制下线
+	PermissionUserManage Permission = "user:manage"
+	// PermissionRoleManage 修改用户角色
+	PermissionRoleManage Permission = "role:manage"
+	// PermissionSystemManage 运行时调整访问日志之类的系统配置
+	PermissionSystemManage Permission = "system:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser:     {},
+	RoleOperator: {PermissionUserRead, PermissionUserManage},
+	RoleAdmin:    {PermissionUserRead, PermissionUserManage, PermissionRoleManage, PermissionSystemManage},
+}
+
+// roleRanks 后台只能管理级别比自己低的用户
+var roleRanks = map[Role]int{
+	RoleUser:     0,
+	RoleOperator: 1,
+	RoleAdmin:    2,
+}
+
+// Outranks 级别一样的也不行，运营不能禁用别的运营
+func (r Role) Outranks(other Role) bool {
+	return roleRanks[r] > roleRanks[other]
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, perm := range rolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,22 @@
 package domain
 
-import "time"
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+type UserStatus uint8
+
+const (
+	UserStatusActive UserStatus = iota
+	// UserStatusDeactivated 用户申请注销，账号立刻不可用
+	UserStatusDeactivated
+	// UserStatusPurged 已经清理了个人信息，不能再恢复
+	UserStatusPurged
+	// UserStatusDisabled 被后台禁用
+	UserStatusDisabled
+)
 
 // User 领域对象， 是 DDD 中的entirely
 // BO(business object)
@@ -12,6 +28,67 @@ type User struct {
 	NickName     string
 	Birthday     string
 	Introduction string
-	CreateTime   time.Time
-	UpdateTime   time.Time
+	// Avatar 头像在对象存储里的 key，不是 URL，返回给前端之前要签名
+	Avatar         string
+	Role           Role
+	Status         UserStatus
+	DeactivateTime time.Time
+	CreateTime     time.Time
+	UpdateTime     time.Time
+}
+
+func (u User) Deactivated() bool {
+	return u.Status == UserStatusDeactivated
+}
+
+func (u User) Disabled() bool {
+	return u.Status == UserStatusDisabled
+}
+
+// Restorable 还在宽限期内
+func (u User) Restorable(now time.Time, gracePeriod time.Duration) bool {
+	return u.Deactivated() && now.Sub(u.DeactivateTime) <= gracePeriod
+}
+
+// AvatarThumbKey 缩略图和原图放在一起，avatars/1/123.jpg 的缩略图是 avatars/1/123_thumb.jpg
+func AvatarThumbKey(avatar string) string {
+	ext := path.Ext(avatar)
+	return strings.TrimSuffix(avatar, ext) + "_thumb" + ext
+}
+
+// UserFilter 查询用户的条件，零值表示不过滤
+type UserFilter struct {
+	Email       string
+	Phone       string
+	NickName    string
+	CreateStart time.Time
+	CreateEnd   time.Time
+	Statuses    []UserStatus
+}
+
+type UserOrderBy string
+
+const (
+	UserOrderById         UserOrderBy = "id"
+	UserOrderByCreateTime UserOrderBy = "create_time"
+)
+
+// UserListQuery 游标分页
+// Cursor 是上一页返回的 NextCursor，第一页传空
+// 翻页的时候 OrderBy 和 Desc 不能变，不然游标就对不上了
+type UserListQuery struct {
+	Filter    UserFilter
+	OrderBy   UserOrderBy
+	Desc      bool
+	Cursor    string
+	Limit     int
+	WithTotal bool
+}
+
+type UserListResult struct {
+	Users []User
+	// 为空说明没有下一页了
+	NextCursor string
+	// 只有 WithTotal 的时候才有
+	Total int64
 }
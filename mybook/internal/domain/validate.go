@@ -0,0 +1,40 @@
+package domain
+
+import (
+	regexp "github.com/dlclark/regexp2"
+	"time"
+)
+
+// 用户字段的校验规则，web 层校验请求和导入用户都用这一套
+const (
+	EmailRegexPattern    = `^\w+([-+.]\w+)*@\w+([-.]\w+)*\.\w+([-.]\w+)*$`
+	PasswordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
+	PhoneRegexPattern    = `^1[3456789]\d{9}$`
+)
+
+var (
+	emailRegexp    = regexp.MustCompile(EmailRegexPattern, 0)
+	passwordRegexp = regexp.MustCompile(PasswordRegexPattern, 0)
+	phoneRegexp    = regexp.MustCompile(PhoneRegexPattern, 0)
+)
+
+func ValidEmail(email string) bool {
+	ok, _ := emailRegexp.MatchString(email)
+	return ok
+}
+
+func ValidPhone(phone string) bool {
+	ok, _ := phoneRegexp.MatchString(phone)
+	return ok
+}
+
+func ValidPassword(password string) bool {
+	ok, _ := passwordRegexp.MatchString(password)
+	return ok
+}
+
+// ValidBirthday 必须是真实存在的日期，2000-02-30 这种不行
+func ValidBirthday(birthday string) bool {
+	_, err := time.Parse(time.DateOnly, birthday)
+	return err == nil
+}
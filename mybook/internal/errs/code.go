@@ -0,0 +1,110 @@
+// Package errs 错误码
+// 错误码是 6 位数字，前 3 位是模块，后 3 位是模块内的错误
+//   - 100 通用
+//   - 101 用户
+//   - 102 验证码
+//
+// 错误码一旦对外就不能再改，前端根据错误码判断发生了什么，不要去解析 Msg
+package errs
+
+import "errors"
+
+type Code int
+
+const OK Code = 0
+
+// 通用
+const (
+	SystemError Code = 100001 + iota
+	InvalidParams
+	Unauthorized
+	Forbidden
+	TooManyRequests
+	ServiceUnavailable
+)
+
+// 用户
+const (
+	UserDuplicate Code = 101001 + iota
+	UserInvalidCredential
+	UserNotFound
+	UserDeactivated
+	UserRestoreExpired
+	UserDisabled
+	UserStatusConflict
+	UserInvalidRole
+	UserInvalidCursor
+)
+
+// 用户头像，101010 到 101016 以前是表单校验的错误码，现在校验失败统一返回 InvalidParams，不要复用
+const (
+	UserAvatarTooLarge Code = 101017 + iota
+	UserAvatarInvalid
+)
+
+// 验证码
+const (
+	CodeSendTooMany Code = 102001 + iota
+	CodeIncorrect
+	CodeVerifyTooManyTimes
+	CodeInvalid
+	CodeTimeout
+)
+
+var messages = map[Code]string{
+	OK: "OK",
+
+	SystemError:        "系统错误",
+	InvalidParams:      "参数错误",
+	Unauthorized:       "未登录",
+	Forbidden:          "没有权限",
+	TooManyRequests:    "请求太频繁，请稍后重试",
+	ServiceUnavailable: "系统繁忙，请稍后重试",
+
+	UserDuplicate:         "邮箱或手机号已被注册",
+	UserInvalidCredential: "用户名/邮箱或密码不对",
+	UserNotFound:          "用户不存在",
+	UserDeactivated:       "账号已注销，宽限期内可以恢复",
+	UserRestoreExpired:    "已经超过注销宽限期，账号无法恢复",
+	UserDisabled:          "账号已被禁用",
+	UserStatusConflict:    "用户当前状态不允许这个操作",
+	UserInvalidRole:       "角色不存在",
+	UserInvalidCursor:     "分页参数不对",
+	UserAvatarTooLarge:    "头像不能超过5M",
+	UserAvatarInvalid:     "头像只支持jpeg、png、gif和webp格式的图片",
+
+	CodeSendTooMany:        "发送频繁，请稍后重试",
+	CodeIncorrect:          "验证有误！",
+	CodeVerifyTooManyTimes: "验证次数太多，请重新获取验证码",
+	CodeInvalid:            "验证码失效，请重新获取验证码",
+	CodeTimeout:            "验证码已过期!",
+}
+
+func (c Code) Msg() string {
+	return messages[c]
+}
+
+type registered struct {
+	err  error
+	code Code
+}
+
+var registry []registered
+
+// Register 把业务错误映射到错误码，一般在 init 里调用
+func Register(err error, code Code) {
+	registry = append(registry, registered{err: err, code: code})
+}
+
+// FromError 没有注册过的错误都当成系统错误，不把内部的错误信息暴露出去
+func FromError(err error) Code {
+	if err == nil {
+		return OK
+	}
+	for _, r := range registry {
+		if errors.Is(err, r.err) {
+			return r.code
+		}
+	}
+	return SystemError
+}
@@ -0,0 +1,35 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFromError(t *testing.T) {
+	errMock := errors.New("mock 业务错误")
+	Register(errMock, UserDuplicate)
+
+	assert.Equal(t, OK, FromError(nil))
+	assert.Equal(t, UserDuplicate, FromError(errMock))
+	assert.Equal(t, UserDuplicate, FromError(fmt.Errorf("包装一下: %w", errMock)))
+	assert.Equal(t, SystemError, FromError(errors.New("没注册过")))
+	assert.Equal(t, "邮箱或手机号已被注册", FromError(errMock).Msg())
+}
+
+// TestMessages 每个错误码都要有信息
+func TestMessages(t *testing.T) {
+	ranges := [][2]Code{
+		{OK, OK},
+		{SystemError, ServiceUnavailable},
+		{UserDuplicate, UserInvalidCursor},
+		{UserAvatarTooLarge, UserAvatarInvalid},
+		{CodeSendTooMany, CodeTimeout},
+	}
+	for _, r := range ranges {
+		for c := r[0]; c <= r[1]; c++ {
+			assert.NotEmpty(t, c.Msg(), c)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"basic-go/mybook/internal/service"
+	"context"
+)
+
+// PurgeUserJob 清理超过注销宽限期的用户
+type PurgeUserJob struct {
+	svc       service.UserServicePackage
+	batchSize int
+}
+
+func NewPurgeUserJob(svc service.UserServicePackage) *PurgeUserJob {
+	return &PurgeUserJob{
+		svc:       svc,
+		batchSize: 100,
+	}
+}
+
+func (j *PurgeUserJob) Name() string {
+	return "purge_user"
+}
+
+// Run 一批一批地清理，直到清理完或者超时
+func (j *PurgeUserJob) Run(ctx context.Context) error {
+	for {
+		cnt, err := j.svc.PurgeExpired(ctx, j.batchSize)
+		if err != nil {
+			return err
+		}
+		if cnt < j.batchSize {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
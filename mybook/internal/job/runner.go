@@ -0,0 +1,66 @@
+package job
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"sync"
+	"time"
+)
+
+// Runner 按固定间隔执行 Job
+type Runner struct {
+	job      Job
+	interval time.Duration
+	// 单次执行的超时时间
+	timeout time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	l      logger.Logger
+}
+
+func NewRunner(job Job, interval time.Duration, l logger.Logger) *Runner {
+	return &Runner{
+		job:      job,
+		interval: interval,
+		timeout:  interval,
+		l:        l.With(logger.String("job", job.Name())),
+	}
+}
+
+// Start 启动之后立刻执行一次，之后每隔 interval 执行一次
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.runOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止调度，并且等待正在执行的任务结束
+func (r *Runner) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Runner) runOnce(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if err := r.job.Run(ctx); err != nil {
+		r.l.Error("执行任务失败", logger.Error(err))
+	}
+}
@@ -0,0 +1,41 @@
+package job
+
+import "context"
+
+// SessionCleaner 见 sqlx_store.Store
+type SessionCleaner interface {
+	Cleanup(ctx context.Context, limit int) (int64, error)
+}
+
+// SessionCleanupJob 删掉过期的 session
+type SessionCleanupJob struct {
+	store     SessionCleaner
+	batchSize int
+}
+
+func NewSessionCleanupJob(store SessionCleaner) *SessionCleanupJob {
+	return &SessionCleanupJob{
+		store:     store,
+		batchSize: 1000,
+	}
+}
+
+func (j *SessionCleanupJob) Name() string {
+	return "session_cleanup"
+}
+
+// Run 一次最多删 batchSize 条，避免一个大事务锁住太多行
+func (j *SessionCleanupJob) Run(ctx context.Context) error {
+	for {
+		cnt, err := j.store.Cleanup(ctx, j.batchSize)
+		if err != nil {
+			return err
+		}
+		if cnt < int64(j.batchSize) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
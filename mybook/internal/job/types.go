@@ -0,0 +1,10 @@
+// Package job 后台任务
+package job
+
+import "context"
+
+// Job 执行一次任务，由 Runner 决定什么时候执行
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
@@ -12,6 +12,7 @@ import (
 	domain "basic-go/mybook/internal/domain"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,20 @@ func (m *MockUserCache) EXPECT() *MockUserCacheMockRecorder {
 	return m.recorder
 }
 
+// Del mocks base method.
+func (m *MockUserCache) Del(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Del", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Del indicates an expected call of Del.
+func (mr *MockUserCacheMockRecorder) Del(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockUserCache)(nil).Del), ctx, id)
+}
+
 // Get mocks base method.
 func (m *MockUserCache) Get(ctx context.Context, id int64) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -54,6 +69,21 @@ func (mr *MockUserCacheMockRecorder) Get(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUserCache)(nil).Get), ctx, id)
 }
 
+// GetRevokeTime mocks base method.
+func (m *MockUserCache) GetRevokeTime(ctx context.Context, id int64) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevokeTime", ctx, id)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevokeTime indicates an expected call of GetRevokeTime.
+func (mr *MockUserCacheMockRecorder) GetRevokeTime(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevokeTime", reflect.TypeOf((*MockUserCache)(nil).GetRevokeTime), ctx, id)
+}
+
 // Set mocks base method.
 func (m *MockUserCache) Set(ctx context.Context, u domain.User) error {
 	m.ctrl.T.Helper()
@@ -67,3 +97,17 @@ func (mr *MockUserCacheMockRecorder) Set(ctx, u any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockUserCache)(nil).Set), ctx, u)
 }
+
+// SetRevokeTime mocks base method.
+func (m *MockUserCache) SetRevokeTime(ctx context.Context, id int64, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRevokeTime", ctx, id, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRevokeTime indicates an expected call of SetRevokeTime.
+func (mr *MockUserCacheMockRecorder) SetRevokeTime(ctx, id, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRevokeTime", reflect.TypeOf((*MockUserCache)(nil).SetRevokeTime), ctx, id, t)
+}
@@ -14,6 +14,10 @@ var ErrKeyNotExist = redis.Nil
 type UserCache interface {
 	Get(ctx context.Context, id int64) (domain.User, error)
 	Set(ctx context.Context, u domain.User) error
+	Del(ctx context.Context, id int64) error
+	// SetRevokeTime 在这个时间之前签发的 token 都失效
+	SetRevokeTime(ctx context.Context, id int64, t time.Time) error
+	GetRevokeTime(ctx context.Context, id int64) (time.Time, error)
 }
 
 type RedisUserCache struct {
@@ -55,6 +59,28 @@ func (cache *RedisUserCache) Set(ctx context.Context, u domain.User) error {
 	return cache.client.Set(ctx, key, val, cache.expiration).Err()
 }
 
+func (cache *RedisUserCache) Del(ctx context.Context, id int64) error {
+	return cache.client.Del(ctx, cache.Key(id)).Err()
+}
+
+func (cache *RedisUserCache) SetRevokeTime(ctx context.Context, id int64, t time.Time) error {
+	// token 会不停续约，没有一个确定的最长有效期，所以这里给一个足够长的过期时间
+	return cache.client.Set(ctx, cache.revokeKey(id), t.UnixMilli(), time.Hour*24*7).Err()
+}
+
+// GetRevokeTime 没有被强制下线过的返回 ErrKeyNotExist
+func (cache *RedisUserCache) GetRevokeTime(ctx context.Context, id int64) (time.Time, error) {
+	val, err := cache.client.Get(ctx, cache.revokeKey(id)).Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(val), nil
+}
+
+func (cache *RedisUserCache) revokeKey(id int64) string {
+	return fmt.Sprintf("user:revoke:%d", id)
+}
+
 func (cache *RedisUserCache) Key(id int64) string {
 	return fmt.Sprintf("user:info:%d", id)
 }
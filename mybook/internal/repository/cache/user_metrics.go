@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/pkg/metricsx"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsUserCache 统计 Get 的命中率，其他方法直接用 UserCache 的
+type MetricsUserCache struct {
+	UserCache
+	counter *prometheus.CounterVec
+}
+
+func NewMetricsUserCache(c UserCache) UserCache {
+	return &MetricsUserCache{
+		UserCache: c,
+		counter: metricsx.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mybook",
+			Subsystem: "cache",
+			Name:      "user_get_total",
+			Help:      "UserCache 查询的命中情况",
+		}, []string{"result"})),
+	}
+}
+
+func (c *MetricsUserCache) Get(ctx context.Context, id int64) (domain.User, error) {
+	u, err := c.UserCache.Get(ctx, id)
+	switch {
+	case err == nil:
+		c.counter.WithLabelValues("hit").Inc()
+	case errors.Is(err, ErrKeyNotExist):
+		c.counter.WithLabelValues("miss").Inc()
+	default:
+		c.counter.WithLabelValues("error").Inc()
+	}
+	return u, err
+}
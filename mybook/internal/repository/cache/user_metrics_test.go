@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"basic-go/mybook/internal/domain"
+	cachemocks "basic-go/mybook/internal/repository/cache/mocks"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestMetricsUserCache_Get(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) UserCache
+		wantErr error
+		result  string
+	}{
+		{
+			name: "命中",
+			mock: func(ctrl *gomock.Controller) UserCache {
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				return c
+			},
+			result: "hit",
+		},
+		{
+			name: "没命中",
+			mock: func(ctrl *gomock.Controller) UserCache {
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), int64(1)).Return(domain.User{}, ErrKeyNotExist)
+				return c
+			},
+			wantErr: ErrKeyNotExist,
+			result:  "miss",
+		},
+		{
+			name: "Redis 出错",
+			mock: func(ctrl *gomock.Controller) UserCache {
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), int64(1)).Return(domain.User{}, errors.New("mock error"))
+				return c
+			},
+			wantErr: errors.New("mock error"),
+			result:  "error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			c := NewMetricsUserCache(tc.mock(ctrl)).(*MetricsUserCache)
+			before := testutil.ToFloat64(c.counter.WithLabelValues(tc.result))
+
+			_, err := c.Get(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, before+1, testutil.ToFloat64(c.counter.WithLabelValues(tc.result)))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/repository/dao"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("非法的分页游标")
+
+// userCursor 游标里面带上排序方式，防止翻页的时候换了排序
+// 对外是 base64 之后的字符串，前端不需要关心里面是什么
+type userCursor struct {
+	OrderBy    domain.UserOrderBy `json:"o"`
+	Desc       bool               `json:"d"`
+	Id         int64              `json:"i"`
+	CreateTime int64              `json:"c"`
+}
+
+func encodeUserCursor(q domain.UserListQuery, last dao.User) string {
+	val, _ := json.Marshal(userCursor{
+		OrderBy:    q.OrderBy,
+		Desc:       q.Desc,
+		Id:         last.Id,
+		CreateTime: last.CreateTime,
+	})
+	return base64.RawURLEncoding.EncodeToString(val)
+}
+
+// decodeUserCursor 第一页返回 nil
+func decodeUserCursor(q domain.UserListQuery) (*dao.UserCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	val, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c userCursor
+	if err = json.Unmarshal(val, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.OrderBy != q.OrderBy || c.Desc != q.Desc {
+		return nil, ErrInvalidCursor
+	}
+	return &dao.UserCursor{
+		Id:         c.Id,
+		CreateTime: c.CreateTime,
+	}, nil
+}
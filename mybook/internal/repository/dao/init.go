@@ -1,7 +1,23 @@
 package dao
 
-import "gorm.io/gorm"
+import (
+	"basic-go/mybook/internal/repository/dao/migrations"
+	"basic-go/mybook/pkg/migrator"
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+)
 
+// InitTables 启动的时候执行所有还没执行的迁移
+// 不再用 AutoMigrate，它改不了索引，而且出错了也不会告诉你
 func InitTables(db *gorm.DB) error {
-	return db.AutoMigrate(&User{})
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return NewMigrator(sqlDB).Up(context.Background())
+}
+
+func NewMigrator(db *sql.DB) *migrator.Migrator {
+	return migrator.New(db, migrations.FS)
 }
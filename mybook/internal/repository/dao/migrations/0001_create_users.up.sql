@@ -0,0 +1,16 @@
+-- 和之前 AutoMigrate 建出来的表结构保持一致，已经存在的库不会受影响
+CREATE TABLE IF NOT EXISTS `users`
+(
+    `id`           BIGINT AUTO_INCREMENT,
+    `email`        VARCHAR(191),
+    `phone`        VARCHAR(191),
+    `password`     LONGTEXT,
+    `nick_name`    LONGTEXT,
+    `birthday`     LONGTEXT,
+    `introduction` LONGTEXT,
+    `create_time`  BIGINT,
+    `update_time`  BIGINT,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `email` (`email`),
+    UNIQUE INDEX `phone` (`phone`)
+);
@@ -0,0 +1,4 @@
+DROP INDEX `idx_users_status_deactivate_time` ON `users`;
+ALTER TABLE `users`
+    DROP COLUMN `status`,
+    DROP COLUMN `deactivate_time`;
@@ -0,0 +1,6 @@
+-- status: 0 正常，1 已注销（宽限期内可以恢复），2 已清理
+ALTER TABLE `users`
+    ADD COLUMN `status`          TINYINT NOT NULL DEFAULT 0,
+    ADD COLUMN `deactivate_time` BIGINT  NOT NULL DEFAULT 0;
+-- 清理任务按 status + deactivate_time 扫描
+CREATE INDEX `idx_users_status_deactivate_time` ON `users` (`status`, `deactivate_time`);
@@ -0,0 +1,3 @@
+DROP INDEX `idx_users_create_time` ON `users`;
+ALTER TABLE `users`
+    DROP COLUMN `role`;
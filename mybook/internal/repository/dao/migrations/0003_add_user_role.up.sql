@@ -0,0 +1,6 @@
+-- 角色: user 普通用户，operator 运营/客服，admin 管理员
+-- 每个角色有哪些权限定义在 domain.Role 里
+ALTER TABLE `users`
+    ADD COLUMN `role` VARCHAR(32) NOT NULL DEFAULT 'user';
+-- 后台按创建时间搜索用户
+CREATE INDEX `idx_users_create_time` ON `users` (`create_time`);
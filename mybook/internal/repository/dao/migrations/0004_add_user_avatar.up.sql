@@ -0,0 +1,3 @@
+-- 头像在对象存储里的 key，缩略图的 key 是在它后面加 _thumb
+ALTER TABLE `users`
+    ADD COLUMN `avatar` VARCHAR(255) NOT NULL DEFAULT '';
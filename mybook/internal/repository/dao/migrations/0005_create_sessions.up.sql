@@ -0,0 +1,12 @@
+-- MySQL 版本的 session，给不能用 Redis 的环境用，见 sqlx_store
+-- data 是签名加密过的 session 数据，expires_at 是毫秒数
+CREATE TABLE IF NOT EXISTS `sessions`
+(
+    `id`          VARCHAR(64) NOT NULL,
+    `data`        MEDIUMTEXT  NOT NULL,
+    `expires_at`  BIGINT      NOT NULL,
+    `create_time` BIGINT      NOT NULL,
+    `update_time` BIGINT      NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_sessions_expires_at` (`expires_at`)
+);
@@ -0,0 +1,3 @@
+-- 强制下线的时间，毫秒数。session 模式不依赖 Redis，存在这里
+ALTER TABLE `users`
+    ADD COLUMN `revoke_time` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+// Package migrations 数据库迁移文件
+// 新增迁移的时候，version 在最大的基础上加一，并且同时提供 up 和 down
+// 已经上线的迁移文件不要再改，要改就新增一个版本
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
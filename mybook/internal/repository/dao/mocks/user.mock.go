@@ -39,6 +39,49 @@ func (m *MockUserDAO) EXPECT() *MockUserDAOMockRecorder {
 	return m.recorder
 }
 
+// BatchInsert mocks base method.
+func (m *MockUserDAO) BatchInsert(ctx context.Context, us []dao.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchInsert", ctx, us)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchInsert indicates an expected call of BatchInsert.
+func (mr *MockUserDAOMockRecorder) BatchInsert(ctx, us any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchInsert", reflect.TypeOf((*MockUserDAO)(nil).BatchInsert), ctx, us)
+}
+
+// Count mocks base method.
+func (m *MockUserDAO) Count(ctx context.Context, filter dao.UserFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filter)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockUserDAOMockRecorder) Count(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockUserDAO)(nil).Count), ctx, filter)
+}
+
+// Deactivate mocks base method.
+func (m *MockUserDAO) Deactivate(ctx context.Context, userId, now int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deactivate", ctx, userId, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Deactivate indicates an expected call of Deactivate.
+func (mr *MockUserDAOMockRecorder) Deactivate(ctx, userId, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deactivate", reflect.TypeOf((*MockUserDAO)(nil).Deactivate), ctx, userId, now)
+}
+
 // Edit mocks base method.
 func (m *MockUserDAO) Edit(ctx context.Context, u dao.User) error {
 	m.ctrl.T.Helper()
@@ -98,6 +141,36 @@ func (mr *MockUserDAOMockRecorder) FindByPhone(ctx, phone any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByPhone", reflect.TypeOf((*MockUserDAO)(nil).FindByPhone), ctx, phone)
 }
 
+// FindDeactivated mocks base method.
+func (m *MockUserDAO) FindDeactivated(ctx context.Context, before int64, limit int) ([]dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeactivated", ctx, before, limit)
+	ret0, _ := ret[0].([]dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeactivated indicates an expected call of FindDeactivated.
+func (mr *MockUserDAOMockRecorder) FindDeactivated(ctx, before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeactivated", reflect.TypeOf((*MockUserDAO)(nil).FindDeactivated), ctx, before, limit)
+}
+
+// FindRevokeTime mocks base method.
+func (m *MockUserDAO) FindRevokeTime(ctx context.Context, userId int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRevokeTime", ctx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRevokeTime indicates an expected call of FindRevokeTime.
+func (mr *MockUserDAOMockRecorder) FindRevokeTime(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRevokeTime", reflect.TypeOf((*MockUserDAO)(nil).FindRevokeTime), ctx, userId)
+}
+
 // Insert mocks base method.
 func (m *MockUserDAO) Insert(ctx context.Context, u dao.User) error {
 	m.ctrl.T.Helper()
@@ -111,3 +184,102 @@ func (mr *MockUserDAOMockRecorder) Insert(ctx, u any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockUserDAO)(nil).Insert), ctx, u)
 }
+
+// List mocks base method.
+func (m *MockUserDAO) List(ctx context.Context, q dao.UserListQuery) ([]dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, q)
+	ret0, _ := ret[0].([]dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockUserDAOMockRecorder) List(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserDAO)(nil).List), ctx, q)
+}
+
+// Purge mocks base method.
+func (m *MockUserDAO) Purge(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockUserDAOMockRecorder) Purge(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockUserDAO)(nil).Purge), ctx, userId)
+}
+
+// Restore mocks base method.
+func (m *MockUserDAO) Restore(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockUserDAOMockRecorder) Restore(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockUserDAO)(nil).Restore), ctx, userId)
+}
+
+// UpdateAvatar mocks base method.
+func (m *MockUserDAO) UpdateAvatar(ctx context.Context, userId int64, avatar string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAvatar", ctx, userId, avatar)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAvatar indicates an expected call of UpdateAvatar.
+func (mr *MockUserDAOMockRecorder) UpdateAvatar(ctx, userId, avatar any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatar", reflect.TypeOf((*MockUserDAO)(nil).UpdateAvatar), ctx, userId, avatar)
+}
+
+// UpdateRevokeTime mocks base method.
+func (m *MockUserDAO) UpdateRevokeTime(ctx context.Context, userId, revokeTime int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRevokeTime", ctx, userId, revokeTime)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRevokeTime indicates an expected call of UpdateRevokeTime.
+func (mr *MockUserDAOMockRecorder) UpdateRevokeTime(ctx, userId, revokeTime any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRevokeTime", reflect.TypeOf((*MockUserDAO)(nil).UpdateRevokeTime), ctx, userId, revokeTime)
+}
+
+// UpdateRole mocks base method.
+func (m *MockUserDAO) UpdateRole(ctx context.Context, userId int64, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", ctx, userId, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockUserDAOMockRecorder) UpdateRole(ctx, userId, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockUserDAO)(nil).UpdateRole), ctx, userId, role)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockUserDAO) UpdateStatus(ctx context.Context, userId int64, from, to uint8) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, userId, from, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockUserDAOMockRecorder) UpdateStatus(ctx, userId, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockUserDAO)(nil).UpdateStatus), ctx, userId, from, to)
+}
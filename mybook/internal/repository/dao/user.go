@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 	"time"
@@ -12,6 +13,8 @@ import (
 var (
 	ErrUseDuplicate = errors.New("邮箱冲突")
 	ErrUserNotFund  = gorm.ErrRecordNotFound
+	// ErrUserStatusConflict 用户当前的状态不允许这次变更
+	ErrUserStatusConflict = errors.New("用户状态不对")
 )
 
 type UserDAO interface {
@@ -19,7 +22,19 @@ type UserDAO interface {
 	FindByPhone(ctx context.Context, phone string) (User, error)
 	FindById(ctx context.Context, userId int64) (User, error)
 	Insert(ctx context.Context, u User) error
+	BatchInsert(ctx context.Context, us []User) error
 	Edit(ctx context.Context, u User) error
+	Deactivate(ctx context.Context, userId int64, now int64) error
+	Restore(ctx context.Context, userId int64) error
+	FindDeactivated(ctx context.Context, before int64, limit int) ([]User, error)
+	Purge(ctx context.Context, userId int64) error
+	UpdateStatus(ctx context.Context, userId int64, from, to uint8) error
+	UpdateRole(ctx context.Context, userId int64, role string) error
+	UpdateAvatar(ctx context.Context, userId int64, avatar string) error
+	UpdateRevokeTime(ctx context.Context, userId int64, revokeTime int64) error
+	FindRevokeTime(ctx context.Context, userId int64) (int64, error)
+	List(ctx context.Context, q UserListQuery) ([]User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
 }
 
 type GORMUserDAO struct {
@@ -70,18 +85,41 @@ func (dao *GORMUserDAO) Insert(ctx context.Context, u User) error {
 	return err
 }
 
+// BatchInsert 一条语句插入多个用户，任何一个冲突整批都会失败
+func (dao *GORMUserDAO) BatchInsert(ctx context.Context, us []User) error {
+	now := time.Now().UnixMilli()
+	for i := range us {
+		us[i].CreateTime = now
+		us[i].UpdateTime = now
+	}
+	err := dao.db.WithContext(ctx).Create(&us).Error
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return ErrUseDuplicate
+		}
+	}
+	return err
+}
+
 func (dao *GORMUserDAO) Edit(ctx context.Context, u User) error {
 	//存更新时间
 	now := time.Now().UnixMilli()
 	//u.CreateTime = now
 	u.UpdateTime = now
 
-	// 构建需要更新的字段映射
+	// 构建需要更新的字段映射，空的字段不更新，不然会被覆盖成 ""
 	updateFields := map[string]interface{}{
-		"UpdateTime":   now,
-		"NickName":     u.NickName,
-		"Birthday":     u.Birthday,
-		"Introduction": u.Introduction,
+		"UpdateTime": now,
+	}
+	if u.NickName != "" {
+		updateFields["NickName"] = u.NickName
+	}
+	if u.Birthday != "" {
+		updateFields["Birthday"] = u.Birthday
+	}
+	if u.Introduction != "" {
+		updateFields["Introduction"] = u.Introduction
 	}
 
 	// 构建更新条件
@@ -102,6 +140,211 @@ func (dao *GORMUserDAO) Edit(ctx context.Context, u User) error {
 	return err
 }
 
+// Deactivate 注销，只有正常的用户才能注销
+func (dao *GORMUserDAO) Deactivate(ctx context.Context, userId int64, now int64) error {
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND status = ?", userId, UserStatusActive).
+		Updates(map[string]any{
+			"status":          UserStatusDeactivated,
+			"deactivate_time": now,
+			"update_time":     now,
+		}).Error
+}
+
+// Restore 恢复账号，已经清理过的不能恢复
+func (dao *GORMUserDAO) Restore(ctx context.Context, userId int64) error {
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND status = ?", userId, UserStatusDeactivated).
+		Updates(map[string]any{
+			"status":          UserStatusActive,
+			"deactivate_time": 0,
+			"update_time":     time.Now().UnixMilli(),
+		}).Error
+}
+
+// FindDeactivated 找出在 before 之前注销的用户
+func (dao *GORMUserDAO) FindDeactivated(ctx context.Context, before int64, limit int) ([]User, error) {
+	var res []User
+	err := dao.db.WithContext(ctx).
+		Where("status = ? AND deactivate_time < ?", UserStatusDeactivated, before).
+		Order("deactivate_time ASC").
+		Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// Purge 清理个人信息
+// 邮箱和手机号置为 NULL，这样唯一索引就空出来了，别人（或者他自己）可以重新注册
+func (dao *GORMUserDAO) Purge(ctx context.Context, userId int64) error {
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND status = ?", userId, UserStatusDeactivated).
+		Updates(map[string]any{
+			"email":        sql.NullString{},
+			"phone":        sql.NullString{},
+			"password":     "",
+			"nick_name":    "",
+			"birthday":     "",
+			"introduction": "",
+			"avatar":       "",
+			"status":       UserStatusPurged,
+			"update_time":  time.Now().UnixMilli(),
+		}).Error
+}
+
+// UpdateStatus 只有当前状态是 from 的时候才会更新成 to
+func (dao *GORMUserDAO) UpdateStatus(ctx context.Context, userId int64, from, to uint8) error {
+	res := dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND status = ?", userId, from).
+		Updates(map[string]any{
+			"status":      to,
+			"update_time": time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserStatusConflict
+	}
+	return nil
+}
+
+func (dao *GORMUserDAO) UpdateRole(ctx context.Context, userId int64, role string) error {
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", userId).
+		Updates(map[string]any{
+			"role":        role,
+			"update_time": time.Now().UnixMilli(),
+		}).Error
+}
+
+func (dao *GORMUserDAO) UpdateAvatar(ctx context.Context, userId int64, avatar string) error {
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", userId).
+		Updates(map[string]any{
+			"avatar":      avatar,
+			"update_time": time.Now().UnixMilli(),
+		}).Error
+}
+
+func (dao *GORMUserDAO) UpdateRevokeTime(ctx context.Context, userId int64, revokeTime int64) error {
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", userId).
+		Updates(map[string]any{
+			"revoke_time": revokeTime,
+			"update_time": time.Now().UnixMilli(),
+		}).Error
+}
+
+// FindRevokeTime 每个请求都会查，只查这一列；用户不存在的时候返回 0
+func (dao *GORMUserDAO) FindRevokeTime(ctx context.Context, userId int64) (int64, error) {
+	var revokeTime []int64
+	err := dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", userId).
+		Limit(1).Pluck("revoke_time", &revokeTime).Error
+	if err != nil || len(revokeTime) == 0 {
+		return 0, err
+	}
+	return revokeTime[0], nil
+}
+
+// List 游标分页，不用 OFFSET，翻到多深都是走索引
+// 按 create_time 排序的时候用 id 兜底，保证顺序稳定
+// create_time 上的二级索引本身就带了主键，所以 (create_time, id) 也能走索引
+func (dao *GORMUserDAO) List(ctx context.Context, q UserListQuery) ([]User, error) {
+	query := dao.filter(dao.db.WithContext(ctx).Model(&User{}), q.Filter)
+	cmp, order := ">", "ASC"
+	if q.Desc {
+		cmp, order = "<", "DESC"
+	}
+	switch q.OrderBy {
+	case UserOrderByCreateTime:
+		if q.After != nil {
+			query = query.Where(fmt.Sprintf("(create_time %s ? OR (create_time = ? AND id %s ?))", cmp, cmp),
+				q.After.CreateTime, q.After.CreateTime, q.After.Id)
+		}
+		query = query.Order("create_time " + order).Order("id " + order)
+	default:
+		if q.After != nil {
+			query = query.Where(fmt.Sprintf("id %s ?", cmp), q.After.Id)
+		}
+		query = query.Order("id " + order)
+	}
+	var res []User
+	err := query.Limit(q.Limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMUserDAO) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	var total int64
+	err := dao.filter(dao.db.WithContext(ctx).Model(&User{}), filter).Count(&total).Error
+	return total, err
+}
+
+// filter email 和 phone 精确匹配，nick_name 模糊匹配
+func (dao *GORMUserDAO) filter(query *gorm.DB, filter UserFilter) *gorm.DB {
+	if filter.Email != "" {
+		query = query.Where("email = ?", filter.Email)
+	}
+	if filter.Phone != "" {
+		query = query.Where("phone = ?", filter.Phone)
+	}
+	if filter.NickName != "" {
+		query = query.Where("nick_name LIKE ?", "%"+filter.NickName+"%")
+	}
+	if filter.CreateStart > 0 {
+		query = query.Where("create_time >= ?", filter.CreateStart)
+	}
+	if filter.CreateEnd > 0 {
+		query = query.Where("create_time < ?", filter.CreateEnd)
+	}
+	if len(filter.Statuses) > 0 {
+		// []uint8 会被当成 []byte，要转一下
+		statuses := make([]int, 0, len(filter.Statuses))
+		for _, st := range filter.Statuses {
+			statuses = append(statuses, int(st))
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+	return query
+}
+
+// UserFilter 零值表示不过滤，时间都是毫秒数
+type UserFilter struct {
+	Email       string
+	Phone       string
+	NickName    string
+	CreateStart int64
+	CreateEnd   int64
+	Statuses    []uint8
+}
+
+const (
+	UserOrderById         = "id"
+	UserOrderByCreateTime = "create_time"
+)
+
+type UserListQuery struct {
+	Filter UserFilter
+	// UserOrderById 或者 UserOrderByCreateTime
+	OrderBy string
+	Desc    bool
+	// 上一页的最后一条，第一页为 nil
+	After *UserCursor
+	Limit int
+}
+
+// UserCursor 游标，只用到排序相关的字段
+type UserCursor struct {
+	Id         int64
+	CreateTime int64
+}
+
+const (
+	UserStatusActive uint8 = iota
+	UserStatusDeactivated
+	UserStatusPurged
+	UserStatusDisabled
+)
+
 // User 对标数据库
 // 有人叫model， 也有叫 PO(persistent object)
 type User struct {
@@ -115,6 +358,16 @@ type User struct {
 	NickName     string
 	Birthday     string
 	Introduction string
+	// 头像在对象存储里的 key
+	Avatar string
+	// 不设置的时候用数据库的默认值 user
+	Role string `gorm:"default:user"`
+	// 0 正常，1 已注销，2 已清理，3 已禁用
+	Status uint8
+	// 注销时间，毫秒数
+	DeactivateTime int64
+	// 强制下线的时间，毫秒数，只有 session 模式用，JWT 模式存在 Redis 里
+	RevokeTime int64
 	//创建时间 -毫秒数
 	CreateTime int64
 	UpdateTime int64
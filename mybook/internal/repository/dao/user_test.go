@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-sql-driver/mysql"
@@ -85,3 +86,54 @@ func TestGORMUserDAO_Insert(t *testing.T) {
 		})
 	}
 }
+
+func TestGORMUserDAO_List(t *testing.T) {
+	testCase := []struct {
+		name     string
+		query    UserListQuery
+		wantSQL  string
+		wantArgs []driver.Value
+	}{
+		{
+			name:     "第一页按 id 升序",
+			query:    UserListQuery{Limit: 10},
+			wantSQL:  "SELECT * FROM `users` ORDER BY id ASC LIMIT 10",
+			wantArgs: []driver.Value{},
+		},
+		{
+			name: "按创建时间倒序翻页",
+			query: UserListQuery{
+				Filter:  UserFilter{NickName: "大明", Statuses: []uint8{UserStatusActive}},
+				OrderBy: UserOrderByCreateTime,
+				Desc:    true,
+				After:   &UserCursor{Id: 12, CreateTime: 1000},
+				Limit:   10,
+			},
+			wantSQL: "SELECT * FROM `users` WHERE nick_name LIKE ? AND status IN (?) " +
+				"AND ((create_time < ? OR (create_time = ? AND id < ?))) " +
+				"ORDER BY create_time DESC,id DESC LIMIT 10",
+			wantArgs: []driver.Value{"%大明%", 0, 1000, 1000, 12},
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(t, err)
+			mock.ExpectQuery(tc.wantSQL).WithArgs(tc.wantArgs...).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      mockDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			us, err := NewUserDao(db).List(context.Background(), tc.query)
+			require.NoError(t, err)
+			assert.Equal(t, []User{{Id: 11}}, us)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
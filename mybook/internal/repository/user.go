@@ -4,31 +4,50 @@ import (
 	"basic-go/mybook/internal/domain"
 	"basic-go/mybook/internal/repository/cache"
 	"basic-go/mybook/internal/repository/dao"
+	"basic-go/mybook/pkg/logger"
 	"context"
 	"database/sql"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 )
 
 var ErrUseDuplicate = dao.ErrUseDuplicate
 var ErrUserNotFund = dao.ErrUserNotFund
+var ErrUserStatusConflict = dao.ErrUserStatusConflict
+
+// defaultListLimit List 没传 Limit 的时候一页查多少条
+const defaultListLimit = 20
 
 type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (domain.User, error)
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 	Created(ctx context.Context, u domain.User) error
+	BatchCreate(ctx context.Context, us []domain.User) error
 	FindById(ctx context.Context, userId int64) (domain.User, error)
 	Edit(ctx context.Context, u domain.User) error
+	Deactivate(ctx context.Context, userId int64) error
+	Restore(ctx context.Context, userId int64) error
+	FindDeactivatedBefore(ctx context.Context, before time.Time, limit int) ([]domain.User, error)
+	Purge(ctx context.Context, userId int64) error
+	UpdateStatus(ctx context.Context, userId int64, from, to domain.UserStatus) error
+	UpdateRole(ctx context.Context, userId int64, role domain.Role) error
+	UpdateAvatar(ctx context.Context, userId int64, avatar string) error
+	List(ctx context.Context, q domain.UserListQuery) (domain.UserListResult, error)
+	RevokeTokens(ctx context.Context, userId int64) error
+	TokenRevokeTime(ctx context.Context, userId int64) (time.Time, error)
 }
 
 type CacheUserRepository struct {
 	dao   dao.UserDAO
 	cache cache.UserCache
+	l     logger.Logger
 }
 
-func NewUserRepository(dao dao.UserDAO, c cache.UserCache) UserRepository {
+func NewUserRepository(dao dao.UserDAO, c cache.UserCache, l logger.Logger) UserRepository {
 	return &CacheUserRepository{
 		dao:   dao,
 		cache: c,
+		l:     l,
 	}
 }
 
@@ -52,13 +71,165 @@ func (r *CacheUserRepository) Created(ctx context.Context, u domain.User) error
 	return r.dao.Insert(ctx, r.DomainToEntity(u))
 }
 
+func (r *CacheUserRepository) BatchCreate(ctx context.Context, us []domain.User) error {
+	entities := make([]dao.User, 0, len(us))
+	for _, u := range us {
+		entities = append(entities, r.DomainToEntity(u))
+	}
+	return r.dao.BatchInsert(ctx, entities)
+}
+
+// Edit 只更新不为空的字段
 func (r *CacheUserRepository) Edit(ctx context.Context, u domain.User) error {
-	return r.dao.Edit(ctx, dao.User{
+	err := r.dao.Edit(ctx, dao.User{
 		Id:           u.Id,
 		NickName:     u.NickName,
 		Birthday:     u.Birthday,
 		Introduction: u.Introduction,
 	})
+	if err != nil {
+		return err
+	}
+	// 改完删缓存，不然 Profile 拿到的还是旧的
+	return r.cache.Del(ctx, u.Id)
+}
+
+func (r *CacheUserRepository) Deactivate(ctx context.Context, userId int64) error {
+	err := r.dao.Deactivate(ctx, userId, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	// 状态变了，缓存要删掉，不然 FindById 还能查到正常的用户
+	return r.cache.Del(ctx, userId)
+}
+
+func (r *CacheUserRepository) Restore(ctx context.Context, userId int64) error {
+	err := r.dao.Restore(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return r.cache.Del(ctx, userId)
+}
+
+func (r *CacheUserRepository) FindDeactivatedBefore(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+	us, err := r.dao.FindDeactivated(ctx, before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(us))
+	for _, u := range us {
+		res = append(res, r.entityToDomain(u))
+	}
+	return res, nil
+}
+
+func (r *CacheUserRepository) Purge(ctx context.Context, userId int64) error {
+	err := r.dao.Purge(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return r.cache.Del(ctx, userId)
+}
+
+func (r *CacheUserRepository) UpdateStatus(ctx context.Context, userId int64, from, to domain.UserStatus) error {
+	err := r.dao.UpdateStatus(ctx, userId, uint8(from), uint8(to))
+	if err != nil {
+		return err
+	}
+	return r.cache.Del(ctx, userId)
+}
+
+func (r *CacheUserRepository) UpdateRole(ctx context.Context, userId int64, role domain.Role) error {
+	err := r.dao.UpdateRole(ctx, userId, string(role))
+	if err != nil {
+		return err
+	}
+	return r.cache.Del(ctx, userId)
+}
+
+func (r *CacheUserRepository) UpdateAvatar(ctx context.Context, userId int64, avatar string) error {
+	err := r.dao.UpdateAvatar(ctx, userId, avatar)
+	if err != nil {
+		return err
+	}
+	return r.cache.Del(ctx, userId)
+}
+
+// List 游标分页，会多查一条用来判断还有没有下一页
+func (r *CacheUserRepository) List(ctx context.Context, q domain.UserListQuery) (domain.UserListResult, error) {
+	if q.OrderBy == "" {
+		q.OrderBy = domain.UserOrderById
+	}
+	// 不是所有调用方都会先校验，小于等于 0 的时候后面截取最后一条会越界
+	if q.Limit <= 0 {
+		q.Limit = defaultListLimit
+	}
+	if q.OrderBy != domain.UserOrderById && q.OrderBy != domain.UserOrderByCreateTime {
+		return domain.UserListResult{}, ErrInvalidCursor
+	}
+	after, err := decodeUserCursor(q)
+	if err != nil {
+		return domain.UserListResult{}, err
+	}
+	filter := r.toFilter(q.Filter)
+	us, err := r.dao.List(ctx, dao.UserListQuery{
+		Filter:  filter,
+		OrderBy: string(q.OrderBy),
+		Desc:    q.Desc,
+		After:   after,
+		Limit:   q.Limit + 1,
+	})
+	if err != nil {
+		return domain.UserListResult{}, err
+	}
+	var res domain.UserListResult
+	if len(us) > q.Limit {
+		us = us[:q.Limit]
+		res.NextCursor = encodeUserCursor(q, us[len(us)-1])
+	}
+	res.Users = make([]domain.User, 0, len(us))
+	for _, u := range us {
+		res.Users = append(res.Users, r.entityToDomain(u))
+	}
+	if q.WithTotal {
+		res.Total, err = r.dao.Count(ctx, filter)
+		if err != nil {
+			return domain.UserListResult{}, err
+		}
+	}
+	return res, nil
+}
+
+func (r *CacheUserRepository) toFilter(f domain.UserFilter) dao.UserFilter {
+	filter := dao.UserFilter{
+		Email:    f.Email,
+		Phone:    f.Phone,
+		NickName: f.NickName,
+	}
+	if !f.CreateStart.IsZero() {
+		filter.CreateStart = f.CreateStart.UnixMilli()
+	}
+	if !f.CreateEnd.IsZero() {
+		filter.CreateEnd = f.CreateEnd.UnixMilli()
+	}
+	for _, st := range f.Statuses {
+		filter.Statuses = append(filter.Statuses, uint8(st))
+	}
+	return filter
+}
+
+// RevokeTokens 强制下线，现在之前签发的 token 都失效
+func (r *CacheUserRepository) RevokeTokens(ctx context.Context, userId int64) error {
+	return r.cache.SetRevokeTime(ctx, userId, time.Now())
+}
+
+// TokenRevokeTime 没有被强制下线过的，返回零值
+func (r *CacheUserRepository) TokenRevokeTime(ctx context.Context, userId int64) (time.Time, error) {
+	t, err := r.cache.GetRevokeTime(ctx, userId)
+	if err == cache.ErrKeyNotExist {
+		return time.Time{}, nil
+	}
+	return t, err
 }
 
 func (r *CacheUserRepository) FindById(ctx context.Context, id int64) (domain.User, error) {
@@ -79,6 +250,9 @@ func (r *CacheUserRepository) FindById(ctx context.Context, id int64) (domain.Us
 	//没有这个数据
 	if err == cache.ErrKeyNotExist {
 		//去数据里面加载
+	} else {
+		//缓存出错了，还是去数据库查，打个日志做监控
+		r.l.WithContext(ctx).Warn("查询用户缓存失败", logger.Int64("uid", id), logger.Error(err))
 	}
 
 	ue, err := r.dao.FindById(ctx, id)
@@ -88,11 +262,10 @@ func (r *CacheUserRepository) FindById(ctx context.Context, id int64) (domain.Us
 
 	u = r.entityToDomain(ue)
 
-	_ = r.cache.Set(ctx, u)
-	//if err != nil {
-	//	//这里打日志做监控
-	//	//return
-	//}
+	if err = r.cache.Set(ctx, u); err != nil {
+		//这里打日志做监控
+		r.l.WithContext(ctx).Warn("回写用户缓存失败", logger.Int64("uid", id), logger.Error(err))
+	}
 
 	//go func() {
 	//
@@ -138,9 +311,13 @@ func (r *CacheUserRepository) CacheFindById(ctx context.Context, id int64) (doma
 	//	//return
 	//}
 
+	// 请求结束之后 ctx 会被取消，gin.Context 还会被复用，不能直接用
+	// 只保留链路信息，这样回写缓存还在这个请求的链路里
+	spanCtx := trace.SpanContextFromContext(ctx)
 	go func() {
+		ctx, cancel := context.WithTimeout(trace.ContextWithSpanContext(context.Background(), spanCtx), time.Second)
+		defer cancel()
 		_ = r.cache.Set(ctx, u)
-
 	}()
 
 	return u, nil
@@ -172,21 +349,34 @@ func (r *CacheUserRepository) DomainToEntity(u domain.User) dao.User {
 			String: u.Phone,
 			Valid:  u.Phone != "",
 		},
-		Password:   u.Password,
-		CreateTime: u.CreateTime.UnixMilli(),
+		Password:     u.Password,
+		NickName:     u.NickName,
+		Birthday:     u.Birthday,
+		Introduction: u.Introduction,
+		Avatar:       u.Avatar,
+		Role:         string(u.Role),
+		CreateTime:   u.CreateTime.UnixMilli(),
 	}
 }
 
 func (r *CacheUserRepository) entityToDomain(u dao.User) domain.User {
+	var deactivateTime time.Time
+	if u.DeactivateTime > 0 {
+		deactivateTime = time.UnixMilli(u.DeactivateTime)
+	}
 	return domain.User{
-		Id:           u.Id,
-		Email:        u.Email.String,
-		Phone:        u.Phone.String,
-		Password:     u.Password,
-		NickName:     u.NickName,
-		Birthday:     u.Birthday,
-		Introduction: u.Introduction,
-		CreateTime:   time.UnixMilli(u.CreateTime),
-		UpdateTime:   time.UnixMilli(u.UpdateTime),
+		Id:             u.Id,
+		Email:          u.Email.String,
+		Phone:          u.Phone.String,
+		Password:       u.Password,
+		NickName:       u.NickName,
+		Birthday:       u.Birthday,
+		Introduction:   u.Introduction,
+		Avatar:         u.Avatar,
+		Role:           domain.Role(u.Role),
+		Status:         domain.UserStatus(u.Status),
+		DeactivateTime: deactivateTime,
+		CreateTime:     time.UnixMilli(u.CreateTime),
+		UpdateTime:     time.UnixMilli(u.UpdateTime),
 	}
 }
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"basic-go/mybook/internal/repository/dao"
+	"context"
+	"time"
+)
+
+// DBRevokeUserRepository 强制下线的时间存在 MySQL 里，其他方法直接用 UserRepository 的
+// session 模式不依赖 Redis，登录态本身就在 MySQL 里，每个请求多查一列问题不大
+type DBRevokeUserRepository struct {
+	UserRepository
+	dao dao.UserDAO
+}
+
+func NewDBRevokeUserRepository(repo UserRepository, d dao.UserDAO) UserRepository {
+	return &DBRevokeUserRepository{
+		UserRepository: repo,
+		dao:            d,
+	}
+}
+
+// RevokeTokens 强制下线，现在之前登录的都失效
+func (r *DBRevokeUserRepository) RevokeTokens(ctx context.Context, userId int64) error {
+	return r.dao.UpdateRevokeTime(ctx, userId, time.Now().UnixMilli())
+}
+
+// TokenRevokeTime 没有被强制下线过的，返回零值
+func (r *DBRevokeUserRepository) TokenRevokeTime(ctx context.Context, userId int64) (time.Time, error) {
+	t, err := r.dao.FindRevokeTime(ctx, userId)
+	if err != nil || t == 0 {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(t), nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	cachemocks "basic-go/mybook/internal/repository/cache/mocks"
+	daomocks "basic-go/mybook/internal/repository/dao/mocks"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// TestDBRevokeUserRepository 强制下线只读写 MySQL，不碰 Redis
+func TestDBRevokeUserRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(d *daomocks.MockUserDAO)
+
+		wantTime time.Time
+		wantErr  error
+	}{
+		{
+			name: "被强制下线过",
+			mock: func(d *daomocks.MockUserDAO) {
+				d.EXPECT().FindRevokeTime(gomock.Any(), int64(123)).Return(int64(1697700000000), nil)
+			},
+			wantTime: time.UnixMilli(1697700000000),
+		},
+		{
+			name: "没有被强制下线过",
+			mock: func(d *daomocks.MockUserDAO) {
+				d.EXPECT().FindRevokeTime(gomock.Any(), int64(123)).Return(int64(0), nil)
+			},
+		},
+		{
+			name: "数据库出错",
+			mock: func(d *daomocks.MockUserDAO) {
+				d.EXPECT().FindRevokeTime(gomock.Any(), int64(123)).Return(int64(0), errors.New("mock db error"))
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d := daomocks.NewMockUserDAO(ctrl)
+			tc.mock(d)
+			// cache 上没有 EXPECT，调用了就会失败
+			repo := NewDBRevokeUserRepository(
+				NewUserRepository(d, cachemocks.NewMockUserCache(ctrl), logger.NewNopLogger()), d)
+			revokeTime, err := repo.TokenRevokeTime(context.Background(), 123)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantTime, revokeTime)
+		})
+	}
+}
+
+func TestDBRevokeUserRepository_RevokeTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockUserDAO(ctrl)
+	before := time.Now().UnixMilli()
+	d.EXPECT().UpdateRevokeTime(gomock.Any(), int64(123), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, id int64, revokeTime int64) error {
+			assert.GreaterOrEqual(t, revokeTime, before)
+			return nil
+		})
+	repo := NewDBRevokeUserRepository(
+		NewUserRepository(d, cachemocks.NewMockUserCache(ctrl), logger.NewNopLogger()), d)
+	assert.NoError(t, repo.RevokeTokens(context.Background(), 123))
+}
@@ -6,6 +6,7 @@ import (
 	cachemocks "basic-go/mybook/internal/repository/cache/mocks"
 	"basic-go/mybook/internal/repository/dao"
 	daomocks "basic-go/mybook/internal/repository/dao/mocks"
+	"basic-go/mybook/pkg/logger"
 	"context"
 	"database/sql"
 	"errors"
@@ -136,7 +137,7 @@ func TestCacheUserRepository_FindById(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			ud, uc := tc.mock(ctrl)
-			repo := NewUserRepository(ud, uc)
+			repo := NewUserRepository(ud, uc, logger.NewNopLogger())
 			u, err := repo.FindById(tc.ctx, tc.id)
 			assert.Equal(t, tc.wantErr, err)
 			assert.Equal(t, tc.wantUser, u)
@@ -146,3 +147,68 @@ func TestCacheUserRepository_FindById(t *testing.T) {
 		})
 	}
 }
+
+func TestCacheUserRepository_List(t *testing.T) {
+	query := domain.UserListQuery{
+		OrderBy: domain.UserOrderByCreateTime,
+		Desc:    true,
+		Limit:   2,
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockUserDAO(ctrl)
+	//第一页，多查一条，说明还有下一页
+	d.EXPECT().List(gomock.Any(), dao.UserListQuery{
+		OrderBy: dao.UserOrderByCreateTime,
+		Desc:    true,
+		Limit:   3,
+	}).Return([]dao.User{
+		{Id: 3, CreateTime: 300},
+		{Id: 2, CreateTime: 200},
+		{Id: 1, CreateTime: 100},
+	}, nil)
+	//第二页，从上一页的最后一条开始
+	d.EXPECT().List(gomock.Any(), dao.UserListQuery{
+		OrderBy: dao.UserOrderByCreateTime,
+		Desc:    true,
+		After:   &dao.UserCursor{Id: 2, CreateTime: 200},
+		Limit:   3,
+	}).Return([]dao.User{
+		{Id: 1, CreateTime: 100},
+	}, nil)
+	repo := NewUserRepository(d, cachemocks.NewMockUserCache(ctrl), logger.NewNopLogger())
+
+	res, err := repo.List(context.Background(), query)
+	assert.NoError(t, err)
+	assert.Len(t, res.Users, 2)
+	assert.NotEmpty(t, res.NextCursor)
+
+	query.Cursor = res.NextCursor
+	res, err = repo.List(context.Background(), query)
+	assert.NoError(t, err)
+	assert.Len(t, res.Users, 1)
+	assert.Empty(t, res.NextCursor)
+
+	//换了排序方式，游标就不能用了
+	query.Desc = false
+	_, err = repo.List(context.Background(), query)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+// TestCacheUserRepository_ListLimit 没传 Limit 的时候用默认值，不能越界
+func TestCacheUserRepository_ListLimit(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		ctrl := gomock.NewController(t)
+		d := daomocks.NewMockUserDAO(ctrl)
+		d.EXPECT().List(gomock.Any(), dao.UserListQuery{
+			OrderBy: dao.UserOrderById,
+			Limit:   defaultListLimit + 1,
+		}).Return([]dao.User{{Id: 1}}, nil)
+		repo := NewUserRepository(d, cachemocks.NewMockUserCache(ctrl), logger.NewNopLogger())
+		res, err := repo.List(context.Background(), domain.UserListQuery{Limit: limit})
+		assert.NoError(t, err)
+		assert.Len(t, res.Users, 1)
+		assert.Empty(t, res.NextCursor)
+		ctrl.Finish()
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/repository"
+	"basic-go/mybook/internal/service/oss"
+	"basic-go/mybook/pkg/logger"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrAvatarTooLarge = errors.New("头像太大")
+	ErrAvatarInvalid  = errors.New("不支持的图片格式")
+)
+
+const (
+	// AvatarMaxSize 上传的原图最大 5M
+	AvatarMaxSize = 5 << 20
+	// 宽高超过这个的直接拒绝，防止一张很小的图解码出来占几个 G 内存
+	avatarMaxPixels = 4096 * 4096
+	// 原图最长边缩到 1024
+	avatarMaxEdge   = 1024
+	avatarThumbEdge = 200
+	avatarQuality   = 85
+	avatarURLExpire = time.Hour
+)
+
+// 只认内容，不认文件名和前端传的 Content-Type
+var avatarMIMETypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type AvatarServicePackage interface {
+	// Upload 校验图片，重新编码成 jpeg 并生成缩略图，存好之后换掉用户原来的头像
+	// 返回新头像的 key
+	Upload(ctx context.Context, userId int64, r io.Reader) (string, error)
+	// URLs 给头像和缩略图签名，没有头像的时候返回空字符串
+	URLs(ctx context.Context, avatar string) (url string, thumb string, err error)
+}
+
+type AvatarService struct {
+	repo    repository.UserRepository
+	storage oss.ObjectStorage
+	now     func() time.Time
+	l       logger.Logger
+}
+
+func NewAvatarService(repo repository.UserRepository, storage oss.ObjectStorage, l logger.Logger) AvatarServicePackage {
+	return &AvatarService{
+		repo:    repo,
+		storage: storage,
+		now:     time.Now,
+		l:       l,
+	}
+}
+
+func (svc *AvatarService) Upload(ctx context.Context, userId int64, r io.Reader) (string, error) {
+	u, err := svc.repo.FindById(ctx, userId)
+	if err == repository.ErrUserNotFund {
+		return "", ErrUserDataNotFund
+	}
+	if err != nil {
+		return "", err
+	}
+	if u.Status != domain.UserStatusActive {
+		return "", ErrUserDataNotFund
+	}
+	// 多读一个字节，读得到就说明超了
+	data, err := io.ReadAll(io.LimitReader(r, AvatarMaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > AvatarMaxSize {
+		return "", ErrAvatarTooLarge
+	}
+	avatar, thumb, err := svc.process(data)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("avatars/%d/%d.jpg", userId, svc.now().UnixMilli())
+	if err = svc.put(ctx, key, avatar); err != nil {
+		return "", err
+	}
+	if err = svc.put(ctx, domain.AvatarThumbKey(key), thumb); err != nil {
+		return "", err
+	}
+	if err = svc.repo.UpdateAvatar(ctx, userId, key); err != nil {
+		return "", err
+	}
+	if u.Avatar != "" {
+		// 旧的删不掉也不影响用户，打个日志就行
+		for _, old := range []string{u.Avatar, domain.AvatarThumbKey(u.Avatar)} {
+			if er := svc.storage.Delete(ctx, old); er != nil {
+				svc.l.WithContext(ctx).Warn("删除旧头像失败", logger.String("key", old), logger.Error(er))
+			}
+		}
+	}
+	return key, nil
+}
+
+func (svc *AvatarService) URLs(ctx context.Context, avatar string) (string, string, error) {
+	if avatar == "" {
+		return "", "", nil
+	}
+	url, err := svc.storage.SignURL(ctx, avatar, avatarURLExpire)
+	if err != nil {
+		return "", "", err
+	}
+	thumb, err := svc.storage.SignURL(ctx, domain.AvatarThumbKey(avatar), avatarURLExpire)
+	if err != nil {
+		return "", "", err
+	}
+	return url, thumb, nil
+}
+
+func (svc *AvatarService) put(ctx context.Context, key string, data []byte) error {
+	return svc.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg")
+}
+
+// process 重新编码可以去掉 EXIF 之类的信息，也能挡住伪装成图片的文件
+func (svc *AvatarService) process(data []byte) (avatar []byte, thumb []byte, err error) {
+	if !avatarMIMETypes[http.DetectContentType(data)] {
+		return nil, nil, ErrAvatarInvalid
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > avatarMaxPixels {
+		return nil, nil, ErrAvatarInvalid
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrAvatarInvalid
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > avatarMaxEdge || h > avatarMaxEdge {
+		if w > h {
+			w, h = avatarMaxEdge, h*avatarMaxEdge/w
+		} else {
+			w, h = w*avatarMaxEdge/h, avatarMaxEdge
+		}
+	}
+	// 很细长的图按比例缩完可能是 0
+	if w == 0 {
+		w = 1
+	}
+	if h == 0 {
+		h = 1
+	}
+	avatar, err = encodeJPEG(scale(src, b, w, h))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 缩略图取中间的正方形
+	edge := b.Dx()
+	if b.Dy() < edge {
+		edge = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-edge)/2
+	y0 := b.Min.Y + (b.Dy()-edge)/2
+	square := image.Rect(x0, y0, x0+edge, y0+edge)
+	thumb, err = encodeJPEG(scale(src, square, avatarThumbEdge, avatarThumbEdge))
+	if err != nil {
+		return nil, nil, err
+	}
+	return avatar, thumb, nil
+}
+
+// scale 把 src 里 sr 这一块缩放到 w*h，透明的地方填白色，jpeg 没有透明通道
+func scale(src image.Image, sr image.Rectangle, w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, sr, draw.Over, nil)
+	return dst
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: avatarQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"basic-go/mybook/internal/domain"
+	repomocks "basic-go/mybook/internal/repository/mocks"
+	"basic-go/mybook/internal/service/oss"
+	ossmocks "basic-go/mybook/internal/service/oss/mocks"
+	"basic-go/mybook/pkg/logger"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestAvatarService_Upload(t *testing.T) {
+	now := time.UnixMilli(1697700000000)
+	img := image.NewNRGBA(image.Rect(0, 0, 2048, 1024))
+	img.Set(10, 10, color.NRGBA{R: 255, A: 255})
+	var pngData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, img))
+
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (*repomocks.MockUserRepository, oss.ObjectStorage)
+		data    []byte
+		wantKey string
+		wantErr error
+	}{
+		{
+			name: "上传成功，删掉旧头像",
+			mock: func(ctrl *gomock.Controller) (*repomocks.MockUserRepository, oss.ObjectStorage) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				storage := ossmocks.NewMockObjectStorage(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Avatar: "avatars/123/1.jpg"}, nil)
+				storage.EXPECT().Put(gomock.Any(), "avatars/123/1697700000000.jpg", gomock.Any(), gomock.Any(), "image/jpeg").
+					DoAndReturn(func(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+						// 最长边缩到 1024
+						cfg, err := jpeg.DecodeConfig(r)
+						require.NoError(t, err)
+						assert.Equal(t, 1024, cfg.Width)
+						assert.Equal(t, 512, cfg.Height)
+						return nil
+					})
+				storage.EXPECT().Put(gomock.Any(), "avatars/123/1697700000000_thumb.jpg", gomock.Any(), gomock.Any(), "image/jpeg").
+					DoAndReturn(func(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+						cfg, err := jpeg.DecodeConfig(r)
+						require.NoError(t, err)
+						assert.Equal(t, avatarThumbEdge, cfg.Width)
+						assert.Equal(t, avatarThumbEdge, cfg.Height)
+						return nil
+					})
+				repo.EXPECT().UpdateAvatar(gomock.Any(), int64(123), "avatars/123/1697700000000.jpg").Return(nil)
+				storage.EXPECT().Delete(gomock.Any(), "avatars/123/1.jpg").Return(nil)
+				storage.EXPECT().Delete(gomock.Any(), "avatars/123/1_thumb.jpg").Return(nil)
+				return repo, storage
+			},
+			data:    pngData.Bytes(),
+			wantKey: "avatars/123/1697700000000.jpg",
+		},
+		{
+			name: "不是图片",
+			mock: func(ctrl *gomock.Controller) (*repomocks.MockUserRepository, oss.ObjectStorage) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				return repo, ossmocks.NewMockObjectStorage(ctrl)
+			},
+			data:    []byte("<html><script>alert(1)</script></html>"),
+			wantErr: ErrAvatarInvalid,
+		},
+		{
+			name: "太大",
+			mock: func(ctrl *gomock.Controller) (*repomocks.MockUserRepository, oss.ObjectStorage) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				return repo, ossmocks.NewMockObjectStorage(ctrl)
+			},
+			data:    make([]byte, AvatarMaxSize+1),
+			wantErr: ErrAvatarTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, storage := tc.mock(ctrl)
+			svc := NewAvatarService(repo, storage, logger.NewNopLogger()).(*AvatarService)
+			svc.now = func() time.Time {
+				return now
+			}
+			key, err := svc.Upload(context.Background(), 123, bytes.NewReader(tc.data))
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantKey, key)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"basic-go/mybook/internal/repository"
 	"basic-go/mybook/internal/service/sms"
+	"basic-go/mybook/pkg/logger"
 	"context"
 	"fmt"
 	"math/rand"
@@ -25,12 +26,14 @@ type CodeServicePackage interface {
 type CodeService struct {
 	repo   repository.CodeRepository
 	smsSvc sms.Service
+	l      logger.Logger
 }
 
-func NewCodeService(repo repository.CodeRepository, smsSvc sms.Service) CodeServicePackage {
+func NewCodeService(repo repository.CodeRepository, smsSvc sms.Service, l logger.Logger) CodeServicePackage {
 	return &CodeService{
 		repo:   repo,
 		smsSvc: smsSvc,
+		l:      l,
 	}
 }
 
@@ -50,7 +53,12 @@ func (svc *CodeService) Send(ctx context.Context, biz string, phone string) erro
 }
 
 func (svc *CodeService) Verify(ctx context.Context, biz, phone, inputCode string) (bool, error) {
-	return svc.repo.Verify(ctx, biz, phone, inputCode)
+	ok, err := svc.repo.Verify(ctx, biz, phone, inputCode)
+	if err == ErrCodeVerifyTooManyTimes {
+		// 一直输错很可能是有人在攻击
+		svc.l.WithContext(ctx).Warn("验证码校验次数过多", logger.String("biz", biz), logger.String("phone", phone))
+	}
+	return ok, err
 }
 
 func (svc *CodeService) generateCode() string {
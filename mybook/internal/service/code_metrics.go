@@ -0,0 +1,68 @@
+package service
+
+import (
+	"basic-go/mybook/pkg/metricsx"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsCodeService 按 biz 统计验证码发送和校验的结果
+// 校验次数过多的突然变多，很可能是有人在撞验证码
+type MetricsCodeService struct {
+	svc    CodeServicePackage
+	send   *prometheus.CounterVec
+	verify *prometheus.CounterVec
+}
+
+func NewMetricsCodeService(svc CodeServicePackage) CodeServicePackage {
+	return &MetricsCodeService{
+		svc: svc,
+		send: metricsx.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mybook",
+			Subsystem: "code",
+			Name:      "send_total",
+			Help:      "验证码发送的结果",
+		}, []string{"biz", "result"})),
+		verify: metricsx.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mybook",
+			Subsystem: "code",
+			Name:      "verify_total",
+			Help:      "验证码校验的结果",
+		}, []string{"biz", "result"})),
+	}
+}
+
+func (m *MetricsCodeService) Send(ctx context.Context, biz string, phone string) error {
+	err := m.svc.Send(ctx, biz, phone)
+	m.send.WithLabelValues(biz, codeResult(err)).Inc()
+	return err
+}
+
+func (m *MetricsCodeService) Verify(ctx context.Context, biz, phone, inputCode string) (bool, error) {
+	ok, err := m.svc.Verify(ctx, biz, phone, inputCode)
+	result := codeResult(err)
+	if err == nil && !ok {
+		result = "incorrect"
+	}
+	m.verify.WithLabelValues(biz, result).Inc()
+	return ok, err
+}
+
+// codeResult label 的值是固定的几个，不要直接用 err.Error()
+func codeResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrCodeSendTooMany):
+		return "send_too_many"
+	case errors.Is(err, ErrCodeVerifyTooManyTimes):
+		return "too_many"
+	case errors.Is(err, ErrCodeInvalid):
+		return "invalid"
+	case errors.Is(err, ErrCodeTimeOut):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
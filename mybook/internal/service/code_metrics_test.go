@@ -0,0 +1,74 @@
+package service
+
+import (
+	repomocks "basic-go/mybook/internal/repository/mocks"
+	smsmocks "basic-go/mybook/internal/service/sms/mocks"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestMetricsCodeService_Send(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		result string
+	}{
+		{name: "发送成功", result: "ok"},
+		{name: "发送太频繁", err: ErrCodeSendTooMany, result: "send_too_many"},
+		{name: "系统错误", err: errors.New("mock error"), result: "error"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := repomocks.NewMockCodeRepository(ctrl)
+			repo.EXPECT().Store(gomock.Any(), "login", "13511112222", gomock.Any()).Return(tc.err)
+			smsSvc := smsmocks.NewMockService(ctrl)
+			if tc.err == nil {
+				smsSvc.EXPECT().Send(gomock.Any(), codeTplId, gomock.Any(), "13511112222").Return(nil)
+			}
+			svc := NewMetricsCodeService(NewCodeService(repo, smsSvc, logger.NewNopLogger())).(*MetricsCodeService)
+			before := testutil.ToFloat64(svc.send.WithLabelValues("login", tc.result))
+
+			err := svc.Send(context.Background(), "login", "13511112222")
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, before+1, testutil.ToFloat64(svc.send.WithLabelValues("login", tc.result)))
+		})
+	}
+}
+
+func TestMetricsCodeService_Verify(t *testing.T) {
+	testCases := []struct {
+		name   string
+		ok     bool
+		err    error
+		result string
+	}{
+		{name: "校验通过", ok: true, result: "ok"},
+		{name: "验证码不对", result: "incorrect"},
+		{name: "校验次数过多", err: ErrCodeVerifyTooManyTimes, result: "too_many"},
+		{name: "验证码失效", err: ErrCodeInvalid, result: "invalid"},
+		{name: "验证码过期", err: ErrCodeTimeOut, result: "timeout"},
+		{name: "系统错误", err: errors.New("mock error"), result: "error"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := repomocks.NewMockCodeRepository(ctrl)
+			repo.EXPECT().Verify(gomock.Any(), "login", "13511112222", "123456").Return(tc.ok, tc.err)
+			svc := NewMetricsCodeService(NewCodeService(repo, smsmocks.NewMockService(ctrl), logger.NewNopLogger())).(*MetricsCodeService)
+			before := testutil.ToFloat64(svc.verify.WithLabelValues("login", tc.result))
+
+			ok, err := svc.Verify(context.Background(), "login", "13511112222", "123456")
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, before+1, testutil.ToFloat64(svc.verify.WithLabelValues("login", tc.result)))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: mybook/internal/service/avatar.go
+//
+// Generated by this command:
+//
+//	mockgen -source=mybook/internal/service/avatar.go -package=svcmocks -destination=mybook/internal/service/mocks/avatar.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAvatarServicePackage is a mock of AvatarServicePackage interface.
+type MockAvatarServicePackage struct {
+	ctrl     *gomock.Controller
+	recorder *MockAvatarServicePackageMockRecorder
+}
+
+// MockAvatarServicePackageMockRecorder is the mock recorder for MockAvatarServicePackage.
+type MockAvatarServicePackageMockRecorder struct {
+	mock *MockAvatarServicePackage
+}
+
+// NewMockAvatarServicePackage creates a new mock instance.
+func NewMockAvatarServicePackage(ctrl *gomock.Controller) *MockAvatarServicePackage {
+	mock := &MockAvatarServicePackage{ctrl: ctrl}
+	mock.recorder = &MockAvatarServicePackageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAvatarServicePackage) EXPECT() *MockAvatarServicePackageMockRecorder {
+	return m.recorder
+}
+
+// URLs mocks base method.
+func (m *MockAvatarServicePackage) URLs(ctx context.Context, avatar string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "URLs", ctx, avatar)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// URLs indicates an expected call of URLs.
+func (mr *MockAvatarServicePackageMockRecorder) URLs(ctx, avatar any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "URLs", reflect.TypeOf((*MockAvatarServicePackage)(nil).URLs), ctx, avatar)
+}
+
+// Upload mocks base method.
+func (m *MockAvatarServicePackage) Upload(ctx context.Context, userId int64, r io.Reader) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upload", ctx, userId, r)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upload indicates an expected call of Upload.
+func (mr *MockAvatarServicePackageMockRecorder) Upload(ctx, userId, r any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockAvatarServicePackage)(nil).Upload), ctx, userId, r)
+}
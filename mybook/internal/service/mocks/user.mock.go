@@ -12,6 +12,7 @@ import (
 	domain "basic-go/mybook/internal/domain"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,34 @@ func (m *MockUserServicePackage) EXPECT() *MockUserServicePackageMockRecorder {
 	return m.recorder
 }
 
+// Deactivate mocks base method.
+func (m *MockUserServicePackage) Deactivate(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deactivate", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Deactivate indicates an expected call of Deactivate.
+func (mr *MockUserServicePackageMockRecorder) Deactivate(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deactivate", reflect.TypeOf((*MockUserServicePackage)(nil).Deactivate), ctx, userId)
+}
+
+// Disable mocks base method.
+func (m *MockUserServicePackage) Disable(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockUserServicePackageMockRecorder) Disable(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockUserServicePackage)(nil).Disable), ctx, userId)
+}
+
 // Edit mocks base method.
 func (m *MockUserServicePackage) Edit(ctx context.Context, u domain.User) error {
 	m.ctrl.T.Helper()
@@ -53,6 +82,20 @@ func (mr *MockUserServicePackageMockRecorder) Edit(ctx, u any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Edit", reflect.TypeOf((*MockUserServicePackage)(nil).Edit), ctx, u)
 }
 
+// Enable mocks base method.
+func (m *MockUserServicePackage) Enable(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockUserServicePackageMockRecorder) Enable(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockUserServicePackage)(nil).Enable), ctx, userId)
+}
+
 // FindById mocks base method.
 func (m *MockUserServicePackage) FindById(ctx context.Context, userId int64) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -68,6 +111,21 @@ func (mr *MockUserServicePackageMockRecorder) FindById(ctx, userId any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockUserServicePackage)(nil).FindById), ctx, userId)
 }
 
+// FindByIdAnyStatus mocks base method.
+func (m *MockUserServicePackage) FindByIdAnyStatus(ctx context.Context, userId int64) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByIdAnyStatus", ctx, userId)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByIdAnyStatus indicates an expected call of FindByIdAnyStatus.
+func (mr *MockUserServicePackageMockRecorder) FindByIdAnyStatus(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByIdAnyStatus", reflect.TypeOf((*MockUserServicePackage)(nil).FindByIdAnyStatus), ctx, userId)
+}
+
 // FindOrCreate mocks base method.
 func (m *MockUserServicePackage) FindOrCreate(ctx context.Context, phone string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -83,6 +141,35 @@ func (mr *MockUserServicePackageMockRecorder) FindOrCreate(ctx, phone any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreate", reflect.TypeOf((*MockUserServicePackage)(nil).FindOrCreate), ctx, phone)
 }
 
+// ForceLogout mocks base method.
+func (m *MockUserServicePackage) ForceLogout(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceLogout", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForceLogout indicates an expected call of ForceLogout.
+func (mr *MockUserServicePackageMockRecorder) ForceLogout(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceLogout", reflect.TypeOf((*MockUserServicePackage)(nil).ForceLogout), ctx, userId)
+}
+
+// List mocks base method.
+func (m *MockUserServicePackage) List(ctx context.Context, q domain.UserListQuery) (domain.UserListResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, q)
+	ret0, _ := ret[0].(domain.UserListResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockUserServicePackageMockRecorder) List(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserServicePackage)(nil).List), ctx, q)
+}
+
 // Login mocks base method.
 func (m *MockUserServicePackage) Login(ctx context.Context, email, password string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -113,6 +200,51 @@ func (mr *MockUserServicePackageMockRecorder) Profile(ctx, id any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockUserServicePackage)(nil).Profile), ctx, id)
 }
 
+// PurgeExpired mocks base method.
+func (m *MockUserServicePackage) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpired", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpired indicates an expected call of PurgeExpired.
+func (mr *MockUserServicePackageMockRecorder) PurgeExpired(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpired", reflect.TypeOf((*MockUserServicePackage)(nil).PurgeExpired), ctx, limit)
+}
+
+// RestoreByEmail mocks base method.
+func (m *MockUserServicePackage) RestoreByEmail(ctx context.Context, email, password string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreByEmail", ctx, email, password)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreByEmail indicates an expected call of RestoreByEmail.
+func (mr *MockUserServicePackageMockRecorder) RestoreByEmail(ctx, email, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreByEmail", reflect.TypeOf((*MockUserServicePackage)(nil).RestoreByEmail), ctx, email, password)
+}
+
+// RestoreByPhone mocks base method.
+func (m *MockUserServicePackage) RestoreByPhone(ctx context.Context, phone string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreByPhone", ctx, phone)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreByPhone indicates an expected call of RestoreByPhone.
+func (mr *MockUserServicePackageMockRecorder) RestoreByPhone(ctx, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreByPhone", reflect.TypeOf((*MockUserServicePackage)(nil).RestoreByPhone), ctx, phone)
+}
+
 // SignUp mocks base method.
 func (m *MockUserServicePackage) SignUp(ctx context.Context, u domain.User) error {
 	m.ctrl.T.Helper()
@@ -126,3 +258,32 @@ func (mr *MockUserServicePackageMockRecorder) SignUp(ctx, u any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockUserServicePackage)(nil).SignUp), ctx, u)
 }
+
+// TokenRevoked mocks base method.
+func (m *MockUserServicePackage) TokenRevoked(ctx context.Context, userId int64, issuedAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TokenRevoked", ctx, userId, issuedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TokenRevoked indicates an expected call of TokenRevoked.
+func (mr *MockUserServicePackageMockRecorder) TokenRevoked(ctx, userId, issuedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TokenRevoked", reflect.TypeOf((*MockUserServicePackage)(nil).TokenRevoked), ctx, userId, issuedAt)
+}
+
+// UpdateRole mocks base method.
+func (m *MockUserServicePackage) UpdateRole(ctx context.Context, userId int64, role domain.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", ctx, userId, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockUserServicePackageMockRecorder) UpdateRole(ctx, userId, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockUserServicePackage)(nil).UpdateRole), ctx, userId, role)
+}
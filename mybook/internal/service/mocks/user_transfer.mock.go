@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: mybook/internal/service/user_transfer.go
+//
+// Generated by this command:
+//
+//	mockgen -source=mybook/internal/service/user_transfer.go -package=svcmocks -destination=mybook/internal/service/mocks/user_transfer.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	domain "basic-go/mybook/internal/domain"
+	service "basic-go/mybook/internal/service"
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserTransferServicePackage is a mock of UserTransferServicePackage interface.
+type MockUserTransferServicePackage struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserTransferServicePackageMockRecorder
+}
+
+// MockUserTransferServicePackageMockRecorder is the mock recorder for MockUserTransferServicePackage.
+type MockUserTransferServicePackageMockRecorder struct {
+	mock *MockUserTransferServicePackage
+}
+
+// NewMockUserTransferServicePackage creates a new mock instance.
+func NewMockUserTransferServicePackage(ctrl *gomock.Controller) *MockUserTransferServicePackage {
+	mock := &MockUserTransferServicePackage{ctrl: ctrl}
+	mock.recorder = &MockUserTransferServicePackageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserTransferServicePackage) EXPECT() *MockUserTransferServicePackageMockRecorder {
+	return m.recorder
+}
+
+// Export mocks base method.
+func (m *MockUserTransferServicePackage) Export(ctx context.Context, w io.Writer, format string, filter domain.UserFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, w, format, filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockUserTransferServicePackageMockRecorder) Export(ctx, w, format, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockUserTransferServicePackage)(nil).Export), ctx, w, format, filter)
+}
+
+// Import mocks base method.
+func (m *MockUserTransferServicePackage) Import(ctx context.Context, r io.Reader, format string) (service.ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", ctx, r, format)
+	ret0, _ := ret[0].(service.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockUserTransferServicePackageMockRecorder) Import(ctx, r, format any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockUserTransferServicePackage)(nil).Import), ctx, r, format)
+}
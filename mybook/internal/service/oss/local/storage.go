@@ -0,0 +1,123 @@
+package local
+
+import (
+	"basic-go/mybook/internal/service/oss"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidKey = errors.New("非法的 key")
+
+// Storage 存在本地磁盘上，开发环境用
+// 签名的 URL 由 Storage 自己校验，所以它也是一个 http.Handler，挂在 baseURL 对应的路由下面
+type Storage struct {
+	dir string
+	// 例如 http://localhost:8080/files
+	baseURL string
+	secret  []byte
+	now     func() time.Time
+}
+
+var _ oss.ObjectStorage = (*Storage)(nil)
+
+func NewStorage(dir, baseURL, secret string) *Storage {
+	return &Storage{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  []byte(secret),
+		now:     time.Now,
+	}
+}
+
+func (s *Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，避免别人读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *Storage) SignURL(ctx context.Context, key string, expire time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(s.now().Add(expire).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("sign", s.sign(key, expires))
+	return s.baseURL + "/" + key + "?" + q.Encode(), nil
+}
+
+// ServeHTTP 校验签名和过期时间，通过了才返回文件
+// 路由要把 baseURL 的前缀去掉，例如 http.StripPrefix("/files", storage)
+func (s *Storage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	expires := r.URL.Query().Get("expires")
+	sign := r.URL.Query().Get("sign")
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.now().Unix() > exp ||
+		!hmac.Equal([]byte(sign), []byte(s.sign(key, expires))) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	p, err := s.path(key)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	http.ServeFile(w, r, p)
+}
+
+func (s *Storage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path 不允许 key 跳出 dir，例如 ../../etc/passwd
+func (s *Storage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,71 @@
+package local
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStorage(t *testing.T) {
+	s := NewStorage(t.TempDir(), "http://localhost:8080/files", "secret")
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, "avatars/1/a.jpg", strings.NewReader("hello"), 5, "image/jpeg"))
+
+	u, err := s.SignURL(ctx, "avatars/1/a.jpg", time.Minute)
+	require.NoError(t, err)
+	h := http.StripPrefix("/files", s)
+
+	testCases := []struct {
+		name     string
+		url      string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "签名正确",
+			url:      u,
+			wantCode: http.StatusOK,
+			wantBody: "hello",
+		},
+		{
+			name:     "签名不对",
+			url:      strings.Replace(u, "sign=", "sign=0", 1),
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "拿别的文件的签名",
+			url:      strings.Replace(u, "a.jpg", "b.jpg", 1),
+			wantCode: http.StatusForbidden,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, resp.Body.String())
+			}
+		})
+	}
+
+	// 过期了
+	s.now = func() time.Time {
+		return time.Now().Add(time.Hour)
+	}
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, u, nil))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	_, err = s.SignURL(ctx, "../etc/passwd", time.Minute)
+	assert.Equal(t, ErrInvalidKey, err)
+	require.NoError(t, s.Delete(ctx, "avatars/1/a.jpg"))
+	// 删不存在的不报错
+	require.NoError(t, s.Delete(ctx, "avatars/1/a.jpg"))
+}
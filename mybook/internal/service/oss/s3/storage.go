@@ -0,0 +1,44 @@
+package s3
+
+import (
+	"basic-go/mybook/internal/service/oss"
+	"context"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"time"
+)
+
+// Storage 兼容 S3 协议的对象存储，本地可以用 MinIO 代替
+type Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+var _ oss.ObjectStorage = (*Storage)(nil)
+
+func NewStorage(client *minio.Client, bucket string) *Storage {
+	return &Storage{
+		client: client,
+		bucket: bucket,
+	}
+}
+
+func (s *Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	// S3 删除不存在的对象也是成功的
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *Storage) SignURL(ctx context.Context, key string, expire time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expire, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
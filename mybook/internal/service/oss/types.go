@@ -0,0 +1,17 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ObjectStorage 对象存储，key 是对象在存储里的路径，例如 avatars/123/1697700000000.jpg
+// 数据库里只存 key，返回给前端的时候再签名成 URL，换存储不用改数据
+type ObjectStorage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Delete 对象不存在不算错
+	Delete(ctx context.Context, key string) error
+	// SignURL 生成一个 expire 之后失效的下载地址
+	SignURL(ctx context.Context, key string, expire time.Duration) (string, error)
+}
@@ -1,18 +1,21 @@
 package memory
 
 import (
+	"basic-go/mybook/pkg/logger"
 	"context"
-	"fmt"
 )
 
+// Service 不真的发，打到日志里，本地开发的时候从日志里看验证码
 type Service struct {
+	l logger.Logger
 }
 
-func NewService() *Service {
-	return &Service{}
+func NewService(l logger.Logger) *Service {
+	return &Service{l: l}
 }
 
 func (s *Service) Send(ctx context.Context, tpl string, args []string, number ...string) error {
-	fmt.Println(args)
+	s.l.WithContext(ctx).Info("发送短信", logger.String("tpl", tpl),
+		logger.Any("args", args), logger.Any("numbers", number))
 	return nil
 }
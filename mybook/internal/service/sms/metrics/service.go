@@ -0,0 +1,38 @@
+// Package metrics 统计短信发送的结果和耗时
+package metrics
+
+import (
+	"basic-go/mybook/internal/service/sms"
+	"basic-go/mybook/pkg/metricsx"
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Service 装饰器，provider 区分不同的短信服务商
+type Service struct {
+	svc      sms.Service
+	provider string
+	vector   *prometheus.HistogramVec
+}
+
+func NewService(svc sms.Service, provider string) sms.Service {
+	return &Service{
+		svc:      svc,
+		provider: provider,
+		vector: metricsx.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "mybook",
+			Subsystem: "sms",
+			Name:      "send_duration_seconds",
+			Help:      "短信发送的耗时和结果",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"provider", "status"})),
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tpl string, args []string, number ...string) error {
+	start := time.Now()
+	err := s.svc.Send(ctx, tpl, args, number...)
+	s.vector.WithLabelValues(s.provider, metricsx.Status(err)).Observe(time.Since(start).Seconds())
+	return err
+}
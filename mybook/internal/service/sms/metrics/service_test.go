@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	smsmocks "basic-go/mybook/internal/service/sms/mocks"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status string
+	}{
+		{name: "发送成功", status: "ok"},
+		{name: "发送失败", err: errors.New("mock error"), status: "error"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mock := smsmocks.NewMockService(ctrl)
+			mock.EXPECT().Send(gomock.Any(), "tpl", []string{"123456"}, "13511112222").Return(tc.err)
+			svc := NewService(mock, "test_"+tc.status).(*Service)
+
+			err := svc.Send(context.Background(), "tpl", []string{"123456"}, "13511112222")
+			assert.Equal(t, tc.err, err)
+			var m dto.Metric
+			require.NoError(t, svc.vector.WithLabelValues("test_"+tc.status, tc.status).(prometheus.Metric).Write(&m))
+			assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Package tracing 给短信发送加上 span
+package tracing
+
+import (
+	"basic-go/mybook/internal/service/sms"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "basic-go/mybook/internal/service/sms/tracing"
+
+// Service 装饰器，手机号和验证码都不记
+type Service struct {
+	svc      sms.Service
+	provider string
+	tracer   trace.Tracer
+}
+
+func NewService(svc sms.Service, provider string, tp trace.TracerProvider) sms.Service {
+	return &Service{
+		svc:      svc,
+		provider: provider,
+		tracer:   tp.Tracer(instrumentationName),
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tpl string, args []string, number ...string) error {
+	ctx, span := s.tracer.Start(ctx, "sms send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("sms.provider", s.provider),
+			attribute.String("sms.tpl", tpl),
+			attribute.Int("sms.number_count", len(number)),
+		))
+	defer span.End()
+	err := s.svc.Send(ctx, tpl, args, number...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	smsmocks "basic-go/mybook/internal/service/sms/mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+	}{
+		{name: "发送成功"},
+		{name: "发送失败", err: errors.New("mock error"), wantStatus: codes.Error},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			mock := smsmocks.NewMockService(ctrl)
+			var sendSpan trace.SpanContext
+			mock.EXPECT().Send(gomock.Any(), "tpl", []string{"123456"}, "13511112222").
+				DoAndReturn(func(ctx context.Context, tpl string, args []string, number ...string) error {
+					sendSpan = trace.SpanContextFromContext(ctx)
+					return tc.err
+				})
+			svc := NewService(mock, "memory", tp)
+
+			err := svc.Send(context.Background(), "tpl", []string{"123456"}, "13511112222")
+			assert.Equal(t, tc.err, err)
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, "sms send", span.Name())
+			assert.Equal(t, tc.wantStatus, span.Status().Code)
+			assert.Equal(t, span.SpanContext().SpanID(), sendSpan.SpanID())
+			assert.Contains(t, span.Attributes(), attribute.String("sms.provider", "memory"))
+			for _, attr := range span.Attributes() {
+				assert.NotContains(t, attr.Value.Emit(), "13511112222")
+				assert.NotContains(t, attr.Value.Emit(), "123456")
+			}
+		})
+	}
+}
@@ -3,14 +3,29 @@ package service
 import (
 	"basic-go/mybook/internal/domain"
 	"basic-go/mybook/internal/repository"
+	"basic-go/mybook/pkg/degrade"
+	"basic-go/mybook/pkg/logger"
 	"context"
 	"errors"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 var ErrUseDuplicateEmail = repository.ErrUseDuplicate
 var ErrInvalidUserOrPassword = errors.New("账号/邮箱或密码不对")
 var ErrUserDataNotFund = errors.New("该用户不存在！")
+var ErrUserDeactivated = errors.New("账号已注销")
+var ErrUserRestoreExpired = errors.New("已经超过注销宽限期，账号无法恢复")
+var ErrUserDisabled = errors.New("账号已被禁用")
+var ErrUserStatusConflict = repository.ErrUserStatusConflict
+var ErrInvalidRole = errors.New("角色不存在")
+var ErrInvalidCursor = repository.ErrInvalidCursor
+var ErrSystemDegraded = errors.New("系统降级")
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 1000
+)
 
 type UserServicePackage interface {
 	SignUp(ctx context.Context, u domain.User) error
@@ -18,16 +33,33 @@ type UserServicePackage interface {
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
 	Profile(ctx context.Context, id int64) (domain.User, error)
 	FindById(ctx context.Context, userId int64) (domain.User, error)
+	// FindByIdAnyStatus 后台用，禁用、注销了的也返回
+	FindByIdAnyStatus(ctx context.Context, userId int64) (domain.User, error)
 	Edit(ctx context.Context, u domain.User) error
+	Deactivate(ctx context.Context, userId int64) error
+	RestoreByEmail(ctx context.Context, email, password string) (domain.User, error)
+	RestoreByPhone(ctx context.Context, phone string) (domain.User, error)
+	PurgeExpired(ctx context.Context, limit int) (int, error)
+	List(ctx context.Context, q domain.UserListQuery) (domain.UserListResult, error)
+	Disable(ctx context.Context, userId int64) error
+	Enable(ctx context.Context, userId int64) error
+	ForceLogout(ctx context.Context, userId int64) error
+	UpdateRole(ctx context.Context, userId int64, role domain.Role) error
+	TokenRevoked(ctx context.Context, userId int64, issuedAt time.Time) (bool, error)
 }
 
 type UserService struct {
 	repo repository.UserRepository
+	// 注销之后的宽限期，宽限期内可以恢复账号
+	gracePeriod time.Duration
+	l           logger.Logger
 }
 
-func NewUserService(repo repository.UserRepository) UserServicePackage {
+func NewUserService(repo repository.UserRepository, gracePeriod time.Duration, l logger.Logger) UserServicePackage {
 	return &UserService{
-		repo: repo,
+		repo:        repo,
+		gracePeriod: gracePeriod,
+		l:           l,
 	}
 }
 
@@ -62,6 +94,13 @@ func (svc *UserService) Login(ctx context.Context, email, password string) (doma
 		//DEBUG
 		return domain.User{}, ErrInvalidUserOrPassword
 	}
+	//密码对了才告诉他账号注销了，不然别人可以拿来探测账号
+	if u.Deactivated() {
+		return domain.User{}, ErrUserDeactivated
+	}
+	if u.Disabled() {
+		return domain.User{}, ErrUserDisabled
+	}
 	return u, nil
 }
 
@@ -73,12 +112,20 @@ func (svc *UserService) FindOrCreate(ctx context.Context, phone string) (domain.
 		//绝大部分请求进来这里
 		// nil 会进来
 		// 不为 ErrUserNotFound 的也会进来这里
+		if u.Deactivated() {
+			//注销了的手机号不能再自动创建一个新用户
+			return domain.User{}, ErrUserDeactivated
+		}
+		if u.Disabled() {
+			return domain.User{}, ErrUserDisabled
+		}
 		return u, nil
 	}
-	//在系统资源不足，触发降级之后，不执行满路径
-	//if ctx.Value("降级") == "true" {
-	//	return domain.User{}, errors.New("系统降级")
-	//}
+	//在系统资源不足，触发降级之后，不执行慢路径
+	if degrade.IsDegraded(ctx) {
+		svc.l.WithContext(ctx).Warn("系统降级，不自动注册新用户", logger.String("phone", phone))
+		return domain.User{}, ErrSystemDegraded
+	}
 
 	//这个叫慢路径
 	//如果没有这个用户就创建一下
@@ -93,11 +140,7 @@ func (svc *UserService) FindOrCreate(ctx context.Context, phone string) (domain.
 }
 
 func (svc *UserService) Profile(ctx context.Context, id int64) (domain.User, error) {
-	u, err := svc.repo.FindById(ctx, id)
-	if err != nil {
-		return domain.User{}, err
-	}
-	return u, nil
+	return svc.FindById(ctx, id)
 }
 
 func (svc *UserService) FindById(ctx context.Context, userId int64) (domain.User, error) {
@@ -109,10 +152,147 @@ func (svc *UserService) FindById(ctx context.Context, userId int64) (domain.User
 	if err != nil {
 		return domain.User{}, err
 	}
+	//注销了的用户，对外就当不存在
+	if u.Status != domain.UserStatusActive {
+		return domain.User{}, ErrUserDataNotFund
+	}
 	//正常返回该条数据
 	return u, err
 }
 
+func (svc *UserService) FindByIdAnyStatus(ctx context.Context, userId int64) (domain.User, error) {
+	u, err := svc.repo.FindById(ctx, userId)
+	if err == repository.ErrUserNotFund {
+		return domain.User{}, ErrUserDataNotFund
+	}
+	return u, err
+}
+
+// Edit 修改个人信息，为空的字段保持原样
 func (svc *UserService) Edit(ctx context.Context, u domain.User) error {
+	//注销或者禁用了的用户不能改
+	if _, err := svc.FindById(ctx, u.Id); err != nil {
+		return err
+	}
 	return svc.repo.Edit(ctx, u)
 }
+
+// Deactivate 申请注销，账号立刻不可用，宽限期内可以恢复
+func (svc *UserService) Deactivate(ctx context.Context, userId int64) error {
+	err := svc.repo.Deactivate(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return svc.repo.RevokeTokens(ctx, userId)
+}
+
+// RestoreByEmail 用邮箱密码恢复账号
+func (svc *UserService) RestoreByEmail(ctx context.Context, email, password string) (domain.User, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err == repository.ErrUserNotFund {
+		return domain.User{}, ErrInvalidUserOrPassword
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	if err != nil {
+		return domain.User{}, ErrInvalidUserOrPassword
+	}
+	return svc.restore(ctx, u)
+}
+
+// RestoreByPhone 用手机号恢复账号，调用方要先校验验证码
+func (svc *UserService) RestoreByPhone(ctx context.Context, phone string) (domain.User, error) {
+	u, err := svc.repo.FindByPhone(ctx, phone)
+	if err == repository.ErrUserNotFund {
+		return domain.User{}, ErrUserDataNotFund
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	return svc.restore(ctx, u)
+}
+
+func (svc *UserService) restore(ctx context.Context, u domain.User) (domain.User, error) {
+	if u.Disabled() {
+		return domain.User{}, ErrUserDisabled
+	}
+	if !u.Deactivated() {
+		//本来就是正常的，不需要恢复
+		return u, nil
+	}
+	if !u.Restorable(time.Now(), svc.gracePeriod) {
+		return domain.User{}, ErrUserRestoreExpired
+	}
+	err := svc.repo.Restore(ctx, u.Id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	u.Status = domain.UserStatusActive
+	u.DeactivateTime = time.Time{}
+	return u, nil
+}
+
+// PurgeExpired 清理超过宽限期的用户，返回这一批清理了多少个
+func (svc *UserService) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	us, err := svc.repo.FindDeactivatedBefore(ctx,
+		time.Now().Add(-svc.gracePeriod), limit)
+	if err != nil {
+		return 0, err
+	}
+	cnt := 0
+	for _, u := range us {
+		if err = svc.repo.Purge(ctx, u.Id); err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+// List 游标分页查询用户，后台和导出都用这个
+func (svc *UserService) List(ctx context.Context, q domain.UserListQuery) (domain.UserListResult, error) {
+	if q.Limit <= 0 || q.Limit > maxListLimit {
+		q.Limit = defaultListLimit
+	}
+	return svc.repo.List(ctx, q)
+}
+
+// Disable 禁用账号，并且强制下线
+func (svc *UserService) Disable(ctx context.Context, userId int64) error {
+	err := svc.repo.UpdateStatus(ctx, userId, domain.UserStatusActive, domain.UserStatusDisabled)
+	if err != nil {
+		return err
+	}
+	return svc.repo.RevokeTokens(ctx, userId)
+}
+
+func (svc *UserService) Enable(ctx context.Context, userId int64) error {
+	return svc.repo.UpdateStatus(ctx, userId, domain.UserStatusDisabled, domain.UserStatusActive)
+}
+
+func (svc *UserService) ForceLogout(ctx context.Context, userId int64) error {
+	return svc.repo.RevokeTokens(ctx, userId)
+}
+
+// UpdateRole 修改角色之后强制下线，让他重新登录拿到新的角色
+func (svc *UserService) UpdateRole(ctx context.Context, userId int64, role domain.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	err := svc.repo.UpdateRole(ctx, userId, role)
+	if err != nil {
+		return err
+	}
+	return svc.repo.RevokeTokens(ctx, userId)
+}
+
+// TokenRevoked issuedAt 是 token 的签发时间，在强制下线之前签发的都算失效
+func (svc *UserService) TokenRevoked(ctx context.Context, userId int64, issuedAt time.Time) (bool, error) {
+	t, err := svc.repo.TokenRevokeTime(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return !t.IsZero() && !issuedAt.After(t), nil
+}
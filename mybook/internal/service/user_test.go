@@ -4,6 +4,8 @@ import (
 	"basic-go/mybook/internal/domain"
 	"basic-go/mybook/internal/repository"
 	repomocks "basic-go/mybook/internal/repository/mocks"
+	"basic-go/mybook/pkg/degrade"
+	"basic-go/mybook/pkg/logger"
 	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
@@ -13,6 +15,9 @@ import (
 	"time"
 )
 
+// testGracePeriod 故意和线上配置的不一样，确认用的是传进来的
+const testGracePeriod = 7 * 24 * time.Hour
+
 func TestUserService_Login(t *testing.T) {
 	//公共时间
 	now := time.Now()
@@ -97,7 +102,7 @@ func TestUserService_Login(t *testing.T) {
 			//具体测试代码
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
-			svc := NewUserService(tc.mock(ctrl))
+			svc := NewUserService(tc.mock(ctrl), testGracePeriod, logger.NewNopLogger())
 			u, err := svc.Login(context.Background(), tc.email, tc.password)
 			assert.Equal(t, tc.wantErr, err)
 			assert.Equal(t, tc.wantUser, u)
@@ -111,3 +116,193 @@ func TestEncrypted(t *testing.T) {
 		t.Log(string(res))
 	}
 }
+
+func TestUserService_RestoreByEmail(t *testing.T) {
+	now := time.Now()
+	const hash = "$2a$10$0X2rBtMoM3moeaboBTZS3.C1gnnTWHZCqAT1fJH0yEtosnWprNH7S"
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		email    string
+		password string
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "宽限期内恢复成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{
+						Id:             123,
+						Email:          "123@qq.com",
+						Password:       hash,
+						Status:         domain.UserStatusDeactivated,
+						DeactivateTime: now.Add(-time.Hour),
+					}, nil)
+				repo.EXPECT().Restore(gomock.Any(), int64(123)).Return(nil)
+				return repo
+			},
+			email:    "123@qq.com",
+			password: "Qq@adm331",
+			wantUser: domain.User{
+				Id:       123,
+				Email:    "123@qq.com",
+				Password: hash,
+				Status:   domain.UserStatusActive,
+			},
+		},
+		{
+			name: "超过宽限期",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{
+						Id:             123,
+						Email:          "123@qq.com",
+						Password:       hash,
+						Status:         domain.UserStatusDeactivated,
+						DeactivateTime: now.Add(-testGracePeriod - time.Hour),
+					}, nil)
+				return repo
+			},
+			email:    "123@qq.com",
+			password: "Qq@adm331",
+			wantUser: domain.User{},
+			wantErr:  ErrUserRestoreExpired,
+		},
+		{
+			name: "密码不对",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{
+						Id:       123,
+						Password: hash,
+						Status:   domain.UserStatusDeactivated,
+					}, nil)
+				return repo
+			},
+			email:    "123@qq.com",
+			password: "Qq@adm332",
+			wantUser: domain.User{},
+			wantErr:  ErrInvalidUserOrPassword,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl), testGracePeriod, logger.NewNopLogger())
+			u, err := svc.RestoreByEmail(context.Background(), tc.email, tc.password)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func TestUserService_FindOrCreate(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		ctx   context.Context
+		phone string
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "已注销的手机号",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "13511111111").
+					Return(domain.User{
+						Id:     123,
+						Phone:  "13511111111",
+						Status: domain.UserStatusDeactivated,
+					}, nil)
+				return repo
+			},
+			phone:    "13511111111",
+			wantUser: domain.User{},
+			wantErr:  ErrUserDeactivated,
+		},
+		{
+			name: "新用户",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "13511111111").
+					Return(domain.User{}, repository.ErrUserNotFund)
+				repo.EXPECT().Created(gomock.Any(), domain.User{Phone: "13511111111"}).
+					Return(nil)
+				repo.EXPECT().FindByPhone(gomock.Any(), "13511111111").
+					Return(domain.User{Id: 123, Phone: "13511111111"}, nil)
+				return repo
+			},
+			phone:    "13511111111",
+			wantUser: domain.User{Id: 123, Phone: "13511111111"},
+		},
+		{
+			name: "降级的时候不创建新用户",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "13511111111").
+					Return(domain.User{}, repository.ErrUserNotFund)
+				return repo
+			},
+			ctx:     degrade.With(context.Background()),
+			phone:   "13511111111",
+			wantErr: ErrSystemDegraded,
+		},
+		{
+			name: "降级的时候老用户照常登录",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "13511111111").
+					Return(domain.User{Id: 123, Phone: "13511111111"}, nil)
+				return repo
+			},
+			ctx:      degrade.With(context.Background()),
+			phone:    "13511111111",
+			wantUser: domain.User{Id: 123, Phone: "13511111111"},
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl), testGracePeriod, logger.NewNopLogger())
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			u, err := svc.FindOrCreate(ctx, tc.phone)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func TestUserService_PurgeExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockUserRepository(ctrl)
+	start := time.Now()
+	repo.EXPECT().FindDeactivatedBefore(gomock.Any(), gomock.Any(), 10).
+		DoAndReturn(func(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+			// 截止时间是现在往前推一个宽限期
+			assert.WithinDuration(t, start.Add(-testGracePeriod), before, time.Second)
+			return []domain.User{{Id: 1}, {Id: 2}}, nil
+		})
+	repo.EXPECT().Purge(gomock.Any(), int64(1)).Return(nil)
+	repo.EXPECT().Purge(gomock.Any(), int64(2)).Return(nil)
+	svc := NewUserService(repo, testGracePeriod, logger.NewNopLogger())
+	cnt, err := svc.PurgeExpired(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+}
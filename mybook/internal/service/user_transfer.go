@@ -0,0 +1,384 @@
+package service
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/repository"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var (
+	ErrUnknownFormat = errors.New("不支持的格式，只支持 csv 和 jsonl")
+	// ErrInvalidCSVHeader 第一行不是导出的那种表头，一般是文件选错了或者表头被改了
+	ErrInvalidCSVHeader = errors.New("csv 的表头至少要有 email 或者 phone 一列")
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+const transferBatchSize = 500
+
+// userCSVHeader 导出和导入的列，不包含密码
+var userCSVHeader = []string{"id", "email", "phone", "nickName", "birthday",
+	"introduction", "role", "status", "createTime"}
+
+// UserRecord 导出导入的一行，不包含密码
+type UserRecord struct {
+	Id           int64  `json:"id"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	NickName     string `json:"nickName"`
+	Birthday     string `json:"birthday"`
+	Introduction string `json:"introduction"`
+	Role         string `json:"role"`
+	Status       uint8  `json:"status"`
+	// 毫秒数
+	CreateTime int64 `json:"createTime"`
+}
+
+// ImportFailure 导入失败的行，Line 从 1 开始，CSV 的表头算第 1 行
+type ImportFailure struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	Phone  string `json:"phone"`
+	Reason string `json:"reason"`
+}
+
+type ImportResult struct {
+	Total    int             `json:"total"`
+	Inserted int             `json:"inserted"`
+	Failures []ImportFailure `json:"failures"`
+}
+
+type UserTransferServicePackage interface {
+	// Export 把符合条件的用户按批读出来，写到 w 里，返回导出了多少个
+	Export(ctx context.Context, w io.Writer, format string, filter domain.UserFilter) (int, error)
+	// Import 从 r 里读用户，按批插入
+	// 单行的问题（格式不对、邮箱手机号冲突）记到 ImportResult 里，不会中断导入
+	Import(ctx context.Context, r io.Reader, format string) (ImportResult, error)
+}
+
+type UserTransferService struct {
+	repo repository.UserRepository
+}
+
+func NewUserTransferService(repo repository.UserRepository) UserTransferServicePackage {
+	return &UserTransferService{
+		repo: repo,
+	}
+}
+
+func (svc *UserTransferService) Export(ctx context.Context, w io.Writer, format string, filter domain.UserFilter) (int, error) {
+	var (
+		write func(rec UserRecord) error
+		flush func() error
+	)
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(userCSVHeader); err != nil {
+			return 0, err
+		}
+		write = func(rec UserRecord) error {
+			return cw.Write(rec.csvRow())
+		}
+		// csv 要先 Flush 到 bw 里，bw 再 Flush 到 w
+		flush = func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return bw.Flush()
+		}
+	case FormatJSONL:
+		// Encoder 每次 Encode 都会在末尾加一个换行
+		enc := json.NewEncoder(bw)
+		write = func(rec UserRecord) error {
+			return enc.Encode(rec)
+		}
+		flush = bw.Flush
+	default:
+		return 0, ErrUnknownFormat
+	}
+	cnt, err := svc.export(ctx, filter, write)
+	if err != nil {
+		return cnt, err
+	}
+	return cnt, flush()
+}
+
+func (svc *UserTransferService) export(ctx context.Context, filter domain.UserFilter,
+	write func(rec UserRecord) error) (int, error) {
+	cnt := 0
+	q := domain.UserListQuery{
+		Filter:  filter,
+		OrderBy: domain.UserOrderById,
+		Limit:   transferBatchSize,
+	}
+	for {
+		res, err := svc.repo.List(ctx, q)
+		if err != nil {
+			return cnt, err
+		}
+		for _, u := range res.Users {
+			if err = write(newUserRecord(u)); err != nil {
+				return cnt, err
+			}
+			cnt++
+		}
+		if res.NextCursor == "" {
+			return cnt, nil
+		}
+		q.Cursor = res.NextCursor
+	}
+}
+
+func (svc *UserTransferService) Import(ctx context.Context, r io.Reader, format string) (ImportResult, error) {
+	var next func() (UserRecord, int, error)
+	switch format {
+	case FormatCSV:
+		next = svc.csvReader(r)
+	case FormatJSONL:
+		next = svc.jsonlReader(r)
+	default:
+		return ImportResult{}, ErrUnknownFormat
+	}
+	var (
+		res   ImportResult
+		batch = make([]importRow, 0, transferBatchSize)
+	)
+	for {
+		rec, line, err := next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr rowError
+		if errors.As(err, &rowErr) {
+			res.Total++
+			res.Failures = append(res.Failures, ImportFailure{Line: line, Reason: rowErr.Error()})
+			continue
+		}
+		if err != nil {
+			// 读不下去了，比如文件本身读失败
+			return res, err
+		}
+		res.Total++
+		u, err := rec.toDomain()
+		if err != nil {
+			res.Failures = append(res.Failures, rec.failure(line, err))
+			continue
+		}
+		batch = append(batch, importRow{line: line, rec: rec, user: u})
+		if len(batch) == transferBatchSize {
+			if err = svc.insertBatch(ctx, batch, &res); err != nil {
+				return res, err
+			}
+			batch = batch[:0]
+		}
+	}
+	return res, svc.insertBatch(ctx, batch, &res)
+}
+
+// rowError 某一行解析失败，跳过这一行继续导入
+type rowError struct {
+	err error
+}
+
+func (e rowError) Error() string {
+	return e.err.Error()
+}
+
+type importRow struct {
+	line int
+	rec  UserRecord
+	user domain.User
+}
+
+// insertBatch 整批插入，有冲突的话退化成一条一条插，找出是哪几行冲突
+func (svc *UserTransferService) insertBatch(ctx context.Context, batch []importRow, res *ImportResult) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	us := make([]domain.User, 0, len(batch))
+	for _, row := range batch {
+		us = append(us, row.user)
+	}
+	err := svc.repo.BatchCreate(ctx, us)
+	if err == nil {
+		res.Inserted += len(batch)
+		return nil
+	}
+	if err != repository.ErrUseDuplicate {
+		return err
+	}
+	for _, row := range batch {
+		err = svc.repo.Created(ctx, row.user)
+		switch err {
+		case nil:
+			res.Inserted++
+		case repository.ErrUseDuplicate:
+			res.Failures = append(res.Failures, row.rec.failure(row.line, errors.New("邮箱或手机号已存在")))
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *UserTransferService) csvReader(r io.Reader) func() (UserRecord, int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	var (
+		line    int
+		columns map[string]int
+	)
+	return func() (UserRecord, int, error) {
+		if columns == nil {
+			// 空文件当成没有数据，表头读失败了后面的行也没法解析，直接返回
+			header, err := cr.Read()
+			if err != nil {
+				return UserRecord{}, 0, err
+			}
+			line++
+			columns = make(map[string]int, len(header))
+			for i, name := range header {
+				columns[name] = i
+			}
+			_, hasEmail := columns["email"]
+			_, hasPhone := columns["phone"]
+			if !hasEmail && !hasPhone {
+				return UserRecord{}, line, ErrInvalidCSVHeader
+			}
+		}
+		row, err := cr.Read()
+		if err == io.EOF {
+			return UserRecord{}, 0, io.EOF
+		}
+		line++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return UserRecord{}, line, rowError{err: err}
+			}
+			return UserRecord{}, line, err
+		}
+		get := func(name string) string {
+			idx, ok := columns[name]
+			if !ok || idx >= len(row) {
+				return ""
+			}
+			return row[idx]
+		}
+		return UserRecord{
+			Email:        get("email"),
+			Phone:        get("phone"),
+			NickName:     get("nickName"),
+			Birthday:     get("birthday"),
+			Introduction: get("introduction"),
+			Role:         get("role"),
+		}, line, nil
+	}
+}
+
+func (svc *UserTransferService) jsonlReader(r io.Reader) func() (UserRecord, int, error) {
+	scanner := bufio.NewScanner(r)
+	// 个人简介可能比较长，放大一点
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	return func() (UserRecord, int, error) {
+		for scanner.Scan() {
+			line++
+			content := scanner.Bytes()
+			if len(content) == 0 {
+				continue
+			}
+			var rec UserRecord
+			if err := json.Unmarshal(content, &rec); err != nil {
+				return UserRecord{}, line, rowError{err: err}
+			}
+			return rec, line, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return UserRecord{}, line, err
+		}
+		return UserRecord{}, 0, io.EOF
+	}
+}
+
+func newUserRecord(u domain.User) UserRecord {
+	return UserRecord{
+		Id:           u.Id,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		NickName:     u.NickName,
+		Birthday:     u.Birthday,
+		Introduction: u.Introduction,
+		Role:         string(u.Role),
+		Status:       uint8(u.Status),
+		CreateTime:   u.CreateTime.UnixMilli(),
+	}
+}
+
+func (rec UserRecord) csvRow() []string {
+	return []string{
+		strconv.FormatInt(rec.Id, 10),
+		rec.Email,
+		rec.Phone,
+		rec.NickName,
+		rec.Birthday,
+		rec.Introduction,
+		rec.Role,
+		strconv.Itoa(int(rec.Status)),
+		strconv.FormatInt(rec.CreateTime, 10),
+	}
+}
+
+// toDomain 校验规则和 UserHandler 一样
+// 不导入 id、状态和创建时间，导入之后就是目标环境里的新用户
+func (rec UserRecord) toDomain() (domain.User, error) {
+	if rec.Email == "" && rec.Phone == "" {
+		return domain.User{}, errors.New("邮箱和手机号不能都为空")
+	}
+	if rec.Email != "" && !domain.ValidEmail(rec.Email) {
+		return domain.User{}, fmt.Errorf("邮箱格式不正确: %s", rec.Email)
+	}
+	if rec.Phone != "" && !domain.ValidPhone(rec.Phone) {
+		return domain.User{}, fmt.Errorf("手机号码格式不正确: %s", rec.Phone)
+	}
+	if rec.Birthday != "" && !domain.ValidBirthday(rec.Birthday) {
+		return domain.User{}, fmt.Errorf("生日不是有效的日期: %s", rec.Birthday)
+	}
+	role := domain.Role(rec.Role)
+	if role == "" {
+		role = domain.RoleUser
+	}
+	if !role.Valid() {
+		return domain.User{}, fmt.Errorf("角色不存在: %s", rec.Role)
+	}
+	return domain.User{
+		Email:        rec.Email,
+		Phone:        rec.Phone,
+		NickName:     rec.NickName,
+		Birthday:     rec.Birthday,
+		Introduction: rec.Introduction,
+		Role:         role,
+	}, nil
+}
+
+func (rec UserRecord) failure(line int, err error) ImportFailure {
+	return ImportFailure{
+		Line:   line,
+		Email:  rec.Email,
+		Phone:  rec.Phone,
+		Reason: err.Error(),
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/repository"
+	repomocks "basic-go/mybook/internal/repository/mocks"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestUserTransferService_Export(t *testing.T) {
+	now := time.UnixMilli(1697700000000)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockUserRepository(ctrl)
+	repo.EXPECT().List(gomock.Any(), domain.UserListQuery{
+		OrderBy: domain.UserOrderById,
+		Limit:   transferBatchSize,
+	}).Return(domain.UserListResult{
+		Users: []domain.User{
+			{Id: 1, Email: "123@qq.com", Password: "hash", NickName: "大明", CreateTime: now},
+		},
+		NextCursor: "next",
+	}, nil)
+	repo.EXPECT().List(gomock.Any(), domain.UserListQuery{
+		OrderBy: domain.UserOrderById,
+		Limit:   transferBatchSize,
+		Cursor:  "next",
+	}).Return(domain.UserListResult{
+		Users: []domain.User{
+			{Id: 2, Phone: "13511111111", Password: "hash", Role: domain.RoleAdmin, CreateTime: now},
+		},
+	}, nil)
+
+	svc := NewUserTransferService(repo)
+	var buf bytes.Buffer
+	cnt, err := svc.Export(context.Background(), &buf, FormatCSV, domain.UserFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+	assert.Equal(t, "id,email,phone,nickName,birthday,introduction,role,status,createTime\n"+
+		"1,123@qq.com,,大明,,,,0,1697700000000\n"+
+		"2,,13511111111,,,,admin,0,1697700000000\n", buf.String())
+	assert.NotContains(t, buf.String(), "hash")
+}
+
+func TestUserTransferService_Import(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockUserRepository(ctrl)
+	first := domain.User{Email: "123@qq.com", Role: domain.RoleUser}
+	second := domain.User{Phone: "13511111111", NickName: "大明", Role: domain.RoleUser}
+	//整批冲突，退化成一条一条插
+	repo.EXPECT().BatchCreate(gomock.Any(), []domain.User{first, second}).
+		Return(repository.ErrUseDuplicate)
+	repo.EXPECT().Created(gomock.Any(), first).Return(repository.ErrUseDuplicate)
+	repo.EXPECT().Created(gomock.Any(), second).Return(nil)
+
+	input := strings.Join([]string{
+		`{"email":"123@qq.com"}`,
+		`{"phone":"13511111111","nickName":"大明"}`,
+		`{"email":"123qq.com"}`,
+		`{"email":`,
+		`{"phone":"13511111112","role":"root"}`,
+	}, "\n")
+	svc := NewUserTransferService(repo)
+	res, err := svc.Import(context.Background(), strings.NewReader(input), FormatJSONL)
+	require.NoError(t, err)
+	assert.Equal(t, 5, res.Total)
+	assert.Equal(t, 1, res.Inserted)
+	require.Len(t, res.Failures, 4)
+	lines := make([]int, 0, len(res.Failures))
+	for _, f := range res.Failures {
+		lines = append(lines, f.Line)
+	}
+	assert.ElementsMatch(t, []int{1, 3, 4, 5}, lines)
+}
+
+func TestUserTransferService_ImportCSV(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) repository.UserRepository
+		input io.Reader
+
+		wantRes ImportResult
+		wantErr error
+	}{
+		{
+			name: "导入成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().BatchCreate(gomock.Any(), []domain.User{
+					{Email: "123@qq.com", NickName: "大明", Role: domain.RoleUser},
+				}).Return(nil)
+				return repo
+			},
+			// 只有 email 也可以
+			input:   strings.NewReader("email,nickName\n123@qq.com,大明\n"),
+			wantRes: ImportResult{Total: 1, Inserted: 1},
+		},
+		{
+			name: "空文件",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			input: strings.NewReader(""),
+		},
+		{
+			name: "表头没有 email 和 phone",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			input:   strings.NewReader("name,mail\n大明,123@qq.com\n"),
+			wantErr: ErrInvalidCSVHeader,
+		},
+		{
+			name: "读表头出错",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			input:   iotest.ErrReader(errors.New("mock read error")),
+			wantErr: errors.New("mock read error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserTransferService(tc.mock(ctrl))
+			res, err := svc.Import(context.Background(), tc.input, FormatCSV)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/errs"
+	"basic-go/mybook/internal/web/middleware"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/ginx/middlewares/accesslog"
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*AdminSystemHandler)(nil)
+
+// 访问日志的 body 最多记这么多，再大日志系统扛不住
+const maxAccessLogBodySize = 64 << 10
+
+// AdminSystemHandler 运行时调整系统配置，只对这个实例生效，重启之后恢复成配置文件里的
+type AdminSystemHandler struct {
+	accessLog *accesslog.Builder
+	l         logger.Logger
+}
+
+func NewAdminSystemHandler(accessLog *accesslog.Builder, l logger.Logger) *AdminSystemHandler {
+	return &AdminSystemHandler{
+		accessLog: accessLog,
+		l:         l,
+	}
+}
+
+func (a *AdminSystemHandler) RegisterRoutes(serve *gin.Engine) {
+	g := serve.Group("/admin/system", middleware.RequirePermission(domain.PermissionSystemManage))
+	g.GET("access-log", ginx.Wrap(a.AccessLog))
+	g.PUT("access-log", ginx.WrapBody(a.UpdateAccessLog))
+}
+
+func (a *AdminSystemHandler) AccessLog(ctx *gin.Context) (Result, error) {
+	return Result{Data: a.accessLog.Options()}, nil
+}
+
+// UpdateAccessLog 整个替换，排查问题的时候临时打开某个路由的 body
+func (a *AdminSystemHandler) UpdateAccessLog(ctx *gin.Context, req accesslog.Options) (Result, error) {
+	if req.MaxBodySize < 0 || req.MaxBodySize > maxAccessLogBodySize {
+		return codeResult(errs.InvalidParams), nil
+	}
+	if err := a.accessLog.Update(req); err != nil {
+		// 路由模式是后台填的，写错了不能让整个服务 panic
+		a.l.WithContext(ctx).Warn("访问日志配置不对", logger.Error(err))
+		return codeResult(errs.InvalidParams), nil
+	}
+	a.l.WithContext(ctx).Info("后台操作", logger.String("action", "update_access_log"),
+		logger.Any("options", req))
+	return Result{Data: req}, nil
+}
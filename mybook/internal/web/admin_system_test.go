@@ -0,0 +1,87 @@
+package web
+
+import (
+	"basic-go/mybook/internal/domain"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/ginx/middlewares/accesslog"
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminSystemHandler_UpdateAccessLog(t *testing.T) {
+	testCases := []struct {
+		name string
+		role domain.Role
+		body string
+
+		wantCode int
+		wantBody string
+		wantOpts accesslog.Options
+	}{
+		{
+			name:     "打开某个路由的 body",
+			role:     domain.RoleAdmin,
+			body:     `{"enabled":true,"reqBody":true,"maxBodySize":1024,"bodyPaths":["POST /users/login_sms"]}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"","data":{"enabled":true,"reqBody":true,"respBody":false,"maxBodySize":1024,"skipPaths":null,"bodyPaths":["POST /users/login_sms"]}}`,
+			wantOpts: accesslog.Options{Enabled: true, ReqBody: true, MaxBodySize: 1024,
+				BodyPaths: []string{"POST /users/login_sms"}},
+		},
+		{
+			name:     "body 太大",
+			role:     domain.RoleAdmin,
+			body:     `{"enabled":true,"reqBody":true,"maxBodySize":10485760}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":100002,"msg":"参数错误","data":null}`,
+			wantOpts: accesslog.Options{Enabled: true},
+		},
+		{
+			name:     "路由模式不对",
+			role:     domain.RoleAdmin,
+			body:     `{"enabled":true,"skipPaths":["FOO /x"]}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":100002,"msg":"参数错误","data":null}`,
+			wantOpts: accesslog.Options{Enabled: true},
+		},
+		{
+			name:     "路径模式不对",
+			role:     domain.RoleAdmin,
+			body:     `{"enabled":true,"reqBody":true,"bodyPaths":["["]}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":100002,"msg":"参数错误","data":null}`,
+			wantOpts: accesslog.Options{Enabled: true},
+		},
+		{
+			name:     "运营没有权限",
+			role:     domain.RoleOperator,
+			body:     `{"enabled":false}`,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"code":100004,"msg":"没有权限","data":null}`,
+			wantOpts: accesslog.Options{Enabled: true},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := accesslog.NewBuilder(logger.NewNopLogger(), accesslog.Options{Enabled: true})
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("claims", &ijwt.UserClaims{Uid: 1, Role: string(tc.role)})
+			})
+			NewAdminSystemHandler(b, logger.NewNopLogger()).RegisterRoutes(server)
+			req := httptest.NewRequest(http.MethodPut, "/admin/system/access-log", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, resp.Body.String())
+			}
+			assert.Equal(t, tc.wantOpts, b.Options())
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package web
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/errs"
+	"basic-go/mybook/internal/service"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/internal/web/middleware"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+)
+
+var _ handler = (*AdminUserHandler)(nil)
+
+// errTargetOutranks 不能操作自己，也不能操作角色不比自己低的用户
+var errTargetOutranks = errors.New("不能操作自己或者角色不低于自己的用户")
+
+// AdminUserHandler 后台的用户管理，给运营和客服用
+type AdminUserHandler struct {
+	svc service.UserServicePackage
+	l   logger.Logger
+}
+
+func NewAdminUserHandler(svc service.UserServicePackage, l logger.Logger) *AdminUserHandler {
+	return &AdminUserHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (a *AdminUserHandler) RegisterRoutes(serve *gin.Engine) {
+	ag := serve.Group("/admin/users", middleware.RequirePermission(domain.PermissionUserRead))
+	ag.GET("", ginx.WrapBody(a.List))
+
+	mg := ag.Group("", middleware.RequirePermission(domain.PermissionUserManage))
+	mg.POST(":id/disable", ginx.WrapClaims(a.Disable))
+	mg.POST(":id/enable", ginx.WrapClaims(a.Enable))
+	mg.POST(":id/logout", ginx.WrapClaims(a.ForceLogout))
+
+	rg := ag.Group("", middleware.RequirePermission(domain.PermissionRoleManage))
+	rg.POST(":id/role", ginx.WrapBody(a.UpdateRole))
+}
+
+// AdminUserVO 后台看到的用户信息，不返回密码
+type AdminUserVO struct {
+	Id             int64  `json:"id"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	NickName       string `json:"nickName"`
+	Role           string `json:"role"`
+	Status         uint8  `json:"status"`
+	DeactivateTime int64  `json:"deactivateTime,omitempty"`
+	CreateTime     int64  `json:"createTime"`
+}
+
+// ListReq createStart 和 createEnd 是毫秒数
+type ListReq struct {
+	Email       string `form:"email"`
+	Phone       string `form:"phone"`
+	NickName    string `form:"nickName"`
+	CreateStart int64  `form:"createStart"`
+	CreateEnd   int64  `form:"createEnd"`
+	// id 或者 create_time
+	OrderBy   string `form:"orderBy" binding:"omitempty,oneof=id create_time"`
+	Desc      bool   `form:"desc"`
+	Cursor    string `form:"cursor"`
+	Size      int    `form:"size" binding:"gte=0,lte=1000"`
+	WithTotal bool   `form:"withTotal"`
+}
+
+// List 游标分页搜索
+// 第一页不传 cursor，之后传上一页返回的 nextCursor
+func (a *AdminUserHandler) List(ctx *gin.Context, req ListReq) (Result, error) {
+	q := domain.UserListQuery{
+		Filter: domain.UserFilter{
+			Email:    req.Email,
+			Phone:    req.Phone,
+			NickName: req.NickName,
+		},
+		OrderBy:   domain.UserOrderBy(req.OrderBy),
+		Desc:      req.Desc,
+		Cursor:    req.Cursor,
+		Limit:     req.Size,
+		WithTotal: req.WithTotal,
+	}
+	if req.CreateStart > 0 {
+		q.Filter.CreateStart = time.UnixMilli(req.CreateStart)
+	}
+	if req.CreateEnd > 0 {
+		q.Filter.CreateEnd = time.UnixMilli(req.CreateEnd)
+	}
+	res, err := a.svc.List(ctx, q)
+	if err != nil {
+		return errResult(err), err
+	}
+	vos := make([]AdminUserVO, 0, len(res.Users))
+	for _, u := range res.Users {
+		vos = append(vos, a.toVO(u))
+	}
+	return Result{
+		Data: gin.H{
+			"total":      res.Total,
+			"nextCursor": res.NextCursor,
+			"users":      vos,
+		},
+	}, nil
+}
+
+func (a *AdminUserHandler) Disable(ctx *gin.Context, uc *ijwt.UserClaims) (Result, error) {
+	return a.changeStatus(ctx, uc, "disable", a.svc.Disable)
+}
+
+func (a *AdminUserHandler) Enable(ctx *gin.Context, uc *ijwt.UserClaims) (Result, error) {
+	return a.changeStatus(ctx, uc, "enable", a.svc.Enable)
+}
+
+func (a *AdminUserHandler) ForceLogout(ctx *gin.Context, uc *ijwt.UserClaims) (Result, error) {
+	return a.changeStatus(ctx, uc, "force_logout", a.svc.ForceLogout)
+}
+
+type RoleReq struct {
+	Role string `json:"role" binding:"required"`
+}
+
+func (a *AdminUserHandler) UpdateRole(ctx *gin.Context, req RoleReq) (Result, error) {
+	id, ok := a.userId(ctx)
+	if !ok {
+		return codeResult(errs.InvalidParams), nil
+	}
+	err := a.svc.UpdateRole(ctx, id, domain.Role(req.Role))
+	a.audit(ctx, "update_role", id, err, logger.String("role", req.Role))
+	return errResult(err), err
+}
+
+func (a *AdminUserHandler) changeStatus(ctx *gin.Context, uc *ijwt.UserClaims, action string,
+	fn func(ctx context.Context, id int64) error) (Result, error) {
+	id, ok := a.userId(ctx)
+	if !ok {
+		return codeResult(errs.InvalidParams), nil
+	}
+	err := a.checkTarget(ctx, uc, id)
+	if err == nil {
+		err = fn(ctx, id)
+	}
+	a.audit(ctx, action, id, err)
+	return errResult(err), err
+}
+
+// checkTarget 运营有 PermissionUserManage，但是不能禁用管理员、也不能把自己禁用了
+func (a *AdminUserHandler) checkTarget(ctx *gin.Context, uc *ijwt.UserClaims, id int64) error {
+	if id == uc.Uid {
+		return errTargetOutranks
+	}
+	target, err := a.svc.FindByIdAnyStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !domain.Role(uc.Role).Outranks(target.Role) {
+		return errTargetOutranks
+	}
+	return nil
+}
+
+// audit 后台的操作都要留痕，操作人的 uid 在 ctx 的日志字段里
+func (a *AdminUserHandler) audit(ctx *gin.Context, action string, target int64, err error, fields ...logger.Field) {
+	fields = append(fields, logger.String("action", action), logger.Int64("target_uid", target))
+	if err != nil {
+		a.l.WithContext(ctx).Warn("后台操作失败", append(fields, logger.Error(err))...)
+		return
+	}
+	a.l.WithContext(ctx).Info("后台操作", fields...)
+}
+
+func (a *AdminUserHandler) userId(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	return id, err == nil && id > 0
+}
+func (a *AdminUserHandler) toVO(u domain.User) AdminUserVO {
+	vo := AdminUserVO{
+		Id:         u.Id,
+		Email:      u.Email,
+		Phone:      u.Phone,
+		NickName:   u.NickName,
+		Role:       string(u.Role),
+		Status:     uint8(u.Status),
+		CreateTime: u.CreateTime.UnixMilli(),
+	}
+	if !u.DeactivateTime.IsZero() {
+		vo.DeactivateTime = u.DeactivateTime.UnixMilli()
+	}
+	return vo
+}
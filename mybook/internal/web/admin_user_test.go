@@ -0,0 +1,146 @@
+package web
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/service"
+	svcmocks "basic-go/mybook/internal/service/mocks"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminUserHandler_Disable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.UserServicePackage
+		role     domain.Role
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "禁用成功",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().FindByIdAnyStatus(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Role: domain.RoleUser}, nil)
+				usersvc.EXPECT().Disable(gomock.Any(), int64(123)).Return(nil)
+				return usersvc
+			},
+			role:     domain.RoleOperator,
+			path:     "/admin/users/123/disable",
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+		{
+			name: "普通用户没有权限",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				return svcmocks.NewMockUserServicePackage(ctrl)
+			},
+			role:     domain.RoleUser,
+			path:     "/admin/users/123/disable",
+			wantCode: http.StatusForbidden,
+			wantBody: `{"code":100004,"msg":"没有权限","data":null}`,
+		},
+		{
+			name: "运营不能改角色",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				return svcmocks.NewMockUserServicePackage(ctrl)
+			},
+			role:     domain.RoleOperator,
+			path:     "/admin/users/123/role",
+			wantCode: http.StatusForbidden,
+			wantBody: `{"code":100004,"msg":"没有权限","data":null}`,
+		},
+		{
+			name: "状态不对",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().FindByIdAnyStatus(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Role: domain.RoleOperator}, nil)
+				usersvc.EXPECT().Disable(gomock.Any(), int64(123)).Return(service.ErrUserStatusConflict)
+				return usersvc
+			},
+			role:     domain.RoleAdmin,
+			path:     "/admin/users/123/disable",
+			wantCode: http.StatusOK,
+			wantBody: `{"code":101007,"msg":"用户当前状态不允许这个操作","data":null}`,
+		},
+		{
+			name: "运营不能禁用管理员",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().FindByIdAnyStatus(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Role: domain.RoleAdmin}, nil)
+				return usersvc
+			},
+			role:     domain.RoleOperator,
+			path:     "/admin/users/123/disable",
+			wantCode: http.StatusOK,
+			wantBody: `{"code":100004,"msg":"没有权限","data":null}`,
+		},
+		{
+			name: "运营不能强制下线别的运营",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().FindByIdAnyStatus(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Role: domain.RoleOperator}, nil)
+				return usersvc
+			},
+			role:     domain.RoleOperator,
+			path:     "/admin/users/123/logout",
+			wantCode: http.StatusOK,
+			wantBody: `{"code":100004,"msg":"没有权限","data":null}`,
+		},
+		{
+			name: "不能禁用自己",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				return svcmocks.NewMockUserServicePackage(ctrl)
+			},
+			role:     domain.RoleAdmin,
+			path:     "/admin/users/1/disable",
+			wantCode: http.StatusOK,
+			wantBody: `{"code":100004,"msg":"没有权限","data":null}`,
+		},
+		{
+			name: "禁用的用户也能启用",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().FindByIdAnyStatus(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Role: domain.RoleUser, Status: domain.UserStatusDisabled}, nil)
+				usersvc.EXPECT().Enable(gomock.Any(), int64(123)).Return(nil)
+				return usersvc
+			},
+			role:     domain.RoleOperator,
+			path:     "/admin/users/123/enable",
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			//模拟登录校验之后的结果
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("claims", &ijwt.UserClaims{Uid: 1, Role: string(tc.role)})
+			})
+			h := NewAdminUserHandler(tc.mock(ctrl), logger.NewNopLogger())
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, tc.path, nil)
+			require.NoError(t, err)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
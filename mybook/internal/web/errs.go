@@ -0,0 +1,40 @@
+package web
+
+import (
+	"basic-go/mybook/internal/errs"
+	"basic-go/mybook/internal/service"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/ginx"
+)
+
+// 把 service 的错误映射到对外的错误码
+// 没注册的错误都会变成系统错误
+func init() {
+	errs.Register(service.ErrUseDuplicateEmail, errs.UserDuplicate)
+	errs.Register(service.ErrInvalidUserOrPassword, errs.UserInvalidCredential)
+	errs.Register(service.ErrUserDataNotFund, errs.UserNotFound)
+	errs.Register(service.ErrUserDeactivated, errs.UserDeactivated)
+	errs.Register(service.ErrUserRestoreExpired, errs.UserRestoreExpired)
+	errs.Register(service.ErrUserDisabled, errs.UserDisabled)
+	errs.Register(service.ErrUserStatusConflict, errs.UserStatusConflict)
+	errs.Register(service.ErrInvalidRole, errs.UserInvalidRole)
+	errs.Register(service.ErrInvalidCursor, errs.UserInvalidCursor)
+	errs.Register(service.ErrAvatarTooLarge, errs.UserAvatarTooLarge)
+	errs.Register(service.ErrAvatarInvalid, errs.UserAvatarInvalid)
+	errs.Register(service.ErrSystemDegraded, errs.ServiceUnavailable)
+
+	errs.Register(service.ErrCodeSendTooMany, errs.CodeSendTooMany)
+	errs.Register(service.ErrCodeVerifyTooManyTimes, errs.CodeVerifyTooManyTimes)
+	errs.Register(service.ErrCodeInvalid, errs.CodeInvalid)
+	errs.Register(service.ErrCodeTimeOut, errs.CodeTimeout)
+
+	errs.Register(ijwt.ErrDeviceIdMissing, errs.InvalidParams)
+	errs.Register(errTargetOutranks, errs.Forbidden)
+
+	ginx.BindErrResult = codeResult(errs.InvalidParams)
+	ginx.UnauthorizedResult = codeResult(errs.Unauthorized)
+	ginx.ForbiddenResult = codeResult(errs.Forbidden)
+	ginx.TooManyRequestsResult = codeResult(errs.TooManyRequests)
+	ginx.SystemErrResult = codeResult(errs.SystemError)
+	ginx.ServiceUnavailableResult = codeResult(errs.ServiceUnavailable)
+}
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"basic-go/mybook/pkg/metricsx"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const (
+	BindingNone         = "none"
+	BindingUAFamily     = "ua_family"
+	BindingDevice       = "device"
+	BindingIPSubnet     = "ip_subnet"
+	DefaultDeviceHeader = "X-Device-Id"
+)
+
+// ErrDeviceIdMissing 按设备绑定的时候，登录请求没带设备 ID
+var ErrDeviceIdMissing = errors.New("缺少设备 ID")
+
+// bindingRejections 按原因统计绑定校验失败的次数
+var bindingRejections = metricsx.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "mybook",
+	Name:      "jwt_binding_rejections_total",
+	Help:      "登录态绑定校验失败的次数",
+}, []string{"reason"}))
+
+// BindingPolicy 把登录态和发起登录的客户端绑定起来，token 被人拿走了也用不了
+// 登录的时候用 Bind 算出一个值放在登录态里，之后每个请求用 Check 比较
+type BindingPolicy interface {
+	Bind(ctx *gin.Context) (string, error)
+	// Check 不通过的时候返回 *BindingError
+	Check(ctx *gin.Context, bound string) error
+}
+
+// BindingError Reason 是固定的几个值，用来打日志和统计
+type BindingError struct {
+	Reason string
+}
+
+func (e *BindingError) Error() string {
+	return "登录态绑定校验失败: " + e.Reason
+}
+
+// checkBinding 两种实现共用，不通过的时候记下原因，统一返回 ErrNotLogin
+func checkBinding(ctx *gin.Context, policy BindingPolicy, uc *UserClaims, l logger.Logger) error {
+	err := policy.Check(ctx, uc.Binding)
+	if err == nil {
+		return nil
+	}
+	reason := "unknown"
+	var be *BindingError
+	if errors.As(err, &be) {
+		reason = be.Reason
+	}
+	bindingRejections.WithLabelValues(reason).Inc()
+	l.WithContext(ctx).Warn("登录态绑定校验失败", logger.Int64("uid", uc.Uid),
+		logger.String("reason", reason), logger.String("ip", ctx.ClientIP()))
+	return ErrNotLogin
+}
+
+// NoBinding 不绑定
+type NoBinding struct{}
+
+func (NoBinding) Bind(*gin.Context) (string, error) {
+	return "", nil
+}
+
+func (NoBinding) Check(*gin.Context, string) error {
+	return nil
+}
+
+// UAFamilyBinding 只比较浏览器和操作系统，不比较版本号，浏览器自动升级不会被踢下线
+type UAFamilyBinding struct{}
+
+func (UAFamilyBinding) Bind(ctx *gin.Context) (string, error) {
+	return UAFamily(ctx.Request.UserAgent()), nil
+}
+
+func (UAFamilyBinding) Check(ctx *gin.Context, bound string) error {
+	if UAFamily(ctx.Request.UserAgent()) != bound {
+		return &BindingError{Reason: "ua_family_mismatch"}
+	}
+	return nil
+}
+
+var (
+	// 顺序有关系，Edge 和 Opera 的 UA 里面也有 Chrome，Chrome 的 UA 里面也有 Safari
+	uaBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+	}
+	uaOSes = []struct{ token, name string }{
+		{"Windows", "Windows"}, {"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iOS"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	}
+	uaVersion = regexp.MustCompile(`[0-9][0-9._]*`)
+)
+
+// UAFamily 例如 Chrome/Windows，认不出来的就把版本号去掉
+func UAFamily(ua string) string {
+	browser, os := "", ""
+	for _, b := range uaBrowsers {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range uaOSes {
+		if strings.Contains(ua, o.token) {
+			os = o.name
+			break
+		}
+	}
+	if browser == "" || os == "" {
+		return uaVersion.ReplaceAllString(ua, "")
+	}
+	return browser + "/" + os
+}
+
+// DeviceBinding 客户端自己生成一个设备 ID 放在请求头里，登录态里只放它的哈希
+type DeviceBinding struct {
+	header string
+}
+
+func NewDeviceBinding(header string) DeviceBinding {
+	if header == "" {
+		header = DefaultDeviceHeader
+	}
+	return DeviceBinding{header: header}
+}
+
+func (d DeviceBinding) Bind(ctx *gin.Context) (string, error) {
+	id := ctx.GetHeader(d.header)
+	if id == "" {
+		return "", ErrDeviceIdMissing
+	}
+	return d.hash(id), nil
+}
+
+func (d DeviceBinding) Check(ctx *gin.Context, bound string) error {
+	id := ctx.GetHeader(d.header)
+	if id == "" {
+		return &BindingError{Reason: "device_missing"}
+	}
+	if d.hash(id) != bound {
+		return &BindingError{Reason: "device_mismatch"}
+	}
+	return nil
+}
+
+func (d DeviceBinding) hash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16])
+}
+
+// IPSubnetBinding 同一个网段就行，手机在同一个基站下面换 IP 不会被踢下线
+// 在代理后面的话要先配置好 gin 的 TrustedProxies，不然拿到的是代理的 IP
+type IPSubnetBinding struct {
+	v4Mask net.IPMask
+	v6Mask net.IPMask
+}
+
+// NewIPSubnetBinding 前缀长度为 0 的时候用默认值，IPv4 是 24，IPv6 是 64
+func NewIPSubnetBinding(v4Prefix, v6Prefix int) (IPSubnetBinding, error) {
+	if v4Prefix == 0 {
+		v4Prefix = 24
+	}
+	if v6Prefix == 0 {
+		v6Prefix = 64
+	}
+	if v4Prefix < 0 || v4Prefix > 32 || v6Prefix < 0 || v6Prefix > 128 {
+		return IPSubnetBinding{}, fmt.Errorf("jwt: 网段前缀不对 /%d /%d", v4Prefix, v6Prefix)
+	}
+	return IPSubnetBinding{
+		v4Mask: net.CIDRMask(v4Prefix, 32),
+		v6Mask: net.CIDRMask(v6Prefix, 128),
+	}, nil
+}
+
+func (b IPSubnetBinding) Bind(ctx *gin.Context) (string, error) {
+	subnet, ok := b.subnet(ctx.ClientIP())
+	if !ok {
+		return "", fmt.Errorf("jwt: 拿不到客户端 IP %q", ctx.ClientIP())
+	}
+	return subnet, nil
+}
+
+func (b IPSubnetBinding) Check(ctx *gin.Context, bound string) error {
+	subnet, ok := b.subnet(ctx.ClientIP())
+	if !ok {
+		return &BindingError{Reason: "ip_invalid"}
+	}
+	if subnet != bound {
+		return &BindingError{Reason: "ip_subnet_mismatch"}
+	}
+	return nil
+}
+
+func (b IPSubnetBinding) subnet(ipStr string) (string, bool) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return "", false
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ones, _ := b.v4Mask.Size()
+		return fmt.Sprintf("%s/%d", v4.Mask(b.v4Mask), ones), true
+	}
+	ones, _ := b.v6Mask.Size()
+	return fmt.Sprintf("%s/%d", ip.Mask(b.v6Mask), ones), true
+}
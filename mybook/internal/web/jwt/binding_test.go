@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUAFamily(t *testing.T) {
+	testCases := []struct {
+		ua   string
+		want string
+	}{
+		{ua: chrome118, want: "Chrome/Windows"},
+		{ua: firefox, want: "Firefox/Windows"},
+		{
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.46",
+			want: "Edge/Windows",
+		},
+		{
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			want: "Safari/iOS",
+		},
+		{
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Safari/605.1.15",
+			want: "Safari/macOS",
+		},
+		{
+			ua:   "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36",
+			want: "Chrome/Android",
+		},
+		// 认不出来的去掉版本号
+		{ua: "curl/8.1.2", want: "curl/"},
+		{ua: "mybook-app/2.3.0 (build 1024)", want: "mybook-app/ (build )"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			assert.Equal(t, tc.want, UAFamily(tc.ua))
+		})
+	}
+}
+
+func bindingCtx(modify func(req *http.Request)) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+	modify(ctx.Request)
+	return ctx
+}
+
+func TestBindingPolicy(t *testing.T) {
+	subnet, err := NewIPSubnetBinding(0, 0)
+	require.NoError(t, err)
+	device := NewDeviceBinding("")
+	setUA := func(ua string) func(req *http.Request) {
+		return func(req *http.Request) { req.Header.Set("User-Agent", ua) }
+	}
+	setDevice := func(id string) func(req *http.Request) {
+		return func(req *http.Request) {
+			if id != "" {
+				req.Header.Set(DefaultDeviceHeader, id)
+			}
+		}
+	}
+	setIP := func(ip string) func(req *http.Request) {
+		return func(req *http.Request) { req.RemoteAddr = ip + ":12345" }
+	}
+
+	testCases := []struct {
+		name   string
+		policy BindingPolicy
+		login  func(req *http.Request)
+		later  func(req *http.Request)
+
+		wantReason string
+	}{
+		{name: "不绑定", policy: NoBinding{}, login: setUA(chrome117), later: setUA(firefox)},
+		{name: "浏览器升级", policy: UAFamilyBinding{}, login: setUA(chrome117), later: setUA(chrome118)},
+		{
+			name: "换了浏览器", policy: UAFamilyBinding{}, login: setUA(chrome117), later: setUA(firefox),
+			wantReason: "ua_family_mismatch",
+		},
+		{name: "同一个设备", policy: device, login: setDevice("d-1"), later: setDevice("d-1")},
+		{
+			name: "换了设备", policy: device, login: setDevice("d-1"), later: setDevice("d-2"),
+			wantReason: "device_mismatch",
+		},
+		{
+			name: "没带设备 ID", policy: device, login: setDevice("d-1"), later: setDevice(""),
+			wantReason: "device_missing",
+		},
+		{name: "同一个网段", policy: subnet, login: setIP("10.1.2.3"), later: setIP("10.1.2.200")},
+		{
+			name: "换了网段", policy: subnet, login: setIP("10.1.2.3"), later: setIP("10.1.3.3"),
+			wantReason: "ip_subnet_mismatch",
+		},
+		{name: "IPv6 同一个网段", policy: subnet, login: setIP("[2001:db8::1]"), later: setIP("[2001:db8::ffff]")},
+		{
+			name: "IPv6 换了网段", policy: subnet, login: setIP("[2001:db8::1]"), later: setIP("[2001:db8:0:1::1]"),
+			wantReason: "ip_subnet_mismatch",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bound, err := tc.policy.Bind(bindingCtx(tc.login))
+			require.NoError(t, err)
+			err = tc.policy.Check(bindingCtx(tc.later), bound)
+			if tc.wantReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, &BindingError{Reason: tc.wantReason}, err)
+		})
+	}
+}
+
+func TestDeviceBinding_BindWithoutDevice(t *testing.T) {
+	_, err := NewDeviceBinding("").Bind(bindingCtx(func(req *http.Request) {}))
+	assert.Equal(t, ErrDeviceIdMissing, err)
+}
+
+func TestCheckBinding_Counts(t *testing.T) {
+	count := func() float64 {
+		return testutil.ToFloat64(bindingRejections.WithLabelValues("device_mismatch"))
+	}
+	before := count()
+	ctx := bindingCtx(func(req *http.Request) { req.Header.Set(DefaultDeviceHeader, "d-2") })
+	err := checkBinding(ctx, NewDeviceBinding(""), &UserClaims{Uid: 123, Binding: "other"}, logger.NewNopLogger())
+	assert.Equal(t, ErrNotLogin, err)
+	assert.Equal(t, before+1, count())
+}
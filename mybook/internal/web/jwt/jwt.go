@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+var _ Handler = (*JWTHandler)(nil)
+
+// JWTHandler 登录态放在 JWT 里，前端通过 x-jwt-token 拿到，之后放在 Authorization 里
+// 退出登录的时候把 token 的 id 记到 Redis 里，一直记到 token 过期为止
+type JWTHandler struct {
+	keys    *KeySet
+	client  redis.Cmdable
+	checker RevokeChecker
+	policy  BindingPolicy
+	// token 的有效期
+	expiration time.Duration
+	// 剩下的时间不到这么多的时候续期
+	refreshWithin time.Duration
+	l             logger.Logger
+}
+
+func NewJWTHandler(keys *KeySet, client redis.Cmdable, checker RevokeChecker,
+	policy BindingPolicy, l logger.Logger) *JWTHandler {
+	return &JWTHandler{
+		keys:          keys,
+		client:        client,
+		checker:       checker,
+		policy:        policy,
+		l:             l,
+		expiration:    time.Minute * 30,
+		refreshWithin: time.Minute * 10,
+	}
+}
+
+func (h *JWTHandler) SetLoginToken(ctx *gin.Context, user domain.User) error {
+	binding, err := h.policy.Bind(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return h.setToken(ctx, UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			// 退出登录的时候用
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.expiration)),
+			//强制下线的时候，用签发时间判断 token 是否失效
+			IssuedAt: jwt.NewNumericDate(now),
+		},
+		Uid:        user.Id,
+		Role:       string(user.Role),
+		Binding:    binding,
+		IssuedAtMs: now.UnixMilli(),
+	})
+}
+
+func (h *JWTHandler) setToken(ctx *gin.Context, claims UserClaims) error {
+	tokenStr, err := h.keys.Sign(claims)
+	if err != nil {
+		return err
+	}
+	ctx.Header("x-jwt-token", tokenStr)
+	return nil
+}
+
+func (h *JWTHandler) ExtractToken(ctx *gin.Context) (*UserClaims, error) {
+	//Authorization: Bearer xxx
+	segs := strings.Split(ctx.GetHeader("Authorization"), " ")
+	if len(segs) != 2 || segs[1] == "" {
+		return nil, ErrNotLogin
+	}
+	claims := &UserClaims{}
+	//ParseWithClaims 一定要传指针，因为它会修改里面的值，在返回回去
+	token, err := h.keys.Parse(segs[1], claims)
+	//err 为 nil , token 就不为 nil
+	if err != nil || !token.Valid || claims.Uid == 0 {
+		return nil, ErrNotLogin
+	}
+	if err = checkBinding(ctx, h.policy, claims, h.l); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// JWKS 公钥，别的服务拿去校验我们签的 token
+func (h *JWTHandler) JWKS() JWKS {
+	return h.keys.JWKS()
+}
+
+func (h *JWTHandler) CheckSession(ctx *gin.Context, uc *UserClaims) error {
+	if uc.ID != "" {
+		cnt, err := h.client.Exists(ctx, h.logoutKey(uc.ID)).Result()
+		if err != nil {
+			return err
+		}
+		if cnt > 0 {
+			return ErrSessionInvalid
+		}
+	}
+	if err := checkRevoked(ctx, h.checker, uc); err != nil {
+		return err
+	}
+	// 快过期了就续期，签发时间不变，不然强制下线就没用了
+	if uc.ExpiresAt != nil && time.Until(uc.ExpiresAt.Time) < h.refreshWithin {
+		claims := *uc
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(h.expiration))
+		// 续期失败不影响这次请求
+		_ = h.setToken(ctx, claims)
+	}
+	return nil
+}
+
+// ClearToken 要在登录校验之后调用，需要 claims
+func (h *JWTHandler) ClearToken(ctx *gin.Context) error {
+	val, _ := ctx.Get(ginx.ClaimsKey)
+	uc, ok := val.(*UserClaims)
+	if !ok || uc.ID == "" {
+		return nil
+	}
+	// 续期的时候 ID 不变，同一个 ID 的 token 最晚也就是现在加一个有效期过期
+	return h.client.Set(ctx, h.logoutKey(uc.ID), "", h.expiration).Err()
+}
+
+func (h *JWTHandler) logoutKey(id string) string {
+	return fmt.Sprintf("users:logout:%s", id)
+}
@@ -0,0 +1,229 @@
+package jwt
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/repository/cache/redismocks"
+	repomocks "basic-go/mybook/internal/repository/mocks"
+	"basic-go/mybook/internal/service"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testKeys(t *testing.T) *KeySet {
+	ks, err := NewKeySet("test", NewHMACKey("test", []byte("iF9BZyZtFYktKQtS9bsJAByiT1aVyt06")))
+	require.NoError(t, err)
+	return ks
+}
+
+// revokeFunc 测试用的 RevokeChecker，mocks 包会引用回来，这里用不了
+type revokeFunc func(uid int64) bool
+
+func (f revokeFunc) TokenRevoked(ctx context.Context, userId int64, issuedAt time.Time) (bool, error) {
+	return f(userId), nil
+}
+
+const (
+	chrome117 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.0.0 Safari/537.36"
+	chrome118 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"
+	firefox   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:109.0) Gecko/20100101 Firefox/118.0"
+)
+
+func newCtx(userAgent, token string) (*gin.Context, *httptest.ResponseRecorder) {
+	resp := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(resp)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+	ctx.Request.Header.Set("User-Agent", userAgent)
+	if token != "" {
+		ctx.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return ctx, resp
+}
+
+// issue 登录一次，返回拿到的 token
+func issue(t *testing.T, h *JWTHandler) string {
+	ctx, resp := newCtx(chrome117, "")
+	require.NoError(t, h.SetLoginToken(ctx, domain.User{Id: 123, Role: domain.RoleAdmin}))
+	token := resp.Header().Get("x-jwt-token")
+	require.NotEmpty(t, token)
+	return token
+}
+
+func TestJWTHandler_ExtractToken(t *testing.T) {
+	h := NewJWTHandler(testKeys(t), nil, nil, UAFamilyBinding{}, logger.NewNopLogger())
+	token := issue(t, h)
+
+	// 浏览器升级了还是同一个登录态
+	ctx, _ := newCtx(chrome118, token)
+	uc, err := h.ExtractToken(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), uc.Uid)
+	assert.Equal(t, string(domain.RoleAdmin), uc.Role)
+	assert.Equal(t, "Chrome/Windows", uc.Binding)
+	assert.NotEmpty(t, uc.ID)
+	assert.NotNil(t, uc.IssuedAt)
+
+	testCases := []struct {
+		name      string
+		userAgent string
+		token     string
+	}{
+		{name: "没有 token", userAgent: chrome117},
+		{name: "换了浏览器", userAgent: firefox, token: token},
+		{name: "签名不对", userAgent: chrome117, token: token[:len(token)-2] + "xx"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := newCtx(tc.userAgent, tc.token)
+			_, err := h.ExtractToken(ctx)
+			assert.Equal(t, ErrNotLogin, err)
+		})
+	}
+}
+
+func TestJWTHandler_CheckSession(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (redis.Cmdable, RevokeChecker)
+		// token 还剩多久过期
+		remain time.Duration
+
+		wantErr     error
+		wantRefresh bool
+	}{
+		{
+			name: "有效，不用续期",
+			mock: func(ctrl *gomock.Controller) (redis.Cmdable, RevokeChecker) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:logout:abc").
+					Return(redis.NewIntResult(0, nil))
+				return cmd, revokeFunc(func(uid int64) bool { return false })
+			},
+			remain: time.Minute * 20,
+		},
+		{
+			name: "快过期了续期",
+			mock: func(ctrl *gomock.Controller) (redis.Cmdable, RevokeChecker) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:logout:abc").
+					Return(redis.NewIntResult(0, nil))
+				return cmd, revokeFunc(func(uid int64) bool { return false })
+			},
+			remain:      time.Minute,
+			wantRefresh: true,
+		},
+		{
+			name: "已经退出登录",
+			mock: func(ctrl *gomock.Controller) (redis.Cmdable, RevokeChecker) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:logout:abc").
+					Return(redis.NewIntResult(1, nil))
+				return cmd, nil
+			},
+			remain:  time.Minute * 20,
+			wantErr: ErrSessionInvalid,
+		},
+		{
+			name: "被强制下线",
+			mock: func(ctrl *gomock.Controller) (redis.Cmdable, RevokeChecker) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:logout:abc").
+					Return(redis.NewIntResult(0, nil))
+				return cmd, revokeFunc(func(uid int64) bool { return uid == 123 })
+			},
+			remain:  time.Minute * 20,
+			wantErr: ErrSessionInvalid,
+		},
+		{
+			name: "Redis 出错",
+			mock: func(ctrl *gomock.Controller) (redis.Cmdable, RevokeChecker) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:logout:abc").
+					Return(redis.NewIntResult(0, errors.New("mock redis error")))
+				return cmd, nil
+			},
+			remain:  time.Minute * 20,
+			wantErr: errors.New("mock redis error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd, checker := tc.mock(ctrl)
+			h := NewJWTHandler(testKeys(t), cmd, checker, NoBinding{}, logger.NewNopLogger())
+			ctx, resp := newCtx(chrome117, "")
+			err := h.CheckSession(ctx, &UserClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					ID:        "abc",
+					IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+					ExpiresAt: jwt.NewNumericDate(now.Add(tc.remain)),
+				},
+				Uid: 123,
+			})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRefresh, resp.Header().Get("x-jwt-token") != "")
+		})
+	}
+}
+
+// 强制下线和重新登录在同一秒里，iat 截断到秒之后会早于下线时间，要用 iat_ms 判断
+func TestJWTHandler_RevokeInSameSecond(t *testing.T) {
+	testCases := []struct {
+		name string
+		// 登录之后，相对登录时间多久强制下线
+		revokeAfterLogin time.Duration
+
+		wantErr error
+	}{
+		{name: "先强制下线再登录", revokeAfterLogin: -time.Millisecond},
+		{name: "登录之后被强制下线", revokeAfterLogin: time.Millisecond, wantErr: ErrSessionInvalid},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			h := NewJWTHandler(testKeys(t), nil, nil, NoBinding{}, logger.NewNopLogger())
+			ctx, _ := newCtx(chrome117, issue(t, h))
+			uc, err := h.ExtractToken(ctx)
+			require.NoError(t, err)
+
+			repo := repomocks.NewMockUserRepository(ctrl)
+			repo.EXPECT().TokenRevokeTime(gomock.Any(), int64(123)).
+				Return(time.UnixMilli(uc.IssuedAtMs).Add(tc.revokeAfterLogin), nil)
+			h.checker = service.NewUserService(repo, time.Hour, logger.NewNopLogger())
+			// 只看强制下线，ID 为空不查退出登录
+			uc.ID = ""
+			assert.Equal(t, tc.wantErr, h.CheckSession(ctx, uc))
+		})
+	}
+}
+
+func TestJWTHandler_ClearToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismocks.NewMockCmdable(ctrl)
+	cmd.EXPECT().Set(gomock.Any(), "users:logout:abc", "", time.Minute*30).
+		Return(redis.NewStatusResult("OK", nil))
+	h := NewJWTHandler(testKeys(t), cmd, nil, NoBinding{}, logger.NewNopLogger())
+
+	ctx, _ := newCtx(chrome117, "")
+	ctx.Set(ginx.ClaimsKey, &UserClaims{RegisteredClaims: jwt.RegisteredClaims{ID: "abc"}, Uid: 123})
+	assert.NoError(t, h.ClearToken(ctx))
+
+	// 没有登录态的时候什么都不做
+	ctx, _ = newCtx(chrome117, "")
+	assert.NoError(t, h.ClearToken(ctx))
+}
@@ -0,0 +1,210 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const (
+	AlgHS512 = "HS512"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key 一个签名用的 key，ID 会放在 token 头部的 kid 里
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// 私钥或者 HMAC 的密钥，为 nil 的时候只能校验
+	sign any
+	// 公钥或者 HMAC 的密钥
+	verify any
+}
+
+func NewHMACKey(id string, secret []byte) Key {
+	return Key{ID: id, Method: jwt.SigningMethodHS512, sign: secret, verify: secret}
+}
+
+// ParsePrivateKey alg 是 RS256 或者 EdDSA，pem 里是私钥，公钥从私钥里算出来
+func ParsePrivateKey(id, alg string, pem []byte) (Key, error) {
+	switch alg {
+	case AlgRS256:
+		priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return Key{}, err
+		}
+		return Key{ID: id, Method: jwt.SigningMethodRS256, sign: priv, verify: &priv.PublicKey}, nil
+	case AlgEdDSA:
+		priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err != nil {
+			return Key{}, err
+		}
+		edPriv, ok := priv.(ed25519.PrivateKey)
+		if !ok {
+			return Key{}, fmt.Errorf("jwt: %s 不是 Ed25519 私钥", id)
+		}
+		return Key{ID: id, Method: jwt.SigningMethodEdDSA, sign: edPriv, verify: edPriv.Public()}, nil
+	default:
+		return Key{}, fmt.Errorf("jwt: 不支持的算法 %s", alg)
+	}
+}
+
+// ParsePublicKey 只能校验的 key，一般是轮换下来的旧 key 或者别的服务的 key
+func ParsePublicKey(id, alg string, pem []byte) (Key, error) {
+	switch alg {
+	case AlgRS256:
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return Key{}, err
+		}
+		return Key{ID: id, Method: jwt.SigningMethodRS256, verify: pub}, nil
+	case AlgEdDSA:
+		pub, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return Key{}, err
+		}
+		return Key{ID: id, Method: jwt.SigningMethodEdDSA, verify: pub}, nil
+	default:
+		return Key{}, fmt.Errorf("jwt: 不支持的算法 %s", alg)
+	}
+}
+
+// KeySet 用 primary 签名，里面任何一个 key 签的 token 都能通过校验
+// 轮换的时候先把新 key 加进来，等所有实例都更新了再把它设成 primary，
+// 旧的 key 等它签的 token 都过期了再删掉
+type KeySet struct {
+	primary Key
+	keys    map[string]Key
+	methods []string
+	// 没有 kid 的老 token 用 legacy 校验，只认到 legacyUntil
+	legacy      Key
+	legacyUntil time.Time
+}
+
+func NewKeySet(primary string, keys ...Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]Key, len(keys))}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, errors.New("jwt: key 的 ID 不能为空")
+		}
+		if _, ok := ks.keys[k.ID]; ok {
+			return nil, fmt.Errorf("jwt: key %s 重复了", k.ID)
+		}
+		ks.keys[k.ID] = k
+		if alg := k.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			ks.methods = append(ks.methods, alg)
+		}
+	}
+	p, ok := ks.keys[primary]
+	if !ok {
+		return nil, fmt.Errorf("jwt: 找不到 primary key %s", primary)
+	}
+	if p.sign == nil {
+		return nil, fmt.Errorf("jwt: primary key %s 没有私钥", primary)
+	}
+	ks.primary = p
+	return ks, nil
+}
+
+// AllowLegacy 加 kid 之前签的 token 头部没有 kid，until 之前用 id 这个 HMAC key 校验
+// until 要晚于滚动发布结束的时间再加上 token 的有效期，过了之后没有 kid 的 token 都拒掉
+func (ks *KeySet) AllowLegacy(id string, until time.Time) error {
+	k, ok := ks.keys[id]
+	if !ok {
+		return fmt.Errorf("jwt: 找不到 legacy key %s", id)
+	}
+	if k.Method.Alg() != AlgHS512 {
+		return fmt.Errorf("jwt: legacy key %s 不是 %s", id, AlgHS512)
+	}
+	ks.legacy, ks.legacyUntil = k, until
+	return nil
+}
+
+// Sign 用 primary 签名，头部带上 kid
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.primary.Method, claims)
+	token.Header["kid"] = ks.primary.ID
+	return token.SignedString(ks.primary.sign)
+}
+
+// Parse 按 kid 找 key，算法也要对得上，不然会被人拿公钥当 HMAC 的密钥伪造
+func (ks *KeySet) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, ok := ks.lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("jwt: 不认识的 kid %q", kid)
+		}
+		if token.Method.Alg() != k.Method.Alg() {
+			return nil, fmt.Errorf("jwt: kid %s 的算法对不上", kid)
+		}
+		return k.verify, nil
+	}, jwt.WithValidMethods(ks.methods))
+}
+
+func (ks *KeySet) lookup(kid string) (Key, bool) {
+	if kid == "" && time.Now().Before(ks.legacyUntil) {
+		return ks.legacy, true
+	}
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+// JWK 只放公钥，字段见 RFC 7517 和 RFC 8037
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 给别的服务校验用，HMAC 的 key 不能公开，所以不在里面
+func (ks *KeySet) JWKS() JWKS {
+	res := JWKS{Keys: []JWK{}}
+	// primary 放第一个，其余的按 ID 排，每次返回的都一样
+	others := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		if id != ks.primary.ID {
+			others = append(others, id)
+		}
+	}
+	sort.Strings(others)
+	ids := append([]string{ks.primary.ID}, others...)
+	enc := base64.RawURLEncoding
+	for _, id := range ids {
+		k := ks.keys[id]
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				Kty: "RSA", Kid: id, Use: "sig", Alg: AlgRS256,
+				N: enc.EncodeToString(pub.N.Bytes()),
+				E: enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				Kty: "OKP", Kid: id, Use: "sig", Alg: AlgEdDSA,
+				Crv: "Ed25519", X: enc.EncodeToString(pub),
+			})
+		}
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func privatePEM(t *testing.T, priv any) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(t *testing.T, pub any) []byte {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func testClaims() *UserClaims {
+	return &UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		Uid:              123,
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	oldKey, err := ParsePrivateKey("rsa-1", AlgRS256, privatePEM(t, rsaPriv))
+	require.NoError(t, err)
+	newKey, err := ParsePrivateKey("ed-2", AlgEdDSA, privatePEM(t, edPriv))
+	require.NoError(t, err)
+
+	// 轮换前只有旧 key
+	before, err := NewKeySet("rsa-1", oldKey)
+	require.NoError(t, err)
+	oldToken, err := before.Sign(testClaims())
+	require.NoError(t, err)
+
+	// 轮换之后新的签名，旧的只校验，而且只给了公钥
+	oldPub, err := ParsePublicKey("rsa-1", AlgRS256, publicPEM(t, &rsaPriv.PublicKey))
+	require.NoError(t, err)
+	after, err := NewKeySet("ed-2", newKey, oldPub)
+	require.NoError(t, err)
+	newToken, err := after.Sign(testClaims())
+	require.NoError(t, err)
+
+	for _, tokenStr := range []string{oldToken, newToken} {
+		claims := &UserClaims{}
+		token, err := after.Parse(tokenStr, claims)
+		require.NoError(t, err)
+		assert.True(t, token.Valid)
+		assert.Equal(t, int64(123), claims.Uid)
+	}
+	token, err := after.Parse(newToken, &UserClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "ed-2", token.Header["kid"])
+	assert.Equal(t, AlgEdDSA, token.Method.Alg())
+
+	// 还没更新的实例不认识新的 kid
+	_, err = before.Parse(newToken, &UserClaims{})
+	assert.Error(t, err)
+}
+
+func TestKeySet_Parse(t *testing.T) {
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaKey, err := ParsePrivateKey("rsa-1", AlgRS256, privatePEM(t, rsaPriv))
+	require.NoError(t, err)
+	ks, err := NewKeySet("rsa-1", rsaKey, NewHMACKey("hs-1", []byte("secret")))
+	require.NoError(t, err)
+
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		token := jwt.NewWithClaims(method, testClaims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		str, err := token.SignedString(key)
+		require.NoError(t, err)
+		return str
+	}
+	testCases := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "HMAC 的 key", token: sign(jwt.SigningMethodHS512, "hs-1", []byte("secret"))},
+		{name: "没有 kid", token: sign(jwt.SigningMethodHS512, "", []byte("secret")), wantErr: true},
+		{name: "不认识的 kid", token: sign(jwt.SigningMethodHS512, "hs-2", []byte("secret")), wantErr: true},
+		{
+			// 拿公钥当 HMAC 的密钥伪造 token
+			name:    "算法对不上",
+			token:   sign(jwt.SigningMethodHS512, "rsa-1", publicPEM(t, &rsaPriv.PublicKey)),
+			wantErr: true,
+		},
+		{name: "不支持的算法", token: sign(jwt.SigningMethodHS256, "hs-1", []byte("secret")), wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ks.Parse(tc.token, &UserClaims{})
+			assert.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestKeySet_AllowLegacy(t *testing.T) {
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edKey, err := ParsePrivateKey("ed-1", AlgEdDSA, privatePEM(t, edPriv))
+	require.NoError(t, err)
+	// 升级之前签的 token，头部没有 kid
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS512, testClaims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		until time.Time
+
+		wantErr bool
+	}{
+		{name: "还在过渡期", until: time.Now().Add(time.Minute)},
+		{name: "过渡期过了", until: time.Now().Add(-time.Second), wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ks, err := NewKeySet("ed-1", edKey, NewHMACKey("hs-legacy", []byte("secret")))
+			require.NoError(t, err)
+			require.NoError(t, ks.AllowLegacy("hs-legacy", tc.until))
+			claims := &UserClaims{}
+			_, err = ks.Parse(legacyToken, claims)
+			assert.Equal(t, tc.wantErr, err != nil, err)
+			if !tc.wantErr {
+				assert.Equal(t, int64(123), claims.Uid)
+			}
+		})
+	}
+
+	ks, err := NewKeySet("ed-1", edKey, NewHMACKey("hs-legacy", []byte("secret")))
+	require.NoError(t, err)
+	assert.Error(t, ks.AllowLegacy("hs-2", time.Now().Add(time.Minute)), "找不到 key")
+	assert.Error(t, ks.AllowLegacy("ed-1", time.Now().Add(time.Minute)), "不是 HMAC 的 key")
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := ParsePrivateKey("rsa-1", AlgRS256, privatePEM(t, rsaPriv))
+	require.NoError(t, err)
+	edKey, err := ParsePrivateKey("ed-2", AlgEdDSA, privatePEM(t, edPriv))
+	require.NoError(t, err)
+	ks, err := NewKeySet("ed-2", rsaKey, edKey, NewHMACKey("hs-1", []byte("secret")))
+	require.NoError(t, err)
+
+	jwks := ks.JWKS()
+	// HMAC 的不能公开，primary 在第一个
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JWK{
+		Kty: "OKP", Kid: "ed-2", Use: "sig", Alg: AlgEdDSA, Crv: "Ed25519",
+		X: base64URL(edPub),
+	}, jwks.Keys[0])
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "rsa-1", jwks.Keys[1].Kid)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	assert.Equal(t, base64URL(rsaPriv.PublicKey.N.Bytes()), jwks.Keys[1].N)
+}
+
+func TestNewKeySet(t *testing.T) {
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pub, err := ParsePublicKey("ed-1", AlgEdDSA, publicPEM(t, edPriv.Public()))
+	require.NoError(t, err)
+	hs := NewHMACKey("hs-1", []byte("secret"))
+
+	_, err = NewKeySet("hs-2", hs)
+	assert.Error(t, err, "找不到 primary")
+	_, err = NewKeySet("ed-1", hs, pub)
+	assert.Error(t, err, "primary 没有私钥")
+	_, err = NewKeySet("hs-1", hs, hs)
+	assert.Error(t, err, "ID 重复")
+	_, err = ParsePrivateKey("ed-1", "ES256", privatePEM(t, edPriv))
+	assert.Error(t, err, "不支持的算法")
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
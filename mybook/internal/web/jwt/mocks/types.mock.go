@@ -0,0 +1,138 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: mybook/internal/web/jwt/types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=mybook/internal/web/jwt/types.go -package=jwtmocks -destination=mybook/internal/web/jwt/mocks/types.mock.go
+//
+// Package jwtmocks is a generated GoMock package.
+package jwtmocks
+
+import (
+	domain "basic-go/mybook/internal/domain"
+	jwt "basic-go/mybook/internal/web/jwt"
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockHandler is a mock of Handler interface.
+type MockHandler struct {
+	ctrl     *gomock.Controller
+	recorder *MockHandlerMockRecorder
+}
+
+// MockHandlerMockRecorder is the mock recorder for MockHandler.
+type MockHandlerMockRecorder struct {
+	mock *MockHandler
+}
+
+// NewMockHandler creates a new mock instance.
+func NewMockHandler(ctrl *gomock.Controller) *MockHandler {
+	mock := &MockHandler{ctrl: ctrl}
+	mock.recorder = &MockHandlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
+	return m.recorder
+}
+
+// CheckSession mocks base method.
+func (m *MockHandler) CheckSession(ctx *gin.Context, uc *jwt.UserClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSession", ctx, uc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSession indicates an expected call of CheckSession.
+func (mr *MockHandlerMockRecorder) CheckSession(ctx, uc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSession", reflect.TypeOf((*MockHandler)(nil).CheckSession), ctx, uc)
+}
+
+// ClearToken mocks base method.
+func (m *MockHandler) ClearToken(ctx *gin.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearToken", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearToken indicates an expected call of ClearToken.
+func (mr *MockHandlerMockRecorder) ClearToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearToken", reflect.TypeOf((*MockHandler)(nil).ClearToken), ctx)
+}
+
+// ExtractToken mocks base method.
+func (m *MockHandler) ExtractToken(ctx *gin.Context) (*jwt.UserClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractToken", ctx)
+	ret0, _ := ret[0].(*jwt.UserClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExtractToken indicates an expected call of ExtractToken.
+func (mr *MockHandlerMockRecorder) ExtractToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractToken", reflect.TypeOf((*MockHandler)(nil).ExtractToken), ctx)
+}
+
+// SetLoginToken mocks base method.
+func (m *MockHandler) SetLoginToken(ctx *gin.Context, user domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLoginToken", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLoginToken indicates an expected call of SetLoginToken.
+func (mr *MockHandlerMockRecorder) SetLoginToken(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLoginToken", reflect.TypeOf((*MockHandler)(nil).SetLoginToken), ctx, user)
+}
+
+// MockRevokeChecker is a mock of RevokeChecker interface.
+type MockRevokeChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevokeCheckerMockRecorder
+}
+
+// MockRevokeCheckerMockRecorder is the mock recorder for MockRevokeChecker.
+type MockRevokeCheckerMockRecorder struct {
+	mock *MockRevokeChecker
+}
+
+// NewMockRevokeChecker creates a new mock instance.
+func NewMockRevokeChecker(ctrl *gomock.Controller) *MockRevokeChecker {
+	mock := &MockRevokeChecker{ctrl: ctrl}
+	mock.recorder = &MockRevokeCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevokeChecker) EXPECT() *MockRevokeCheckerMockRecorder {
+	return m.recorder
+}
+
+// TokenRevoked mocks base method.
+func (m *MockRevokeChecker) TokenRevoked(ctx context.Context, userId int64, issuedAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TokenRevoked", ctx, userId, issuedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TokenRevoked indicates an expected call of TokenRevoked.
+func (mr *MockRevokeCheckerMockRecorder) TokenRevoked(ctx, userId, issuedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TokenRevoked", reflect.TypeOf((*MockRevokeChecker)(nil).TokenRevoked), ctx, userId, issuedAt)
+}
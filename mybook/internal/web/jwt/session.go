@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	gsessions "github.com/gorilla/sessions"
+	"time"
+)
+
+var _ Handler = (*SessionHandler)(nil)
+
+// SessionHandler 登录态放在 session 里，cookie 里只有 session id
+// 要先用 Sessions 返回的 middleware，不然拿不到 session
+type SessionHandler struct {
+	name    string
+	store   sessions.Store
+	checker RevokeChecker
+	policy  BindingPolicy
+	// 多久没请求就过期
+	expiration time.Duration
+	// 刷新 update_time 的间隔，不用每个请求都写一次
+	refreshInterval time.Duration
+	l               logger.Logger
+}
+
+func NewSessionHandler(name string, store sessions.Store, checker RevokeChecker,
+	policy BindingPolicy, l logger.Logger) *SessionHandler {
+	return &SessionHandler{
+		name:            name,
+		store:           store,
+		checker:         checker,
+		policy:          policy,
+		l:               l,
+		expiration:      time.Minute * 30,
+		refreshInterval: time.Second * 10,
+	}
+}
+
+// Sessions 要放在登录校验的前面
+func (h *SessionHandler) Sessions() gin.HandlerFunc {
+	return sessions.Sessions(h.name, h.store)
+}
+
+func (h *SessionHandler) SetLoginToken(ctx *gin.Context, user domain.User) error {
+	binding, err := h.policy.Bind(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	sess := sessions.Default(ctx)
+	if err = h.renew(ctx, sess); err != nil {
+		return err
+	}
+	// 登录前的数据不要带过来
+	sess.Clear()
+	sess.Set("userId", user.Id)
+	sess.Set("role", string(user.Role))
+	sess.Set("binding", binding)
+	//强制下线的时候，用登录时间判断 session 是否失效
+	sess.Set("issued_at", now)
+	sess.Set("update_time", now)
+	sess.Options(h.options(int(h.expiration.Seconds())))
+	return sess.Save()
+}
+
+// renew 登录的时候删掉原来的 session，换一个新的 id 保存，
+// 不然登录前被人塞进来的 id 登录之后还能用（session 固定攻击）
+func (h *SessionHandler) renew(ctx *gin.Context, sess sessions.Session) error {
+	raw, ok := sess.(interface{ Session() *gsessions.Session })
+	if !ok {
+		return nil
+	}
+	gs := raw.Session()
+	if gs.ID == "" {
+		return nil
+	}
+	old := *gs
+	old.Options = &gsessions.Options{Path: "/", MaxAge: -1, HttpOnly: true}
+	if err := h.store.Save(ctx.Request, ctx.Writer, &old); err != nil {
+		return err
+	}
+	// id 为空的时候 store 保存时会重新生成
+	gs.ID = ""
+	gs.IsNew = true
+	return nil
+}
+
+func (h *SessionHandler) ExtractToken(ctx *gin.Context) (*UserClaims, error) {
+	sess := sessions.Default(ctx)
+	uid, ok := sess.Get("userId").(int64)
+	if !ok || uid == 0 {
+		return nil, ErrNotLogin
+	}
+	role, _ := sess.Get("role").(string)
+	binding, _ := sess.Get("binding").(string)
+	uc := &UserClaims{Uid: uid, Role: role, Binding: binding}
+	if err := checkBinding(ctx, h.policy, uc, h.l); err != nil {
+		return nil, err
+	}
+	if issuedAt, ok := sess.Get("issued_at").(int64); ok {
+		// 存的是毫秒，不要用 jwt.NewNumericDate，它会截断到秒
+		uc.IssuedAt = &jwt.NumericDate{Time: time.UnixMilli(issuedAt)}
+		uc.IssuedAtMs = issuedAt
+	}
+	return uc, nil
+}
+
+func (h *SessionHandler) CheckSession(ctx *gin.Context, uc *UserClaims) error {
+	if err := checkRevoked(ctx, h.checker, uc); err != nil {
+		return err
+	}
+	sess := sessions.Default(ctx)
+	now := time.Now()
+	updateTime, _ := sess.Get("update_time").(int64)
+	if now.Sub(time.UnixMilli(updateTime)) < h.refreshInterval {
+		return nil
+	}
+	// 续期，cookie 和存储里的过期时间都往后推
+	sess.Set("update_time", now.UnixMilli())
+	sess.Options(h.options(int(h.expiration.Seconds())))
+	return sess.Save()
+}
+
+func (h *SessionHandler) ClearToken(ctx *gin.Context) error {
+	sess := sessions.Default(ctx)
+	sess.Clear()
+	sess.Options(h.options(-1))
+	return sess.Save()
+}
+
+func (h *SessionHandler) options(maxAge int) sessions.Options {
+	return sessions.Options{
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+	}
+}
@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/web/sqlx_store"
+	"basic-go/mybook/pkg/logger"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionHandler_SetLoginToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	store := sqlx_store.NewStore(db, []byte("WbeWraNhhon7NxWP7w9WSKMLzZ8cTiwM"))
+	h := NewSessionHandler("ssid", store, revokeFunc(func(uid int64) bool { return false }),
+		NoBinding{}, logger.NewNopLogger())
+	server := gin.New()
+	server.Use(h.Sessions())
+	server.POST("/visit", func(ctx *gin.Context) {
+		sess := sessions.Default(ctx)
+		sess.Set("visit", true)
+		require.NoError(t, sess.Save())
+	})
+	server.POST("/login", func(ctx *gin.Context) {
+		require.NoError(t, h.SetLoginToken(ctx, domain.User{Id: 123, Role: domain.RoleUser}))
+	})
+
+	// 攻击者先拿到一个 session，再想办法让用户带着这个 cookie 去登录
+	oldID, data := &captureArg{}, &captureArg{}
+	mock.ExpectExec("INSERT INTO `sessions`").
+		WithArgs(oldID, data, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/visit", nil))
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	mock.ExpectQuery("SELECT `data` FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow(data.val))
+	// 老的 session 要删掉，登录态存在新的 id 下面
+	mock.ExpectExec("DELETE FROM `sessions` WHERE `id` = \\?").
+		WithArgs(oldID.val).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	newID := &captureArg{}
+	mock.ExpectExec("INSERT INTO `sessions`").
+		WithArgs(newID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	resp = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.AddCookie(cookies[0])
+	server.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	assert.NotEmpty(t, newID.val)
+	assert.NotEqual(t, oldID.val, newID.val)
+	loginCookies := resp.Result().Cookies()
+	require.Len(t, loginCookies, 2)
+	// 先让老的 cookie 过期，再下发新的
+	assert.Equal(t, -1, loginCookies[0].MaxAge)
+	assert.NotEqual(t, cookies[0].Value, loginCookies[1].Value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// captureArg 记下写进数据库的参数
+type captureArg struct {
+	val driver.Value
+}
+
+func (c *captureArg) Match(v driver.Value) bool {
+	c.val = v
+	return true
+}
@@ -0,0 +1,82 @@
+// Package jwt 登录态相关的东西，web 和 middleware 都会用到
+// 使用的时候一般 import 成 ijwt，避免和 github.com/golang-jwt/jwt 冲突
+package jwt
+
+import (
+	"basic-go/mybook/internal/domain"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+var (
+	// ErrNotLogin 没有登录态，或者登录态本身不合法
+	ErrNotLogin = errors.New("未登录")
+	// ErrSessionInvalid 登录态合法，但是已经退出登录或者被强制下线了
+	ErrSessionInvalid = errors.New("登录态已失效")
+)
+
+const (
+	TypeJWT     = "jwt"
+	TypeSession = "session"
+)
+
+// Handler 管理登录态，handler 和 middleware 只依赖这个接口，
+// 具体是 JWT 还是 session 由配置决定
+type Handler interface {
+	// SetLoginToken 登录成功之后调用，把登录态写给前端
+	SetLoginToken(ctx *gin.Context, user domain.User) error
+	// ExtractToken 从请求里面拿出登录态，拿不到返回 ErrNotLogin
+	ExtractToken(ctx *gin.Context) (*UserClaims, error)
+	// CheckSession 检查登录态是不是还有效，有效的话顺便续期
+	// 失效返回 ErrSessionInvalid，其它的 error 是检查本身出了问题
+	CheckSession(ctx *gin.Context, uc *UserClaims) error
+	// ClearToken 退出登录
+	ClearToken(ctx *gin.Context) error
+}
+
+// RevokeChecker 判断 token 是不是在强制下线之前签发的，一般就是 UserService
+type RevokeChecker interface {
+	TokenRevoked(ctx context.Context, userId int64, issuedAt time.Time) (bool, error)
+}
+
+type UserClaims struct {
+	jwt.RegisteredClaims
+	//声明自己要放进token里的数据
+	Uid  int64
+	Role string
+	// BindingPolicy 算出来的值，按什么绑定看配置
+	Binding string
+	// 签发时间，精确到毫秒。iat 只到秒，强制下线之后同一秒内重新登录，
+	// 拿到的 token 会被当成强制下线之前签发的
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
+}
+
+// issuedAt 老的 token 没有 iat_ms，只能用 iat
+func (uc *UserClaims) issuedAt() (time.Time, bool) {
+	if uc.IssuedAtMs > 0 {
+		return time.UnixMilli(uc.IssuedAtMs), true
+	}
+	if uc.IssuedAt != nil {
+		return uc.IssuedAt.Time, true
+	}
+	return time.Time{}, false
+}
+
+// checkRevoked 两种实现共用的强制下线检查
+func checkRevoked(ctx context.Context, checker RevokeChecker, uc *UserClaims) error {
+	issuedAt, ok := uc.issuedAt()
+	if !ok {
+		return nil
+	}
+	revoked, err := checker.TokenRevoked(ctx, uc.Uid, issuedAt)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrSessionInvalid
+	}
+	return nil
+}
@@ -1,88 +1,81 @@
 package middleware
 
 import (
-	"encoding/gob"
-	"github.com/gin-contrib/sessions"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"time"
+	"sync"
 )
 
+// LoginMiddlewareBuilder 登录校验，具体是 JWT 还是 session 由 ijwt.Handler 决定
+// 不需要登录的路由一般在注册的时候加上 ginx.Public()，
+// 没法改注册代码的（例如整个前缀）用 IgnorePaths
 type LoginMiddlewareBuilder struct {
-	paths []string
+	patterns []ginx.PathPattern
+	hdl      ijwt.Handler
+	l        logger.Logger
+	// 每个路由是不是公开的，路由注册完就不会变了
+	public sync.Map
 }
 
-func NewLoginMiddlewareBuilder() *LoginMiddlewareBuilder {
-	return &LoginMiddlewareBuilder{}
+func NewLoginMiddlewareBuilder(hdl ijwt.Handler, l logger.Logger) *LoginMiddlewareBuilder {
+	return &LoginMiddlewareBuilder{
+		hdl: hdl,
+		l:   l,
+	}
 }
 
-func (l *LoginMiddlewareBuilder) IgnorePaths(path string) *LoginMiddlewareBuilder {
-	l.paths = append(l.paths, path)
+// IgnorePaths 格式见 ginx.PathPattern，例如 "GET /hello"、"/pub/**"
+func (l *LoginMiddlewareBuilder) IgnorePaths(patterns ...string) *LoginMiddlewareBuilder {
+	for _, p := range patterns {
+		l.patterns = append(l.patterns, ginx.ParsePathPattern(p))
+	}
 	return l
 }
 
-var IgnorePaths []string
-
 func (l *LoginMiddlewareBuilder) Build() gin.HandlerFunc {
-	//用go的方式编码解码
-	gob.Register(time.Now())
 	return func(ctx *gin.Context) {
 		//不需要登录校验的
-		for _, path := range l.paths {
-			if ctx.Request.URL.Path == path {
+		if l.isPublic(ctx) {
+			return
+		}
+		for _, p := range l.patterns {
+			if p.Match(ctx.Request.Method, ctx.Request.URL.Path) {
 				return
 			}
 		}
-		sess := sessions.Default(ctx)
-		id := sess.Get("userId")
-		if id == nil {
-			//没有登陆
+		uc, err := l.hdl.ExtractToken(ctx)
+		if err != nil {
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		updateTime := sess.Get("update_time")
-		sess.Set("UserId", id)
-		sess.Options(sessions.Options{
-			MaxAge: 60,
-		})
-		now := time.Now()
-		//说明没刷新过，刚登陆
-		if updateTime == nil {
-			sess.Set("update_time", now)
-			if err := sess.Save(); err != nil {
-				panic(err)
-			}
-		}
-		//updateTime 是有值的
-		updateTimeVal, ok := updateTime.(time.Time)
-		if !ok {
-			ctx.AbortWithStatus(http.StatusInternalServerError)
+		err = l.hdl.CheckSession(ctx, uc)
+		if errors.Is(err, ijwt.ErrSessionInvalid) {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		if now.Sub(updateTimeVal) > time.Second*10 {
-			sess.Set("update_time", now)
-			if err := sess.Save(); err != nil {
-				panic(err)
-			}
+		if err != nil {
+			//Redis 出问题的时候放过去，不然所有人都登录不了
+			l.l.WithContext(ctx).Error("检查登录态失败", logger.Int64("uid", uc.Uid), logger.Error(err))
 		}
+		ctx.Set(ginx.ClaimsKey, uc)
+		// 之后的日志都带上 uid
+		ginx.AddLogFields(ctx, logger.Int64("uid", uc.Uid))
 	}
 }
 
-func CheckLogin() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		// 不需要登录校验的
-		for _, path := range IgnorePaths {
-			if ctx.Request.URL.Path == path {
-				return
-			}
-		}
-
-		sess := sessions.Default(ctx)
-		id := sess.Get("userId")
-		if id == nil {
-			//没有登陆
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
+func (l *LoginMiddlewareBuilder) isPublic(ctx *gin.Context) bool {
+	key := ginx.RouteKey(ctx)
+	if key == "" {
+		return false
+	}
+	if v, ok := l.public.Load(key); ok {
+		return v.(bool)
 	}
+	res := ginx.IsPublic(ctx)
+	l.public.Store(key, res)
+	return res
 }
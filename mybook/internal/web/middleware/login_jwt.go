@@ -1,86 +0,0 @@
-package middleware
-
-import (
-	"basic-go/mybook/internal/web"
-	"encoding/gob"
-	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"log"
-	"net/http"
-	"strings"
-	"time"
-)
-
-// LoginJWTMiddlewareBuilder JWT登录校验
-type LoginJWTMiddlewareBuilder struct {
-	paths []string
-}
-
-func NewLoginJWTMiddlewareBuilder() *LoginJWTMiddlewareBuilder {
-	return &LoginJWTMiddlewareBuilder{}
-}
-
-func (l *LoginJWTMiddlewareBuilder) IgnorePaths(path string) *LoginJWTMiddlewareBuilder {
-	l.paths = append(l.paths, path)
-	return l
-}
-
-func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
-	//用go的方式编码解码
-	gob.Register(time.Now())
-	return func(ctx *gin.Context) {
-		//不需要登录校验的
-		for _, path := range l.paths {
-			if ctx.Request.URL.Path == path {
-				return
-			}
-		}
-		//用jwt来校验
-		tokenHeader := ctx.GetHeader("Authorization")
-		if tokenHeader == "" {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		segs := strings.Split(tokenHeader, " ")
-		if len(segs) != 2 {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-
-		tokenStr := segs[1]
-		claims := &web.UserClaims{}
-		//ParseWithClaims 一定要传指针，因为它会修改里面的值，在返回回去
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte("iF9BZyZtFYktKQtS9bsJAByiT1aVyt06"), nil
-		})
-		if err != nil {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		//err 为 nil , token 就不为 nil
-		if token == nil || !token.Valid || claims.Uid == 0 {
-			//都是未登录
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-
-		if claims.UserAgent != ctx.Request.UserAgent() {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-
-		//每十秒刷新一次
-		now := time.Now()
-		if claims.ExpiresAt.Sub(now) < time.Second*50 {
-			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
-			tokenStr, err = token.SignedString([]byte("iF9BZyZtFYktKQtS9bsJAByiT1aVyt06"))
-			if err != nil {
-				//记录失败
-				log.Println("续约失败", err)
-			}
-			ctx.Header("x-jwt-token", tokenStr)
-		}
-
-		ctx.Set("claims", claims)
-	}
-}
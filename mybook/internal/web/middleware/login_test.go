@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	ijwt "basic-go/mybook/internal/web/jwt"
+	jwtmocks "basic-go/mybook/internal/web/jwt/mocks"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoginMiddlewareBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) ijwt.Handler
+		method string
+		path   string
+
+		wantCode int
+	}{
+		{
+			name: "路由声明了 Public",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			method:   http.MethodPost,
+			path:     "/users/login_sms",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "匹配 IgnorePaths",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			method:   http.MethodGet,
+			path:     "/pub/articles/1",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "登录了",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				uc := &ijwt.UserClaims{Uid: 123}
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), uc).Return(nil)
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/users/profile",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "没登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return(nil, ijwt.ErrNotLogin)
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/users/profile",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "方法不对不算公开",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return(nil, ijwt.ErrNotLogin)
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/users/login_sms",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "已经退出登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				uc := &ijwt.UserClaims{Uid: 123}
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), uc).Return(ijwt.ErrSessionInvalid)
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/users/profile",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "检查登录态出错放过去",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				uc := &ijwt.UserClaims{Uid: 123}
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), uc).Return(errors.New("mock redis error"))
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/users/profile",
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.New()
+			server.Use(NewLoginMiddlewareBuilder(tc.mock(ctrl), logger.NewNopLogger()).IgnorePaths("/pub/**").Build())
+			ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+			server.POST("/users/login_sms", ginx.Public(), ok)
+			server.GET("/users/login_sms", ok)
+			server.GET("/users/profile", ok)
+			server.GET("/pub/articles/:id", ok)
+
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
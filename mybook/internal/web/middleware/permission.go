@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"basic-go/mybook/internal/domain"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RequirePermission 检查当前登录用户的角色有没有 perm 这个权限
+// 一般挂在路由分组上，要放在登录校验的后面
+func RequirePermission(perm domain.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, _ := ctx.Get(ginx.ClaimsKey)
+		claims, ok := c.(*ijwt.UserClaims)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, ginx.UnauthorizedResult)
+			return
+		}
+		if !domain.Role(claims.Role).Can(perm) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, ginx.ForbiddenResult)
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"basic-go/mybook/internal/domain"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePermission(t *testing.T) {
+	testCases := []struct {
+		name   string
+		claims any
+
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "有权限",
+			claims:   &ijwt.UserClaims{Uid: 1, Role: string(domain.RoleAdmin)},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "没有权限",
+			claims:   &ijwt.UserClaims{Uid: 1, Role: string(domain.RoleUser)},
+			wantCode: http.StatusForbidden,
+			wantBody: `{"code":4,"msg":"没有权限","data":null}`,
+		},
+		{
+			name:     "没有登录校验",
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"code":4,"msg":"未登录","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.claims != nil {
+					ctx.Set(ginx.ClaimsKey, tc.claims)
+				}
+			})
+			server.GET("/admin/users", RequirePermission(domain.PermissionUserRead), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
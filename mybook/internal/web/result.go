@@ -1,7 +1,18 @@
 package web
 
-type Result struct {
-	Code int    `json:"code"`
-	Msg  string `json:"msg"`
-	Data any    `json:"data"`
+import (
+	"basic-go/mybook/internal/errs"
+	"basic-go/mybook/pkg/ginx"
+)
+
+// Result 所有接口统一的返回格式，Code 为 0 表示成功，其它的看 errs 包里的错误码
+type Result = ginx.Result
+
+func codeResult(code errs.Code) Result {
+	return Result{Code: int(code), Msg: code.Msg()}
+}
+
+// errResult 根据注册过的错误找错误码，内部的错误信息不会返回给前端
+func errResult(err error) Result {
+	return codeResult(errs.FromError(err))
 }
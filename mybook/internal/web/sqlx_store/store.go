@@ -1,30 +1,179 @@
+// Package sqlx_store 把 session 存在 MySQL 里，给不能用 Redis 的部署环境用
+// 表结构见 dao/migrations 里的 sessions 表
 package sqlx_store
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base32"
+	"errors"
 	ginSession "github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// 默认 30 天，和 gorilla 自带的 store 一样
+const defaultMaxAge = 86400 * 30
+
+var _ ginSession.Store = (*Store)(nil)
+
 type Store struct {
+	db     *sql.DB
+	table  string
+	codecs []securecookie.Codec
+	opts   *sessions.Options
+	now    func() time.Time
+}
+
+// NewStore keyPairs 和 cookie.NewStore 的一样，成对出现，第一个用来签名，第二个用来加密
+// 多对的时候可以轮换密钥，新的放前面
+func NewStore(db *sql.DB, keyPairs ...[]byte) *Store {
+	s := &Store{
+		db:     db,
+		table:  "sessions",
+		codecs: securecookie.CodecsFromPairs(keyPairs...),
+		opts: &sessions.Options{
+			Path:     "/",
+			MaxAge:   defaultMaxAge,
+			HttpOnly: true,
+		},
+		now: time.Now,
+	}
+	s.maxAge(defaultMaxAge)
+	for _, c := range s.codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			// 数据存在数据库里，不受 cookie 4K 的限制
+			sc.MaxLength(0)
+		}
+	}
+	return s
 }
 
 func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
-	//TODO implement me
-	panic("implement me")
+	return sessions.GetRegistry(r).Get(s, name)
 }
 
+// New cookie 里只有加密过的 session id，数据从数据库里读
 func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
-	//TODO implement me
-	panic("implement me")
+	session := sessions.NewSession(s, name)
+	opts := *s.opts
+	session.Options = &opts
+	session.IsNew = true
+	c, err := r.Cookie(name)
+	if err != nil {
+		// 没有 cookie 就是新的 session，不算错
+		return session, nil
+	}
+	if err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.codecs...); err != nil {
+		return session, err
+	}
+	found, err := s.load(r.Context(), session)
+	if err != nil {
+		return session, err
+	}
+	if !found {
+		// 数据库里没有这个 id，不能沿用客户端带过来的，不然别人可以事先塞一个 id 过来，
+		// 等用户登录之后拿着这个 id 冒充他（session 固定攻击）。保存的时候会生成新的
+		session.ID = ""
+	}
+	session.IsNew = !found
+	return session, nil
 }
 
-func (st *Store) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
-	//TODO implement me
-	panic("implement me")
+// Save MaxAge 小于 0 的时候删掉 session
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if err := s.delete(r.Context(), session.ID); err != nil {
+			return err
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+	if session.ID == "" {
+		session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(
+			securecookie.GenerateRandomKey(32)), "=")
+	}
+	if err := s.save(r.Context(), session); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
 }
 
+// Options 之后新建的 session 都用这个配置
 func (s *Store) Options(options ginSession.Options) {
-	//TODO implement me
-	panic("implement me")
+	s.opts = options.ToGorillaOptions()
+	if s.opts.MaxAge > 0 {
+		s.maxAge(s.opts.MaxAge)
+	}
+}
+
+// maxAge cookie 的签名里面带了时间戳，也要跟着改，不然过了默认的时间就解不开了
+func (s *Store) maxAge(age int) {
+	for _, c := range s.codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+func (s *Store) load(ctx context.Context, session *sessions.Session) (bool, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT `data` FROM `"+s.table+"` WHERE `id` = ? AND `expires_at` > ?",
+		session.ID, s.now().UnixMilli()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// 数据库里的数据也签名加密，被人改了数据库也伪造不了 session
+	err = securecookie.DecodeMulti(session.Name(), data, &session.Values, s.codecs...)
+	return err == nil, err
+}
+
+func (s *Store) save(ctx context.Context, session *sessions.Session) error {
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.codecs...)
+	if err != nil {
+		return err
+	}
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		// 浏览器关掉 cookie 就没了，数据库里按默认时间清理
+		maxAge = defaultMaxAge
+	}
+	now := s.now()
+	expiresAt := now.Add(time.Duration(maxAge) * time.Second).UnixMilli()
+	_, err = s.db.ExecContext(ctx, "INSERT INTO `"+s.table+"` (`id`, `data`, `expires_at`, `create_time`, `update_time`) "+
+		"VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE `data` = VALUES(`data`), "+
+		"`expires_at` = VALUES(`expires_at`), `update_time` = VALUES(`update_time`)",
+		session.ID, data, expiresAt, now.UnixMilli(), now.UnixMilli())
+	return err
+}
+
+func (s *Store) delete(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM `"+s.table+"` WHERE `id` = ?", id)
+	return err
+}
+
+// Cleanup 删掉过期的 session，返回删了多少条
+// 一次最多删 limit 条，避免一个大事务锁住太多行，定时清理见 job.SessionCleanupJob
+func (s *Store) Cleanup(ctx context.Context, limit int) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM `"+s.table+"` WHERE `expires_at` <= ? LIMIT ?",
+		s.now().UnixMilli(), limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
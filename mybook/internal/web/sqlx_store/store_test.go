@@ -0,0 +1,108 @@
+package sqlx_store
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	now := time.UnixMilli(1697700000000)
+	s := NewStore(db, []byte("WbeWraNhhon7NxWP7w9WSKMLzZ8cTiwM"))
+	s.now = func() time.Time {
+		return now
+	}
+
+	// 第一次请求，没有 cookie，保存的时候写数据库
+	data := &captureArg{}
+	mock.ExpectExec("INSERT INTO `sessions`").
+		WithArgs(sqlmock.AnyArg(), data, now.Add(time.Minute).UnixMilli(),
+			now.UnixMilli(), now.UnixMilli()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	sess, err := s.New(req, "ssid")
+	require.NoError(t, err)
+	assert.True(t, sess.IsNew)
+	sess.Values["userId"] = int64(123)
+	sess.Options.MaxAge = 60
+	resp := httptest.NewRecorder()
+	require.NoError(t, s.Save(req, resp, sess))
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+	// cookie 里面只有加密过的 id
+	assert.NotContains(t, cookies[0].Value, sess.ID)
+
+	// 第二次请求带上 cookie，从数据库里读
+	mock.ExpectQuery("SELECT `data` FROM `sessions` WHERE `id` = \\? AND `expires_at` > \\?").
+		WithArgs(sess.ID, now.UnixMilli()).
+		WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow(data.val))
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	loaded, err := s.New(req, "ssid")
+	require.NoError(t, err)
+	assert.False(t, loaded.IsNew)
+	assert.Equal(t, sess.ID, loaded.ID)
+	assert.Equal(t, int64(123), loaded.Values["userId"])
+
+	// 过期了或者被删了，当成新的 session
+	mock.ExpectQuery("SELECT `data` FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows([]string{"data"}))
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	expired, err := s.New(req, "ssid")
+	require.NoError(t, err)
+	assert.True(t, expired.IsNew)
+	assert.Empty(t, expired.Values)
+	// 不沿用 cookie 里的 id，保存的时候重新生成
+	assert.Empty(t, expired.ID)
+	mock.ExpectExec("INSERT INTO `sessions`").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, s.Save(req, httptest.NewRecorder(), expired))
+	assert.NotEmpty(t, expired.ID)
+	assert.NotEqual(t, sess.ID, expired.ID)
+
+	// 登出
+	mock.ExpectExec("DELETE FROM `sessions` WHERE `id` = \\?").
+		WithArgs(sess.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	loaded.Options.MaxAge = -1
+	resp = httptest.NewRecorder()
+	require.NoError(t, s.Save(req, resp, loaded))
+	assert.Equal(t, -1, resp.Result().Cookies()[0].MaxAge)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	now := time.UnixMilli(1697700000000)
+	s := NewStore(db, []byte("WbeWraNhhon7NxWP7w9WSKMLzZ8cTiwM"))
+	s.now = func() time.Time {
+		return now
+	}
+	mock.ExpectExec("DELETE FROM `sessions` WHERE `expires_at` <= \\? LIMIT \\?").
+		WithArgs(now.UnixMilli(), 100).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	cnt, err := s.Cleanup(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cnt)
+}
+
+// captureArg 把写进数据库的数据记下来，下一次读的时候原样返回
+type captureArg struct {
+	val driver.Value
+}
+
+func (c *captureArg) Match(v driver.Value) bool {
+	c.val = v
+	return true
+}
@@ -2,14 +2,16 @@ package web
 
 import (
 	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/errs"
 	"basic-go/mybook/internal/service"
-	"fmt"
-	regexp "github.com/dlclark/regexp2"
-	"github.com/gin-contrib/sessions"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"errors"
 	"github.com/gin-gonic/gin"
-	jwt "github.com/golang-jwt/jwt/v5"
 	"net/http"
-	"time"
+	"strings"
+	"unicode/utf8"
 )
 
 const biz = "login"
@@ -21,401 +23,272 @@ var _ handler = &UserHandler{}
 var _ handler = (*UserHandler)(nil)
 
 // UserHandler 定义和跟用户有关的路由
+// 请求参数在 bind 的时候按 binding tag 校验，自定义的规则见 validate.go
 type UserHandler struct {
-	svc         service.UserServicePackage
-	codeSvc     service.CodeServicePackage
-	emailExp    *regexp.Regexp
-	passwordExp *regexp.Regexp
-	birthdayExp *regexp.Regexp
-	phoneExp    *regexp.Regexp
+	svc       service.UserServicePackage
+	codeSvc   service.CodeServicePackage
+	avatarSvc service.AvatarServicePackage
+	// 登录态，JWT 还是 session 看配置
+	hdl ijwt.Handler
+	l   logger.Logger
 }
 
-func NewUserHandler(svc service.UserServicePackage, codeSvc service.CodeServicePackage) *UserHandler {
-	//校验参数
-	const (
-		emailRegExpPattern    = `^\w+([-+.]\w+)*@\w+([-.]\w+)*\.\w+([-.]\w+)*$`
-		passwordRegExpPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
-		birthdayRegExpPattern = `^\d{4}-\d{2}-\d{2}$`
-		phoneExpPattern       = `^1[3456789]\d{9}$`
-	)
-	emailRegExp := regexp.MustCompile(emailRegExpPattern, 0)
-	passwordRegExp := regexp.MustCompile(passwordRegExpPattern, 0)
-	birthdayRegExp := regexp.MustCompile(birthdayRegExpPattern, 0)
-	phoneExp := regexp.MustCompile(phoneExpPattern, 0)
+func NewUserHandler(svc service.UserServicePackage, codeSvc service.CodeServicePackage,
+	avatarSvc service.AvatarServicePackage, hdl ijwt.Handler, l logger.Logger) *UserHandler {
 	return &UserHandler{
-		svc:         svc,
-		emailExp:    emailRegExp,
-		passwordExp: passwordRegExp,
-		birthdayExp: birthdayRegExp,
-		phoneExp:    phoneExp,
-		codeSvc:     codeSvc,
+		svc:       svc,
+		codeSvc:   codeSvc,
+		avatarSvc: avatarSvc,
+		hdl:       hdl,
+		l:         l,
 	}
 }
 
 func (u *UserHandler) RegisterRoutes(serve *gin.Engine) {
 	ug := serve.Group("/users")
-	//ug.POST("login", u.Login)
-	ug.POST("login", u.LoginJWT)
-	ug.POST("signup", u.SignUp)
-	ug.POST("edit", u.Edit)
-	ug.POST("profile", u.ProfileJWT)
+	ug.POST("login", ginx.Public(), ginx.WrapBody(u.Login))
+	ug.POST("logout", ginx.Wrap(u.LogOut))
+	ug.POST("signup", ginx.Public(), ginx.WrapBody(u.SignUp))
+	ug.POST("edit", ginx.WrapBodyAndClaims(u.Edit))
+	ug.GET("profile", ginx.WrapClaims(u.Profile))
+	ug.POST("profile", ginx.WrapClaims(u.Profile))
+	ug.POST("avatar", ginx.WrapClaims(u.UploadAvatar))
 	//put “login/sms/code”发送验证码
 	//put “login/sms/code” 校验验证码
 	//put “login/sms/code”发送验证码
-	ug.POST("login_sms/code/send", u.SendLoginSMSCode)
-	ug.POST("login_sms", u.LoginSMS)
+	ug.POST("login_sms/code/send", ginx.Public(), ginx.WrapBody(u.SendLoginSMSCode))
+	ug.POST("login_sms", ginx.Public(), ginx.WrapBody(u.LoginSMS))
+	ug.POST("deactivate", ginx.WrapClaims(u.Deactivate))
+	ug.POST("restore", ginx.Public(), ginx.WrapBody(u.Restore))
 }
 
-func (u *UserHandler) LoginSMS(ctx *gin.Context) {
-	type Req struct {
-		Phone string `json:"phone"`
-		Code  string `json:"code"`
-	}
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
+type LoginSMSReq struct {
+	Phone string `json:"phone" binding:"required,cnphone"`
+	Code  string `json:"code" binding:"required"`
+}
+
+func (u *UserHandler) LoginSMS(ctx *gin.Context, req LoginSMSReq) (Result, error) {
 	ok, err := u.codeSvc.Verify(ctx, biz, req.Phone, req.Code)
 	if err != nil {
-		switch err {
-		case service.ErrCodeInvalid:
-			ctx.JSON(http.StatusOK, Result{
-				Code: 5,
-				Msg:  "验证码失效，请重新获取验证码",
-			})
-		case service.ErrCodeTimeOut:
-			ctx.JSON(http.StatusOK, Result{
-				Code: 5,
-				Msg:  "验证码已过期!",
-			})
-		default:
-			ctx.JSON(http.StatusOK, Result{
-				Code: 5,
-				Msg:  "系统错误！",
-			})
-		}
-		return
+		return errResult(err), err
 	}
 	if !ok {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 4,
-			Msg:  "验证有误！",
-		})
-		return
+		return codeResult(errs.CodeIncorrect), nil
 	}
 	//有可能是新用户
 	user, err := u.svc.FindOrCreate(ctx, req.Phone)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误 ！",
-		})
-		return
+		return errResult(err), err
 	}
-	if err = u.setJWTToken(ctx, user.Id); err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误！",
-		})
-		return
+	if err = u.hdl.SetLoginToken(ctx, user); err != nil {
+		return errResult(err), err
 	}
-	ctx.JSON(http.StatusOK, Result{
-		Code: 4,
-		Msg:  "验证码校验通过",
-	})
+	u.l.WithContext(ctx).Info("短信登录成功", logger.Int64("uid", user.Id), logger.String("phone", req.Phone))
+	return Result{Msg: "验证码校验通过"}, nil
 }
 
-func (u *UserHandler) SendLoginSMSCode(ctx *gin.Context) {
-	type Req struct {
-		Phone string `json:"phone"`
-	}
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
-	//这里可以校验下手机号，可以用正则
-	if req.Phone == "" {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "手机号不能为空！",
-		})
-		return
-	}
-	isPhone, _ := u.phoneExp.MatchString(req.Phone)
-	if !isPhone {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "手机号码格式不正确！",
-		})
-		return
-	}
-	err := u.codeSvc.Send(ctx, biz, req.Phone)
-	switch err {
-	case nil:
-		ctx.JSON(http.StatusOK, Result{
-			Msg: "发送成功",
-		})
-	case service.ErrCodeSendTooMany:
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "发送频繁，请稍后重试",
-		})
-	default:
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统异常",
-		})
-	}
-
+type SendSMSCodeReq struct {
+	Phone string `json:"phone" binding:"required,cnphone"`
 }
 
-func (u *UserHandler) SignUp(ctx *gin.Context) {
-	type SignUpReq struct {
-		Email           string `json:"email"`
-		ConfirmPassword string `json:"confirmPassword"`
-		Password        string `json:"password"`
-	}
-
-	var req SignUpReq
-	//Bind 方法回根据 Content-type 来解析你的数据到req 里面
-	//解析错了会直接返回400 的错误
-	if err := ctx.Bind(&req); err != nil {
-		return
+func (u *UserHandler) SendLoginSMSCode(ctx *gin.Context, req SendSMSCodeReq) (Result, error) {
+	if err := u.codeSvc.Send(ctx, biz, req.Phone); err != nil {
+		return errResult(err), err
 	}
+	return Result{Msg: "发送成功"}, nil
+}
 
-	isEmail, err := u.emailExp.MatchString(req.Email)
-	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
-	}
-	if !isEmail {
-		ctx.String(http.StatusOK, "Email格式不正确")
-		return
-	}
-	if req.Password != req.ConfirmPassword {
-		ctx.String(http.StatusOK, "两次密码输入不一样！")
-		return
-	}
-	isPassword, err := u.passwordExp.MatchString(req.Password)
-	if err != nil {
-		ctx.String(http.StatusOK, "系统错误1")
-		return
-	}
-	if !isPassword {
-		ctx.String(http.StatusOK, "密码必须大于8位，包含数字，特殊字符")
-		return
-	}
+type SignUpReq struct {
+	Email           string `json:"email" binding:"required,email"`
+	ConfirmPassword string `json:"confirmPassword" binding:"required,eqfield=Password"`
+	Password        string `json:"password" binding:"required,password"`
+}
 
+func (u *UserHandler) SignUp(ctx *gin.Context, req SignUpReq) (Result, error) {
 	//调用service 层了
-	err = u.svc.SignUp(ctx, domain.User{Email: req.Email, Password: req.Password})
-	if err == service.ErrUseDuplicateEmail {
-		ctx.String(http.StatusOK, "邮箱冲突")
-		return
-	}
+	err := u.svc.SignUp(ctx, domain.User{Email: req.Email, Password: req.Password})
 	if err != nil {
-		ctx.String(http.StatusOK, "系统异常2")
-		return
+		return errResult(err), err
 	}
+	return Result{Msg: "注册成功"}, nil
+}
 
-	ctx.String(http.StatusOK, "注册成功")
+type LoginReq struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
 
-func (u *UserHandler) LoginJWT(ctx *gin.Context) {
-	type LoginReq struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-	var req LoginReq
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
+func (u *UserHandler) Login(ctx *gin.Context, req LoginReq) (Result, error) {
 	user, err := u.svc.Login(ctx, req.Email, req.Password)
-	if err == service.ErrInvalidUserOrPassword {
-		ctx.String(http.StatusOK, "用户名/邮箱或密码不对")
-		return
-	}
 	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
+		return errResult(err), err
+	}
+	if err = u.hdl.SetLoginToken(ctx, user); err != nil {
+		return errResult(err), err
 	}
+	u.l.WithContext(ctx).Info("登录成功", logger.Int64("uid", user.Id))
+	return Result{Msg: "登陆成功"}, nil
+}
 
-	//步骤2  使用JWT 设置登录状态
-	//生成一个 JWT token
+func (u *UserHandler) LogOut(ctx *gin.Context) (Result, error) {
+	if err := u.hdl.ClearToken(ctx); err != nil {
+		return errResult(err), err
+	}
+	return Result{Msg: "登出成功"}, nil
+}
 
-	err = u.setJWTToken(ctx, user.Id)
+// EditReq 为空的字段不修改
+type EditReq struct {
+	NickName     string `json:"nickName" binding:"omitempty,runemin=2,runemax=10"`
+	Birthday     string `json:"birthday" binding:"omitempty,date"`
+	Introduction string `json:"introduction" binding:"omitempty,runemax=200"`
+}
+
+// Edit 只能改自己的，用户 id 从 claims 里面拿
+func (u *UserHandler) Edit(ctx *gin.Context, req EditReq, uc *ijwt.UserClaims) (Result, error) {
+	err := u.svc.Edit(ctx, domain.User{
+		Id:           uc.Uid,
+		NickName:     req.NickName,
+		Birthday:     req.Birthday,
+		Introduction: req.Introduction,
+	})
 	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
+		return errResult(err), err
 	}
-	fmt.Println(user)
-	ctx.String(http.StatusOK, "登陆成功")
-	return
+	return Result{Msg: "修改成功"}, nil
+}
 
+// ProfileVO 个人信息，不返回密码，邮箱和手机号打码
+type ProfileVO struct {
+	Id           int64  `json:"id"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	NickName     string `json:"nickName"`
+	Birthday     string `json:"birthday"`
+	Introduction string `json:"introduction"`
+	// 签过名的地址，过一段时间就失效了
+	Avatar      string `json:"avatar"`
+	AvatarThumb string `json:"avatarThumb"`
+	CreateTime  int64  `json:"createTime"`
 }
 
-func (u *UserHandler) setJWTToken(ctx *gin.Context, uid int64) error {
-	claims := UserClaims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 30)),
-		},
-		Uid:       uid,
-		UserAgent: ctx.Request.UserAgent(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	tokenStr, err := token.SignedString([]byte("iF9BZyZtFYktKQtS9bsJAByiT1aVyt06"))
+func (u *UserHandler) Profile(ctx *gin.Context, uc *ijwt.UserClaims) (Result, error) {
+	user, err := u.svc.Profile(ctx, uc.Uid)
 	if err != nil {
-		return err
+		return errResult(err), err
 	}
-
-	ctx.Header("x-jwt-token", tokenStr)
-	return nil
+	avatar, thumb, err := u.avatarSvc.URLs(ctx, user.Avatar)
+	if err != nil {
+		return errResult(err), err
+	}
+	return Result{Data: ProfileVO{
+		Id:           user.Id,
+		Email:        maskEmail(user.Email),
+		Phone:        maskPhone(user.Phone),
+		NickName:     user.NickName,
+		Birthday:     user.Birthday,
+		Introduction: user.Introduction,
+		Avatar:       avatar,
+		AvatarThumb:  thumb,
+		CreateTime:   user.CreateTime.UnixMilli(),
+	}}, nil
 }
 
-func (u *UserHandler) Login(ctx *gin.Context) {
-	type LoginReq struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+// UploadAvatar 表单上传，字段名是 avatar
+func (u *UserHandler) UploadAvatar(ctx *gin.Context, uc *ijwt.UserClaims) (Result, error) {
+	// 整个请求体也限制一下，不然超大的请求会先被 gin 存到临时文件里
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, service.AvatarMaxSize+1<<20)
+	fh, err := ctx.FormFile("avatar")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return codeResult(errs.UserAvatarTooLarge), nil
+		}
+		return codeResult(errs.InvalidParams), nil
 	}
-	var req LoginReq
-	if err := ctx.Bind(&req); err != nil {
-		return
+	if fh.Size > service.AvatarMaxSize {
+		return codeResult(errs.UserAvatarTooLarge), nil
 	}
-	user, err := u.svc.Login(ctx, req.Email, req.Password)
-	if err == service.ErrInvalidUserOrPassword {
-		ctx.String(http.StatusOK, "用户名/邮箱或密码不对")
-		return
+	f, err := fh.Open()
+	if err != nil {
+		return errResult(err), err
 	}
+	defer f.Close()
+	key, err := u.avatarSvc.Upload(ctx, uc.Uid, f)
 	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
+		return errResult(err), err
 	}
+	avatar, thumb, err := u.avatarSvc.URLs(ctx, key)
+	if err != nil {
+		return errResult(err), err
+	}
+	return Result{Msg: "上传成功", Data: gin.H{
+		"avatar":      avatar,
+		"avatarThumb": thumb,
+	}}, nil
+}
 
-	//登陆成功之后获取sessions
-	//登陆之后保存登陆信息 步骤2
-	sess := sessions.Default(ctx)
-	//设置seesion的值
-	sess.Set("userId", user.Id)
-	sess.Options(sessions.Options{
-		MaxAge: 20,
-	})
-	sess.Save()
-	ctx.String(http.StatusOK, "登陆成功2")
-	return
-
+// Deactivate 注销账号，宽限期内可以通过 Restore 恢复
+func (u *UserHandler) Deactivate(ctx *gin.Context, uc *ijwt.UserClaims) (Result, error) {
+	if err := u.svc.Deactivate(ctx, uc.Uid); err != nil {
+		return errResult(err), err
+	}
+	return Result{Msg: "注销成功"}, nil
 }
 
-func (u *UserHandler) LogOut(ctx *gin.Context) {
-	sess := sessions.Default(ctx)
-	//设置seesion的值
-	sess.Options(sessions.Options{
-		MaxAge: -1,
-	})
-	sess.Save()
-	ctx.String(http.StatusOK, "登出成功")
-	return
+// RestoreReq 邮箱密码或者手机验证码二选一
+type RestoreReq struct {
+	Email    string `json:"email" binding:"required_without=Phone,omitempty,email"`
+	Password string `json:"password" binding:"required_with=Email"`
+	Phone    string `json:"phone" binding:"omitempty,cnphone"`
+	Code     string `json:"code" binding:"required_with=Phone"`
 }
 
-// Edit 作业 根据用户id修改数据
-func (u *UserHandler) Edit(ctx *gin.Context) {
-	//定义接收数据
-	type UserDataReq struct {
-		UserId       int64  `json:"userId"`
-		NickName     string `json:"nickName"`
-		Birthday     string `json:"birthday"`
-		Introduction string `json:"introduction"`
-	}
-	//实例化一个req
-	var req UserDataReq
-	//用bing 获取数据
-	if err := ctx.Bind(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请求数据绑定失败"})
-	}
-	if req.UserId == 0 {
-		ctx.String(http.StatusOK, "用户Id丢失，无法编辑")
-		return
-	}
-	//查看该条用户是否存在
-	UserData, _ := u.svc.FindById(ctx, req.UserId)
-	if UserData.Id == 0 {
-		ctx.String(http.StatusOK, "没有改用户，无法编辑")
-		return
-	}
-	//参数验证
-	if req.NickName != "" {
-		if len(req.NickName) < 6 || len(req.NickName) > 30 {
-			ctx.String(http.StatusOK, "昵称大小需要保持2~10个汉字")
-			return
+// Restore 恢复注销的账号，成功之后直接登录
+func (u *UserHandler) Restore(ctx *gin.Context, req RestoreReq) (Result, error) {
+	var (
+		user domain.User
+		err  error
+	)
+	if req.Phone != "" {
+		ok, er := u.codeSvc.Verify(ctx, biz, req.Phone, req.Code)
+		if er != nil {
+			return errResult(er), er
 		}
-	}
-	if req.Birthday != "" {
-		isBirthday, _ := u.birthdayExp.MatchString(req.Birthday)
-		if !isBirthday {
-			ctx.String(http.StatusOK, "生日日期格式不正确，应为YYYY-MM-DD格式")
-			return
+		if !ok {
+			return codeResult(errs.CodeIncorrect), nil
 		}
+		user, err = u.svc.RestoreByPhone(ctx, req.Phone)
+	} else {
+		user, err = u.svc.RestoreByEmail(ctx, req.Email, req.Password)
 	}
-	if req.Introduction == "" {
-		ctx.String(http.StatusOK, "个人简介不能为空")
-		return
+	// 找不到用户也当成账号密码不对，不让别人试出来哪些邮箱注册过
+	if err == service.ErrUserDataNotFund {
+		err = service.ErrInvalidUserOrPassword
 	}
-	//调用service
-	err := u.svc.Edit(ctx, domain.User{
-		Id:           req.UserId,
-		NickName:     req.NickName,
-		Birthday:     req.Birthday,
-		Introduction: req.Introduction,
-	})
 	if err != nil {
-		ctx.String(http.StatusOK, "修改失败")
-		return
+		return errResult(err), err
 	}
-	//ctx.JSON(http.StatusOK, UserData)
-	ctx.String(http.StatusOK, "修改成功")
-}
-
-// Profile 作业回显
-func (u *UserHandler) Profile(ctx *gin.Context, id int64) {
-	////定义接收数据
-	//type UserAllDataReq struct {
-	//	UserId int64 `json:"userId"`
-	//}
-	////实例化一个req
-	//var reqSelect UserAllDataReq
-	////用bing 获取数据
-	//if err := ctx.Bind(&reqSelect); err != nil {
-	//	ctx.JSON(http.StatusBadRequest, gin.H{"error": "请求数据绑定失败"})
-	//}
-	//if reqSelect.UserId == 0 {
-	//	ctx.String(http.StatusOK, "用户Id丢失，无法编辑")
-	//	return
-	//}
-	//查看该条用户是否存在
-	UserData, _ := u.svc.FindById(ctx, id)
-	if UserData.Id == 0 {
-		ctx.String(http.StatusOK, "未找到该用户")
-		return
+	if err = u.hdl.SetLoginToken(ctx, user); err != nil {
+		return errResult(err), err
 	}
-	ctx.JSON(http.StatusOK, UserData)
+	return Result{Msg: "恢复成功"}, nil
 }
 
-func (u *UserHandler) ProfileJWT(ctx *gin.Context) {
-	c, ok := ctx.Get("claims")
-	if !ok {
-		ctx.String(http.StatusOK, "系统错误")
-		return
-	}
-	//断言
-	claims, ok := c.(*UserClaims)
-	if !ok {
-		ctx.String(http.StatusOK, "系统错误")
-		return
-	}
-	println(claims.Uid)
+// maskEmail 用户名只留第一个字符，例如 a***@qq.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	_, size := utf8.DecodeRuneInString(email)
+	return email[:size] + "***" + email[at:]
 }
 
-type UserClaims struct {
-	jwt.RegisteredClaims
-	//声明自己要放进token里的数据
-	Uid       int64
-	UserAgent string
+// maskPhone 留前 3 位和后 4 位，例如 135****1111
+func maskPhone(phone string) string {
+	if len(phone) < 7 {
+		return phone
+	}
+	return phone[:3] + strings.Repeat("*", len(phone)-7) + phone[len(phone)-4:]
 }
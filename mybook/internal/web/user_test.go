@@ -4,6 +4,9 @@ import (
 	"basic-go/mybook/internal/domain"
 	"basic-go/mybook/internal/service"
 	svcmocks "basic-go/mybook/internal/service/mocks"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	jwtmocks "basic-go/mybook/internal/web/jwt/mocks"
+	"basic-go/mybook/pkg/logger"
 	"bytes"
 	"context"
 	"errors"
@@ -15,6 +18,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestEncrypt(t *testing.T) {
@@ -38,11 +42,12 @@ func TestUserHandler_SignUp(t *testing.T) {
 			wantCode: 预期的 HTTP 响应状态码。
 			wantBody: 预期的 HTTP 响应正文。
 		**/
-		name     string
-		mock     func(ctrl *gomock.Controller) service.UserServicePackage
-		reqBody  string
-		wantCode int
-		wantBody string
+		name           string
+		mock           func(ctrl *gomock.Controller) service.UserServicePackage
+		reqBody        string
+		acceptLanguage string
+		wantCode       int
+		wantBody       string
 	}{
 		{
 			name: "注册成功",
@@ -61,7 +66,7 @@ func TestUserHandler_SignUp(t *testing.T) {
    "confirmPassword":"Qq@adm331"
 }`,
 			wantCode: http.StatusOK,
-			wantBody: "注册成功",
+			wantBody: `{"code":0,"msg":"注册成功","data":null}`,
 		},
 		{
 			name: "参数不对， bind 失败",
@@ -75,6 +80,7 @@ func TestUserHandler_SignUp(t *testing.T) {
    "confirmPassword":"Qq@adm331"
 `,
 			wantCode: http.StatusBadRequest,
+			wantBody: `{"code":100002,"msg":"参数错误","data":null}`,
 		},
 		{
 			name: "邮箱格式不对",
@@ -87,8 +93,23 @@ func TestUserHandler_SignUp(t *testing.T) {
    "password":"Qq@adm331",
    "confirmPassword":"Qq@adm331"
 }`,
-			wantCode: http.StatusOK,
-			wantBody: "Email格式不正确",
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"code":100002,"msg":"email必须是一个有效的邮箱","data":{"email":"email必须是一个有效的邮箱"}}`,
+		},
+		{
+			name: "邮箱格式不对，英文",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				return usersvc
+			},
+			reqBody: `{
+   "email":"124qq.com",
+   "password":"Qq@adm331",
+   "confirmPassword":"Qq@adm331"
+}`,
+			acceptLanguage: "en-US,en;q=0.9,zh;q=0.8",
+			wantCode:       http.StatusBadRequest,
+			wantBody:       `{"code":100002,"msg":"email must be a valid email address","data":{"email":"email must be a valid email address"}}`,
 		},
 		{
 			name: "两次输入密码不匹配",
@@ -101,8 +122,8 @@ func TestUserHandler_SignUp(t *testing.T) {
    "password":"Qq@adm3311",
    "confirmPassword":"Qq@adm331"
 }`,
-			wantCode: http.StatusOK,
-			wantBody: "两次密码输入不一样！",
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"code":100002,"msg":"confirmPassword必须等于password","data":{"confirmPassword":"confirmPassword必须等于password"}}`,
 		},
 		{
 			name: "密码格式不对",
@@ -114,9 +135,45 @@ func TestUserHandler_SignUp(t *testing.T) {
    "email":"124@qq.com",
    "password":"Qq1234",
    "confirmPassword":"Qq1234"
+}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"code":100002,"msg":"password必须大于8位，包含字母、数字和特殊字符","data":{"password":"password必须大于8位，包含字母、数字和特殊字符"}}`,
+		},
+		{
+			name: "邮箱冲突",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().SignUp(gomock.Any(), domain.User{
+					Email:    "124@qq.com",
+					Password: "Qq@adm331",
+				}).Return(service.ErrUseDuplicateEmail)
+				return usersvc
+			},
+			reqBody: `{
+   "email":"124@qq.com",
+   "password":"Qq@adm331",
+   "confirmPassword":"Qq@adm331"
 }`,
 			wantCode: http.StatusOK,
-			wantBody: "密码必须大于8位，包含数字，特殊字符",
+			wantBody: `{"code":101001,"msg":"邮箱或手机号已被注册","data":null}`,
+		},
+		{
+			name: "系统错误不暴露内部信息",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().SignUp(gomock.Any(), domain.User{
+					Email:    "124@qq.com",
+					Password: "Qq@adm331",
+				}).Return(errors.New("db 连不上"))
+				return usersvc
+			},
+			reqBody: `{
+   "email":"124@qq.com",
+   "password":"Qq@adm331",
+   "confirmPassword":"Qq@adm331"
+}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":100001,"msg":"系统错误","data":null}`,
 		},
 	}
 
@@ -129,7 +186,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			server := gin.Default()
 			// 用不上 codesvc
 			//创建用户处理程序 h，并为其提供模拟用户服务和模拟验证码服务
-			h := NewUserHandler(tc.mock(ctrl), nil)
+			h := NewUserHandler(tc.mock(ctrl), nil, nil, nil, logger.NewNopLogger())
 			//创建 HTTP 请求 req，模拟用户注册请求，包括 URL 路径和 JSON 数据
 			h.RegisterRoutes(server)
 			//使用 httptest.NewRecorder() 创建一个 HTTP 响应记录器 resp，以捕获处理程序的响应。
@@ -143,6 +200,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			t.Log(resp)
 			//设置请求头的 Content-Type 为 JSON。数据格式是json
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
 			// 这就是 HTTP 请求进去 GIN 框架的入口
 			// 当你这样调用的时候，GIN 就会处理这个请求
 			// 响应写到 resp 里
@@ -155,6 +213,66 @@ func TestUserHandler_SignUp(t *testing.T) {
 	}
 }
 
+func TestUserHandler_Login(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (service.UserServicePackage, ijwt.Handler)
+		wantBody string
+	}{
+		{
+			name: "登录成功",
+			mock: func(ctrl *gomock.Controller) (service.UserServicePackage, ijwt.Handler) {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().Login(gomock.Any(), "124@qq.com", "Qq@adm331").
+					Return(domain.User{Id: 123}, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), domain.User{Id: 123}).Return(nil)
+				return usersvc, hdl
+			},
+			wantBody: `{"code":0,"msg":"登陆成功","data":null}`,
+		},
+		{
+			name: "密码不对不设置登录态",
+			mock: func(ctrl *gomock.Controller) (service.UserServicePackage, ijwt.Handler) {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().Login(gomock.Any(), "124@qq.com", "Qq@adm331").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				return usersvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			wantBody: `{"code":101002,"msg":"用户名/邮箱或密码不对","data":null}`,
+		},
+		{
+			name: "设置登录态失败",
+			mock: func(ctrl *gomock.Controller) (service.UserServicePackage, ijwt.Handler) {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().Login(gomock.Any(), "124@qq.com", "Qq@adm331").
+					Return(domain.User{Id: 123}, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), domain.User{Id: 123}).Return(errors.New("签名失败"))
+				return usersvc, hdl
+			},
+			wantBody: `{"code":100001,"msg":"系统错误","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			usersvc, hdl := tc.mock(ctrl)
+			server := gin.New()
+			NewUserHandler(usersvc, nil, nil, hdl, logger.NewNopLogger()).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/users/login",
+				bytes.NewBufferString(`{"email":"124@qq.com","password":"Qq@adm331"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
 func TestMock(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -174,3 +292,89 @@ func TestMock(t *testing.T) {
 	})
 	t.Log(err)
 }
+
+func TestUserHandler_Edit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.UserServicePackage
+		reqBody  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "只改传了的字段，userId 用 claims 里的",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+				usersvc.EXPECT().Edit(gomock.Any(), domain.User{
+					Id:       123,
+					NickName: "大明",
+				}).Return(nil)
+				return usersvc
+			},
+			reqBody:  `{"userId":456,"nickName":"大明"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"修改成功","data":null}`,
+		},
+		{
+			name: "生日不存在",
+			mock: func(ctrl *gomock.Controller) service.UserServicePackage {
+				return svcmocks.NewMockUserServicePackage(ctrl)
+			},
+			reqBody:  `{"birthday":"2023-02-30"}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"code":100002,"msg":"birthday必须是一个有效的日期，格式为YYYY-MM-DD","data":{"birthday":"birthday必须是一个有效的日期，格式为YYYY-MM-DD"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			//模拟登录校验之后的结果
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("claims", &ijwt.UserClaims{Uid: 123})
+			})
+			h := NewUserHandler(tc.mock(ctrl), nil, nil, nil, logger.NewNopLogger())
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/users/edit", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_Profile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	usersvc := svcmocks.NewMockUserServicePackage(ctrl)
+	usersvc.EXPECT().Profile(gomock.Any(), int64(123)).Return(domain.User{
+		Id:         123,
+		Email:      "abc@qq.com",
+		Phone:      "13511111111",
+		Password:   "hash",
+		NickName:   "大明",
+		Avatar:     "avatars/123/1697700000000.jpg",
+		CreateTime: time.UnixMilli(1697700000000),
+	}, nil)
+	avatarSvc := svcmocks.NewMockAvatarServicePackage(ctrl)
+	avatarSvc.EXPECT().URLs(gomock.Any(), "avatars/123/1697700000000.jpg").
+		Return("http://files/a.jpg?sign=1", "http://files/a_thumb.jpg?sign=1", nil)
+	server := gin.Default()
+	server.Use(func(ctx *gin.Context) {
+		ctx.Set("claims", &ijwt.UserClaims{Uid: 123})
+	})
+	NewUserHandler(usersvc, nil, avatarSvc, nil, logger.NewNopLogger()).RegisterRoutes(server)
+	req, err := http.NewRequest(http.MethodGet, "/users/profile", nil)
+	require.NoError(t, err)
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `{"code":0,"msg":"","data":{"id":123,"email":"a***@qq.com","phone":"135****1111",`+
+		`"nickName":"大明","birthday":"","introduction":"","avatar":"http://files/a.jpg?sign=1",`+
+		`"avatarThumb":"http://files/a_thumb.jpg?sign=1","createTime":1697700000000}}`, resp.Body.String())
+}
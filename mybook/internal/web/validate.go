@@ -0,0 +1,48 @@
+package web
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/pkg/ginx/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+// 和用户有关的校验规则，规则本身定义在 domain 里，导入用户的时候也用同一套
+// 注册失败说明代码写错了，直接 panic
+func init() {
+	rules := []struct {
+		tag      string
+		fn       func(s string) bool
+		messages map[string]string
+	}{
+		{
+			// 覆盖掉 validator 自带的 email，错误信息用自带的
+			tag: "email",
+			fn:  domain.ValidEmail,
+		},
+		{
+			tag: "password",
+			fn:  domain.ValidPassword,
+			messages: map[string]string{
+				validation.LangZh: "{0}必须大于8位，包含字母、数字和特殊字符",
+				validation.LangEn: "{0} must be at least 8 characters and contain letters, digits and special characters",
+			},
+		},
+		{
+			tag: "cnphone",
+			fn:  domain.ValidPhone,
+			messages: map[string]string{
+				validation.LangZh: "{0}必须是一个有效的手机号码",
+				validation.LangEn: "{0} must be a valid mobile phone number",
+			},
+		},
+	}
+	for _, r := range rules {
+		fn := r.fn
+		err := validation.Register(r.tag, func(fl validator.FieldLevel) bool {
+			return fn(fl.Field().String())
+		}, r.messages)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
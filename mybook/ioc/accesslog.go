@@ -0,0 +1,20 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/pkg/ginx/middlewares/accesslog"
+	"basic-go/mybook/pkg/logger"
+)
+
+// InitAccessLog middleware 和后台的开关用的是同一个 Builder
+func InitAccessLog(l logger.Logger) *accesslog.Builder {
+	cfg := config.Config.AccessLog
+	return accesslog.NewBuilder(l, accesslog.Options{
+		Enabled:     cfg.Enabled,
+		ReqBody:     cfg.ReqBody,
+		RespBody:    cfg.RespBody,
+		MaxBodySize: cfg.MaxBodySize,
+		SkipPaths:   cfg.SkipPaths,
+		BodyPaths:   cfg.BodyPaths,
+	})
+}
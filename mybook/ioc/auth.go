@@ -0,0 +1,103 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/internal/service"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/internal/web/sqlx_store"
+	"basic-go/mybook/pkg/logger"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"os"
+	"time"
+)
+
+// InitSessionStore session 存在 MySQL 里，用 JWT 的时候也会创建，但是不会用到
+func InitSessionStore(db *gorm.DB) *sqlx_store.Store {
+	cfg := config.Config.Auth
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+	return sqlx_store.NewStore(sqlDB, []byte(cfg.SessionAuthKey), []byte(cfg.SessionEncryptKey))
+}
+
+// InitTokenHandler 按配置选 JWT 还是 session
+func InitTokenHandler(redisClient redis.Cmdable, store *sqlx_store.Store,
+	userSvc service.UserServicePackage, l logger.Logger) ijwt.Handler {
+	cfg := config.Config.Auth
+	policy := initBindingPolicy(cfg.Binding)
+	switch cfg.Type {
+	case ijwt.TypeSession:
+		return ijwt.NewSessionHandler("ssid", store, userSvc, policy, l)
+	case ijwt.TypeJWT, "":
+		return ijwt.NewJWTHandler(initJWTKeys(cfg.JWT), redisClient, userSvc, policy, l)
+	default:
+		panic("未知的登录态类型 " + cfg.Type)
+	}
+}
+
+func initBindingPolicy(cfg config.BindingConfig) ijwt.BindingPolicy {
+	switch cfg.Policy {
+	case ijwt.BindingNone:
+		return ijwt.NoBinding{}
+	case ijwt.BindingUAFamily, "":
+		return ijwt.UAFamilyBinding{}
+	case ijwt.BindingDevice:
+		return ijwt.NewDeviceBinding(cfg.DeviceHeader)
+	case ijwt.BindingIPSubnet:
+		b, err := ijwt.NewIPSubnetBinding(cfg.IPv4Prefix, cfg.IPv6Prefix)
+		if err != nil {
+			panic(err)
+		}
+		return b
+	default:
+		panic("未知的登录态绑定策略 " + cfg.Policy)
+	}
+}
+
+func initJWTKeys(cfg config.JWTConfig) *ijwt.KeySet {
+	keys := make([]ijwt.Key, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		keys = append(keys, loadJWTKey(kc))
+	}
+	ks, err := ijwt.NewKeySet(cfg.Primary, keys...)
+	if err != nil {
+		panic(err)
+	}
+	// 不然升级的时候所有人都要重新登录
+	if cfg.Legacy != "" {
+		if err = ks.AllowLegacy(cfg.Legacy, time.Now().Add(cfg.LegacyWindow)); err != nil {
+			panic(err)
+		}
+	}
+	return ks
+}
+
+func loadJWTKey(kc config.JWTKeyConfig) ijwt.Key {
+	if kc.Alg == ijwt.AlgHS512 {
+		return ijwt.NewHMACKey(kc.ID, []byte(kc.Secret))
+	}
+	var (
+		key ijwt.Key
+		err error
+	)
+	if kc.PrivateKeyFile != "" {
+		key, err = ijwt.ParsePrivateKey(kc.ID, kc.Alg, readKeyFile(kc.PrivateKeyFile))
+	} else {
+		key, err = ijwt.ParsePublicKey(kc.ID, kc.Alg, readKeyFile(kc.PublicKeyFile))
+	}
+	if err != nil {
+		panic(fmt.Errorf("加载 JWT key %s 失败: %w", kc.ID, err))
+	}
+	return key
+}
+
+func readKeyFile(path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,10 @@
+package ioc
+
+import (
+	"basic-go/mybook/internal/repository/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitUserCache(client redis.Cmdable) cache.UserCache {
+	return cache.NewMetricsUserCache(cache.NewUserCache(client))
+}
@@ -0,0 +1,12 @@
+package ioc
+
+import (
+	"basic-go/mybook/internal/repository"
+	"basic-go/mybook/internal/service"
+	"basic-go/mybook/internal/service/sms"
+	"basic-go/mybook/pkg/logger"
+)
+
+func InitCodeService(repo repository.CodeRepository, smsSvc sms.Service, l logger.Logger) service.CodeServicePackage {
+	return service.NewMetricsCodeService(service.NewCodeService(repo, smsSvc, l))
+}
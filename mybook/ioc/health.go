@@ -0,0 +1,29 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/pkg/health"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func InitHealth(db *gorm.DB, redisClient redis.Cmdable, l logger.Logger) *health.Handler {
+	hdl := health.NewHandler(config.Config.Server.HealthTimeout, l).
+		Add("mysql", func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		})
+	// session 模式可以不部署 Redis，没配置的时候不检查
+	// 所有 pod 连的是同一个 Redis，挂了摘掉哪个 pod 都没用，只报出来，限流自己有降级
+	if config.Config.Redis.Addr != "" {
+		hdl.AddOptional("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+	return hdl
+}
@@ -0,0 +1,22 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/internal/job"
+	"basic-go/mybook/internal/service"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/internal/web/sqlx_store"
+	"basic-go/mybook/pkg/logger"
+	"time"
+)
+
+func InitJobs(userSvc service.UserServicePackage, store *sqlx_store.Store, l logger.Logger) []*job.Runner {
+	jobs := []*job.Runner{
+		job.NewRunner(job.NewPurgeUserJob(userSvc), time.Hour, l),
+	}
+	if config.Config.Auth.Type == ijwt.TypeSession {
+		// 过期的 session 定时清掉
+		jobs = append(jobs, job.NewRunner(job.NewSessionCleanupJob(store), time.Hour, l))
+	}
+	return jobs
+}
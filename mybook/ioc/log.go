@@ -0,0 +1,34 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/ginx/validation"
+	"basic-go/mybook/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// InitLogger 本地开发用 console 格式，线上用 json 格式给日志系统采集
+func InitLogger() logger.Logger {
+	cfg := config.Config.Log
+	zcfg := zap.NewProductionConfig()
+	if cfg.Development {
+		zcfg = zap.NewDevelopmentConfig()
+	}
+	if cfg.Level != "" {
+		lvl, err := zap.ParseAtomicLevel(cfg.Level)
+		if err != nil {
+			panic(err)
+		}
+		zcfg.Level = lvl
+	}
+	zl, err := zcfg.Build()
+	if err != nil {
+		panic(err)
+	}
+	l := logger.NewZapLogger(zl)
+	// ginx 的 Wrap 和校验规则都是包级别的函数，没法注入
+	ginx.L = l
+	validation.L = l
+	return l
+}
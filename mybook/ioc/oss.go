@@ -0,0 +1,43 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/internal/service/oss"
+	"basic-go/mybook/internal/service/oss/local"
+	"basic-go/mybook/internal/service/oss/s3"
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func InitObjectStorage() oss.ObjectStorage {
+	cfg := config.Config.Storage
+	switch cfg.Type {
+	case "s3":
+		client, err := minio.New(cfg.S3.Endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(cfg.S3.AccessKey, cfg.S3.SecretKey, ""),
+			Secure: cfg.S3.UseSSL,
+		})
+		if err != nil {
+			panic(err)
+		}
+		initBucket(client, cfg.S3.Bucket)
+		return s3.NewStorage(client, cfg.S3.Bucket)
+	default:
+		return local.NewStorage(cfg.Local.Dir, cfg.Local.BaseURL, cfg.Local.Secret)
+	}
+}
+
+// initBucket bucket 不存在就建一个，本地的 MinIO 起来的时候是空的
+func initBucket(client *minio.Client, bucket string) {
+	ctx := context.Background()
+	ok, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		panic(err)
+	}
+	if !ok {
+		if err = client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			panic(err)
+		}
+	}
+}
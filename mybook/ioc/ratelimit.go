@@ -0,0 +1,131 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/breaker"
+	"basic-go/mybook/pkg/ginx/middlewares/ratelimit"
+	"basic-go/mybook/pkg/limiter"
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+)
+
+// InitRateLimit 规则见 config.RateLimitRule
+// beforeLogin 是 key 里面没有 uid 的规则，放在 session 和登录校验的前面，被刷的时候不用先查 session、验 token
+// afterLogin 是按 uid 限流的规则，要放在登录校验的后面，不然拿不到 uid
+func InitRateLimit(redisClient redis.Cmdable, l logger.Logger) (beforeLogin, afterLogin gin.HandlerFunc) {
+	cfg := config.Config.RateLimit
+	// 所有规则用的是同一个 Redis，共用一个熔断器
+	br := breaker.NewBreaker(cfg.Degrade.BreakerThreshold, cfg.Degrade.BreakerCooldown)
+	before, after := ratelimit.NewRuleBuilder().Logger(l), ratelimit.NewRuleBuilder().Logger(l)
+	for _, r := range cfg.Rules {
+		b := before
+		if keyNeedsLogin(r.Key) {
+			b = after
+		}
+		b.Rule(r.Name, newDegradeLimiter(redisClient, r, cfg.Degrade, br, l), rateLimitKey(r.Key), r.Paths...)
+	}
+	return before.Build(), after.Build()
+}
+
+func newDegradeLimiter(redisClient redis.Cmdable, r config.RateLimitRule,
+	cfg config.RateLimitDegrade, br *breaker.Breaker, l logger.Logger) limiter.Limiter {
+	primary := newRedisLimiter(redisClient, r)
+	switch cfg.Policy {
+	case limiter.FailOpen:
+		return limiter.NewFailOpenLimiter(primary, br, l)
+	case limiter.FailClosed:
+		return limiter.NewFailClosedLimiter(primary, br, l)
+	case limiter.FailLocal, "":
+		return limiter.NewFallbackLimiter(primary, newLocalLimiter(r, cfg.Instances), br, l)
+	default:
+		panic("未知的限流降级策略 " + cfg.Policy)
+	}
+}
+
+func newRedisLimiter(redisClient redis.Cmdable, r config.RateLimitRule) limiter.Limiter {
+	switch r.Algorithm {
+	case "fixed_window":
+		return limiter.NewRedisFixedWindowLimiter(redisClient, r.Interval, r.Rate)
+	case "sliding_window", "":
+		return limiter.NewRedisSlidingWindowLimiter(redisClient, r.Interval, r.Rate)
+	case "token_bucket":
+		return limiter.NewRedisTokenBucketLimiter(redisClient, r.Interval, r.Rate, r.Capacity)
+	case "leaky_bucket":
+		return limiter.NewRedisLeakyBucketLimiter(redisClient, r.Interval, r.Rate, r.Capacity)
+	default:
+		panic("未知的限流算法 " + r.Algorithm)
+	}
+}
+
+// newLocalLimiter 每个实例只拿 1/instances 的额度，加起来和 Redis 的差不多
+func newLocalLimiter(r config.RateLimitRule, instances int) limiter.Limiter {
+	rate, capacity := perInstance(r.Rate, instances), perInstance(r.Capacity, instances)
+	switch r.Algorithm {
+	case "fixed_window":
+		return limiter.NewLocalFixedWindowLimiter(r.Interval, rate)
+	case "sliding_window", "":
+		return limiter.NewLocalSlidingWindowLimiter(r.Interval, rate)
+	case "token_bucket":
+		return limiter.NewLocalTokenBucketLimiter(r.Interval, rate, capacity)
+	case "leaky_bucket":
+		return limiter.NewLocalLeakyBucketLimiter(r.Interval, rate, capacity)
+	default:
+		panic("未知的限流算法 " + r.Algorithm)
+	}
+}
+
+// perInstance 向上取整，至少是 1，不然一个请求都过不去
+func perInstance(n, instances int) int {
+	if instances <= 1 {
+		return n
+	}
+	res := (n + instances - 1) / instances
+	if res < 1 {
+		return 1
+	}
+	return res
+}
+
+// keyNeedsLogin key 里面有 uid 的要登录之后才能算
+func keyNeedsLogin(key string) bool {
+	for _, part := range strings.Split(key, "+") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(part), ":"); name == "uid" {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitKey 例如 ip+route、header:X-Api-Key
+func rateLimitKey(key string) ratelimit.KeyFunc {
+	parts := strings.Split(key, "+")
+	fns := make([]ratelimit.KeyFunc, 0, len(parts))
+	for _, part := range parts {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), ":")
+		switch name {
+		case "ip":
+			fns = append(fns, ratelimit.KeyByIP())
+		case "uid":
+			fns = append(fns, ratelimit.KeyByClaims(func(uc *ijwt.UserClaims) string {
+				return strconv.FormatInt(uc.Uid, 10)
+			}))
+		case "route":
+			fns = append(fns, ratelimit.KeyByRoute())
+		case "header":
+			if arg == "" {
+				panic("限流的 key 缺少请求头 " + key)
+			}
+			fns = append(fns, ratelimit.KeyByHeader(arg))
+		default:
+			panic("未知的限流 key " + key)
+		}
+	}
+	if len(fns) == 1 {
+		return fns[0]
+	}
+	return ratelimit.KeyCombine(fns...)
+}
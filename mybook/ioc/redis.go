@@ -2,12 +2,16 @@ package ioc
 
 import (
 	"basic-go/mybook/config"
+	"basic-go/mybook/pkg/redisx"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
 )
 
 func InitRedis() redis.Cmdable {
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: config.Config.Redis.Addr,
 	})
+	redisClient.AddHook(redisx.NewMetricsHook("mybook", ""))
+	redisClient.AddHook(redisx.NewTracingHook(otel.GetTracerProvider()))
 	return redisClient
 }
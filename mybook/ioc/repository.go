@@ -0,0 +1,19 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/internal/repository"
+	"basic-go/mybook/internal/repository/cache"
+	"basic-go/mybook/internal/repository/dao"
+	ijwt "basic-go/mybook/internal/web/jwt"
+	"basic-go/mybook/pkg/logger"
+)
+
+// InitUserRepository session 模式可以不部署 Redis，强制下线的时间改存 MySQL
+func InitUserRepository(d dao.UserDAO, c cache.UserCache, l logger.Logger) repository.UserRepository {
+	repo := repository.NewUserRepository(d, c, l)
+	if config.Config.Auth.Type == ijwt.TypeSession {
+		return repository.NewDBRevokeUserRepository(repo, d)
+	}
+	return repo
+}
@@ -0,0 +1,17 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/internal/repository"
+	"basic-go/mybook/internal/service"
+	"basic-go/mybook/pkg/logger"
+)
+
+// InitUserService 宽限期不能是 0，不然刚注销的账号就被清理掉了
+func InitUserService(repo repository.UserRepository, l logger.Logger) service.UserServicePackage {
+	gracePeriod := config.Config.User.DeactivateGracePeriod
+	if gracePeriod <= 0 {
+		panic("注销宽限期要大于 0")
+	}
+	return service.NewUserService(repo, gracePeriod, l)
+}
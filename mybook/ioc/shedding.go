@@ -0,0 +1,23 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/pkg/ginx/middlewares/shedding"
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// InitShedding 放在登录校验前面，过载的时候别再去查 Redis 了
+func InitShedding(l logger.Logger) gin.HandlerFunc {
+	cfg := config.Config.Shedding
+	b := shedding.NewBuilder("mybook", cfg.MaxInFlight, cfg.MaxP99).
+		HighPriority(cfg.HighPriority...).
+		Logger(l)
+	if cfg.DegradeRatio > 0 {
+		b.DegradeRatio(cfg.DegradeRatio)
+	}
+	if cfg.Window > 0 {
+		b.Window(cfg.Window, 1000)
+	}
+	return b.Build()
+}
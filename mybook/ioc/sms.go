@@ -3,9 +3,14 @@ package ioc
 import (
 	"basic-go/mybook/internal/service/sms"
 	"basic-go/mybook/internal/service/sms/memory"
+	"basic-go/mybook/internal/service/sms/metrics"
+	"basic-go/mybook/internal/service/sms/tracing"
+	"basic-go/mybook/pkg/logger"
+	"go.opentelemetry.io/otel"
 )
 
-func InitSMSService() sms.Service {
+func InitSMSService(l logger.Logger) sms.Service {
 	//这里可以换内存，或者换其他
-	return memory.NewService()
+	svc := metrics.NewService(memory.NewService(l), "memory")
+	return tracing.NewService(svc, "memory", otel.GetTracerProvider())
 }
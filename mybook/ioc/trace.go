@@ -0,0 +1,65 @@
+package ioc
+
+import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// InitTracerProvider 设置 otel 全局的 TracerProvider 和 propagator
+// DB、Redis 这些在它之前初始化也没关系，全局的 TracerProvider 设置之后会转过去
+func InitTracerProvider(l logger.Logger) *sdktrace.TracerProvider {
+	cfg := config.Config.Trace
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		panic(err)
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	exporter, err := newSpanExporter(cfg)
+	if err != nil {
+		panic(err)
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	// W3C 的 traceparent 和 baggage
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	// 上报失败之类的错误，默认是打到标准输出
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		l.Warn("opentelemetry 出错", logger.Error(err))
+	}))
+	return tp
+}
+
+func newSpanExporter(cfg config.TraceConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "otlp":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		// 不会等连上，collector 没起来也不影响启动
+		return otlptracegrpc.New(context.Background(), opts...)
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("未知的 trace exporter %s", cfg.Exporter)
+	}
+}
@@ -1,39 +1,101 @@
 package ioc
 
 import (
+	"basic-go/mybook/config"
+	"basic-go/mybook/internal/service/oss"
 	"basic-go/mybook/internal/web"
+	ijwt "basic-go/mybook/internal/web/jwt"
 	"basic-go/mybook/internal/web/middleware"
-	"basic-go/mybook/pkg/ginx/middlewares/ratelimit"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/ginx/middlewares/accesslog"
+	"basic-go/mybook/pkg/ginx/middlewares/metrics"
+	"basic-go/mybook/pkg/ginx/middlewares/recovery"
+	"basic-go/mybook/pkg/ginx/middlewares/requestid"
+	"basic-go/mybook/pkg/ginx/middlewares/tracing"
+	"basic-go/mybook/pkg/health"
+	"basic-go/mybook/pkg/logger"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
-func InitGin(mdls []gin.HandlerFunc, userHdl *web.UserHandler) *gin.Engine {
-	server := gin.Default()
+// InitRecovery InitGin 和 InitMiddleware 用的是同一个
+func InitRecovery(l logger.Logger) *recovery.Builder {
+	return recovery.NewBuilder("mybook", l)
+}
+
+func InitGin(rec *recovery.Builder, mdls []gin.HandlerFunc, healthHdl *health.Handler, userHdl *web.UserHandler, adminHdl *web.AdminUserHandler,
+	sysHdl *web.AdminSystemHandler, storage oss.ObjectStorage, tokenHdl ijwt.Handler) *gin.Engine {
+	// 不用 gin 自带的 Logger，访问日志见 accesslog
+	server := gin.New()
+	// handler 直接把 gin.Context 当 context.Context 传给 service
+	// 不打开的话拿不到 Request 的 context 上的 span，请求取消了也感知不到
+	server.ContextWithFallback = true
+	// 兜底，middleware 里面还有一个，那个能带上 request id 和 trace
+	server.Use(rec.Build())
+	// 健康检查注册在 middleware 前面，不受限流、过载保护影响，也不打访问日志
+	healthHdl.RegisterRoutes(server)
 	server.Use(mdls...)
 	userHdl.RegisterRoutes(server)
+	adminHdl.RegisterRoutes(server)
+	sysHdl.RegisterRoutes(server)
+	// 本地存储的文件由我们自己提供下载，S3 的签名 URL 直接访问 S3
+	if h, ok := storage.(http.Handler); ok {
+		prefix := filesPathPrefix()
+		// 文件的 URL 自己带了签名
+		server.GET(prefix+"/*key", ginx.Public(), gin.WrapH(http.StripPrefix(prefix, h)))
+	}
+	// 别的服务用这里的公钥校验我们签的 token
+	if jh, ok := tokenHdl.(*ijwt.JWTHandler); ok {
+		server.GET("/.well-known/jwks.json", ginx.Public(), func(ctx *gin.Context) {
+			ctx.Header("Cache-Control", "public, max-age=300")
+			ctx.JSON(http.StatusOK, jh.JWKS())
+		})
+	}
 	return server
 }
-func InitMiddleware(redisClient redis.Cmdable) []gin.HandlerFunc {
-	return []gin.HandlerFunc{
-		corsHdl(),
-		middleware.NewLoginJWTMiddlewareBuilder().
-			IgnorePaths("/users/login").
-			IgnorePaths("/users/login_sms/code/send").
-			IgnorePaths("/users/login_sms").
-			IgnorePaths("/users/signup").Build(),
-		ratelimit.NewBuilder(redisClient, time.Second, 100).Build(),
+
+// filesPathPrefix 本地存储的 BaseURL 的路径部分，例如 /files
+func filesPathPrefix() string {
+	u, err := url.Parse(config.Config.Storage.Local.BaseURL)
+	if err != nil || u.Path == "" {
+		return "/files"
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+func InitMiddleware(redisClient redis.Cmdable, hdl ijwt.Handler, l logger.Logger,
+	accessLog *accesslog.Builder, rec *recovery.Builder) []gin.HandlerFunc {
+	// request id 和 trace 要在最前面，后面的日志才能带上
+	// 访问日志和监控紧跟着，被拒掉的请求也要有记录
+	// panic 之后返回 500，前面的访问日志、监控、trace 都能看到
+	// 按 ip、路由、请求头的限流放在 session 和登录校验前面，按 uid 的只能放在后面
+	beforeLogin, afterLogin := InitRateLimit(redisClient, l)
+	mdls := []gin.HandlerFunc{requestid.NewBuilder().Build(), tracing.NewBuilder().Build(), accessLog.Build(),
+		metrics.NewBuilder("mybook", "").Build(), rec.Build(), corsHdl(), InitShedding(l), beforeLogin}
+	// session 的话要先把 session 拿出来
+	if sh, ok := hdl.(*ijwt.SessionHandler); ok {
+		mdls = append(mdls, sh.Sessions())
 	}
+	return append(mdls,
+		// 具体哪些路由不用登录，在注册路由的时候用 ginx.Public() 声明
+		// /pub 下面的约定都是公开的
+		middleware.NewLoginMiddlewareBuilder(hdl, l).
+			IgnorePaths("/pub/**").Build(),
+		afterLogin,
+	)
 }
 
 func corsHdl() gin.HandlerFunc {
 	return cors.New(cors.Config{
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,                    // 是否允许你带 cookie 之类的东西
-		ExposeHeaders:    []string{"x-jwt-token"}, //不设置这个，前端读不到
+		AllowHeaders: []string{"Content-Type", "Authorization", ijwt.DefaultDeviceHeader, requestid.DefaultHeader,
+			"traceparent", "tracestate"},
+		AllowCredentials: true, // 是否允许你带 cookie 之类的东西
+		ExposeHeaders: []string{"x-jwt-token", requestid.DefaultHeader,
+			"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"}, //不设置这个，前端读不到
 		AllowOriginFunc: func(origin string) bool {
 			if strings.HasPrefix(origin, "http://localhost") {
 				//你的开发环境
@@ -1,16 +1,25 @@
 package main
 
 import (
+	"basic-go/mybook/config"
+	ijwt "basic-go/mybook/internal/web/jwt"
 	"basic-go/mybook/internal/web/middleware"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
 	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
 	//db := initDB()
 	//server := initWebServer()
 	////注册路由
@@ -18,15 +27,16 @@ func main() {
 	//u := initUser(db, rdb)
 	//u.RegisterRoutes(server)
 
-	server := InitWebServer()
-	server.GET("/hello", func(ctx *gin.Context) {
+	app := InitApp()
+	app.Server.GET("/hello", ginx.Public(), func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "来了老弟！")
 	})
-	//启动
-	server.Run(":8080")
+	if err := app.Run(config.Config.Server); err != nil {
+		panic(err)
+	}
 }
 
-func initWebServer() *gin.Engine {
+func initWebServer(hdl ijwt.Handler) *gin.Engine {
 	server := gin.Default()
 	//跨域可以在这里处理...
 	server.Use(func(ctx *gin.Context) {
@@ -40,7 +50,7 @@ func initWebServer() *gin.Engine {
 	//redisClient := redis.NewClient(&redis.Options{
 	//	Addr: config.Config.Redis.Addr,
 	//})
-	//server.Use(ratelimit.NewBuilder(redisClient, time.Second, 100).Build())
+	//server.Use(ratelimit.NewBuilder(limiter.NewRedisSlidingWindowLimiter(redisClient, time.Second, 100)).Build())
 
 	server.Use(cors.New(cors.Config{
 		AllowHeaders:     []string{"Content-Type", "Authorization"},
@@ -70,18 +80,13 @@ func initWebServer() *gin.Engine {
 	//	panic(err)
 	//}
 
-	//myStore := &sqlx_store.Store{}
+	//不能用 Redis 的时候存 MySQL
+	//store := sqlx_store.NewStore(sqlDB, []byte("WbeWraNhhon7NxWP7w9WSKMLzZ8cTiwM"), []byte("YHgJ7VQuszth64EuHphVSYVN9SY9NA76"))
 	//server.Use(sessions.Sessions("mysession", store))
 
 	//登陆之后的校验 - 登陆之后保存登陆信息 步骤3
-	//server.Use(middleware.NewLoginMiddlewareBuilder().
-	//	IgnorePaths("/users/login").
-	//	IgnorePaths("/users/signup").Build())
-
-	server.Use(middleware.NewLoginJWTMiddlewareBuilder().
-		IgnorePaths("/users/login").
-		IgnorePaths("/users/login_sms/code/send").
-		IgnorePaths("/users/login_sms").
-		IgnorePaths("/users/signup").Build())
+	//JWT 还是 session 由 hdl 决定，见 ioc.InitTokenHandler
+	//不用登录的路由注册的时候加 ginx.Public()
+	server.Use(middleware.NewLoginMiddlewareBuilder(hdl, logger.NewNopLogger()).Build())
 	return server
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"basic-go/mybook/internal/repository/dao"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "用法: mybook migrate up | down [n] | status"
+
+// migrateCommand mybook migrate up|down|status
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	sqlDB, err := openDB().DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+	m := dao.NewMigrator(sqlDB)
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		// 默认只回滚一个版本，避免手滑把整个库都回滚了
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return errors.New(migrateUsage)
+			}
+		}
+		return m.Down(ctx, steps)
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range status {
+			state, appliedAt := "pending", ""
+			if st.Applied {
+				state = "applied"
+				appliedAt = st.AppliedAt.Format(time.DateTime)
+			}
+			if st.Dirty {
+				state = "dirty"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
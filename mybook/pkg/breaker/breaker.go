@@ -0,0 +1,80 @@
+// Package breaker 熔断器，下游连续出错的时候先别调了，过一会儿再放一个请求去试探
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	// StateClosed 正常
+	StateClosed State = iota
+	// StateOpen 熔断中，所有请求都不放过去
+	StateOpen
+	// StateHalfOpen 冷却时间到了，只放一个请求去试探
+	StateHalfOpen
+)
+
+// Breaker 连续失败 threshold 次就熔断，cooldown 之后半开
+// 半开的时候试探成功就恢复，失败就重新熔断
+type Breaker struct {
+	mu        sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow 返回 true 的时候才去调下游，调完要调用 Success 或者 Failure
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = StateHalfOpen
+		return true
+	case StateHalfOpen:
+		// 已经有一个请求在试探了
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = StateClosed
+	b.failures = 0
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
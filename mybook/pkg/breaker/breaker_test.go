@@ -0,0 +1,45 @@
+package breaker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.UnixMilli(1000)
+	b := NewBreaker(3, time.Second)
+	b.now = func() time.Time { return now }
+
+	// 成功会把失败次数清零
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	assert.Equal(t, StateClosed, b.State())
+	assert.True(t, b.Allow())
+
+	b.Failure()
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// 冷却之后只放一个试探
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// 试探失败，重新熔断
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// 试探成功就恢复了
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.Equal(t, StateClosed, b.State())
+	assert.True(t, b.Allow())
+	assert.True(t, b.Allow())
+}
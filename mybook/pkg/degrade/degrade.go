@@ -0,0 +1,17 @@
+// Package degrade 系统过载的时候在 context 里打个标记，service 看到标记就跳过慢路径
+package degrade
+
+import "context"
+
+type contextKey struct{}
+
+// With 标记这个请求处于降级状态
+func With(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, true)
+}
+
+// IsDegraded gin.Context 要打开 ContextWithFallback 才能找到 Request 的 context 上的标记
+func IsDegraded(ctx context.Context) bool {
+	v, _ := ctx.Value(contextKey{}).(bool)
+	return v
+}
@@ -0,0 +1,199 @@
+// Package accesslog 每个请求一条访问日志，请求和响应的 body 可选，会截断和脱敏
+package accesslog
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Options 可以在运行时用 Builder.Update 整个换掉
+type Options struct {
+	Enabled bool `json:"enabled"`
+	// 记不记录请求体和响应体，只记录文本的，文件上传这种不记
+	ReqBody  bool `json:"reqBody"`
+	RespBody bool `json:"respBody"`
+	// body 超过这么多字节就截断
+	MaxBodySize int `json:"maxBodySize"`
+	// 这些路由不打访问日志，格式见 ginx.PathPattern
+	SkipPaths []string `json:"skipPaths"`
+	// 只有这些路由记录 body，空的表示所有路由
+	BodyPaths []string `json:"bodyPaths"`
+}
+
+type compiledOptions struct {
+	Options
+	skip []ginx.PathPattern
+	body []ginx.PathPattern
+}
+
+type Builder struct {
+	l    logger.Logger
+	opts atomic.Pointer[compiledOptions]
+}
+
+func NewBuilder(l logger.Logger, opts Options) *Builder {
+	b := &Builder{l: l}
+	// 启动时候的配置写错了直接起不来
+	if err := b.Update(opts); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Update 运行时修改，正在处理的请求还是用旧的；路由模式不对的时候不生效，返回 error
+func (b *Builder) Update(opts Options) error {
+	co := &compiledOptions{Options: opts}
+	var err error
+	if co.skip, err = parsePatterns(opts.SkipPaths); err != nil {
+		return err
+	}
+	if co.body, err = parsePatterns(opts.BodyPaths); err != nil {
+		return err
+	}
+	b.opts.Store(co)
+	return nil
+}
+
+func parsePatterns(patterns []string) ([]ginx.PathPattern, error) {
+	var res []ginx.PathPattern
+	for _, p := range patterns {
+		pattern, err := ginx.TryParsePathPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, pattern)
+	}
+	return res, nil
+}
+
+func (b *Builder) Options() Options {
+	return b.opts.Load().Options
+}
+
+// Build 要放在 requestid 后面、其它 middleware 前面，这样被限流、没登录的请求也有日志
+// uid 是登录校验的时候放进去的，处理完之后再打日志就能拿到
+func (b *Builder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		opts := b.opts.Load()
+		method, path := ctx.Request.Method, ctx.Request.URL.Path
+		if !opts.Enabled || matchAny(opts.skip, method, path) {
+			ctx.Next()
+			return
+		}
+		withBody := len(opts.body) == 0 || matchAny(opts.body, method, path)
+
+		var reqBody string
+		if withBody && opts.ReqBody {
+			reqBody = b.captureRequest(ctx, opts.MaxBodySize)
+		}
+		var rw *responseWriter
+		if withBody && opts.RespBody && opts.MaxBodySize > 0 {
+			rw = &responseWriter{ResponseWriter: ctx.Writer, max: opts.MaxBodySize}
+			ctx.Writer = rw
+		}
+
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		fields := []logger.Field{
+			logger.String("method", method),
+			logger.String("route", ctx.FullPath()),
+			logger.String("path", path),
+			logger.Int("status", status),
+			logger.Duration("latency", time.Since(start)),
+			logger.String("ip", ctx.ClientIP()),
+		}
+		if reqBody != "" {
+			fields = append(fields, logger.String("req_body", reqBody))
+		}
+		if ct := ctx.Writer.Header().Get("Content-Type"); rw != nil && rw.buf.Len() > 0 && textual(ct) {
+			fields = append(fields, logger.String("resp_body", redactBody(ct, rw.buf.Bytes(), true)+truncated(rw.truncated)))
+		}
+		l := b.l.WithContext(ctx)
+		switch {
+		case status >= http.StatusInternalServerError:
+			l.Error("访问日志", fields...)
+		case status >= http.StatusBadRequest:
+			l.Warn("访问日志", fields...)
+		default:
+			l.Info("访问日志", fields...)
+		}
+	}
+}
+
+// captureRequest 只读前 max 个字节，读过的再拼回去，handler 拿到的还是完整的 body
+func (b *Builder) captureRequest(ctx *gin.Context, max int) string {
+	contentType := ctx.ContentType()
+	if ctx.Request.Body == nil || !textual(contentType) || max <= 0 {
+		return ""
+	}
+	// 多读一个字节，用来判断有没有截断
+	head, err := io.ReadAll(io.LimitReader(ctx.Request.Body, int64(max)+1))
+	ctx.Request.Body = readCloser{
+		Reader: io.MultiReader(bytes.NewReader(head), ctx.Request.Body),
+		Closer: ctx.Request.Body,
+	}
+	if err != nil {
+		return ""
+	}
+	cut := len(head) > max
+	if cut {
+		head = head[:max]
+	}
+	return redactBody(contentType, head, false) + truncated(cut)
+}
+
+func truncated(cut bool) string {
+	if cut {
+		return "...(truncated)"
+	}
+	return ""
+}
+
+func matchAny(patterns []ginx.PathPattern, method, path string) bool {
+	for _, p := range patterns {
+		if p.Match(method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseWriter 写给客户端的同时留一份前 max 个字节
+type responseWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *responseWriter) capture(data []byte) {
+	left := w.max - w.buf.Len()
+	if len(data) > left {
+		data = data[:left]
+		w.truncated = true
+	}
+	w.buf.Write(data)
+}
@@ -0,0 +1,164 @@
+package accesslog
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        Options
+		method      string
+		path        string
+		contentType string
+		body        string
+
+		wantLogged bool
+		wantLevel  zapcore.Level
+		wantFields map[string]any
+	}{
+		{
+			name:        "记录 body，脱敏",
+			opts:        Options{Enabled: true, ReqBody: true, RespBody: true, MaxBodySize: 1024},
+			method:      http.MethodPost,
+			path:        "/users/login_sms",
+			contentType: "application/json",
+			body:        `{"phone":"13511112222","code":"123456"}`,
+			wantLogged:  true,
+			wantLevel:   zapcore.InfoLevel,
+			wantFields: map[string]any{
+				"method":    "POST",
+				"route":     "/users/login_sms",
+				"path":      "/users/login_sms",
+				"status":    int64(200),
+				"req_body":  `{"phone":"135****2222","code":"***"}`,
+				"resp_body": `{"code":0,"msg":"ok","password":"***"}`,
+			},
+		},
+		{
+			name:        "截断",
+			opts:        Options{Enabled: true, ReqBody: true, MaxBodySize: 20},
+			method:      http.MethodPost,
+			path:        "/users/login",
+			contentType: "application/json",
+			body:        `{"email":"a@b.com","password":"hello#world123"}`,
+			wantLogged:  true,
+			wantLevel:   zapcore.InfoLevel,
+			wantFields: map[string]any{
+				"req_body": `{"email":"a@b.com","...(truncated)`,
+			},
+		},
+		{
+			name:        "表单",
+			opts:        Options{Enabled: true, ReqBody: true, MaxBodySize: 1024},
+			method:      http.MethodPost,
+			path:        "/users/login",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "email=a%40b.com&password=123",
+			wantLogged:  true,
+			wantLevel:   zapcore.InfoLevel,
+			wantFields: map[string]any{
+				"req_body": "email=a%40b.com&password=***",
+			},
+		},
+		{
+			name:       "不记录 body 的路由",
+			opts:       Options{Enabled: true, ReqBody: true, MaxBodySize: 1024, BodyPaths: []string{"/users/login_sms"}},
+			method:     http.MethodPost,
+			path:       "/users/login",
+			body:       `{"email":"a@b.com"}`,
+			wantLogged: true,
+			wantLevel:  zapcore.InfoLevel,
+			wantFields: map[string]any{"req_body": nil},
+		},
+		{
+			name:       "跳过的路由",
+			opts:       Options{Enabled: true, SkipPaths: []string{"GET /hello"}},
+			method:     http.MethodGet,
+			path:       "/hello",
+			wantLogged: false,
+		},
+		{
+			name:       "关掉了",
+			opts:       Options{},
+			method:     http.MethodPost,
+			path:       "/users/login",
+			wantLogged: false,
+		},
+		{
+			name:       "出错了打 Error",
+			opts:       Options{Enabled: true},
+			method:     http.MethodGet,
+			path:       "/boom",
+			wantLogged: true,
+			wantLevel:  zapcore.ErrorLevel,
+			wantFields: map[string]any{"status": int64(500)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			server := gin.New()
+			server.Use(NewBuilder(logger.NewZapLogger(zap.New(core)), tc.opts).Build())
+			var handlerBody string
+			handle := func(ctx *gin.Context) {
+				data, _ := io.ReadAll(ctx.Request.Body)
+				handlerBody = string(data)
+				ctx.JSON(http.StatusOK, gin.H{"code": 0, "msg": "ok", "password": "x"})
+			}
+			server.POST("/users/login_sms", handle)
+			server.POST("/users/login", handle)
+			server.GET("/hello", handle)
+			server.GET("/boom", func(ctx *gin.Context) {
+				ctx.Status(http.StatusInternalServerError)
+			})
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			server.ServeHTTP(httptest.NewRecorder(), req)
+			// handler 拿到的 body 要是完整的
+			assert.Equal(t, tc.body, handlerBody)
+
+			if !tc.wantLogged {
+				assert.Equal(t, 0, logs.Len())
+				return
+			}
+			require.Equal(t, 1, logs.Len())
+			entry := logs.All()[0]
+			assert.Equal(t, tc.wantLevel, entry.Level)
+			fields := entry.ContextMap()
+			for k, v := range tc.wantFields {
+				assert.Equal(t, v, fields[k], k)
+			}
+		})
+	}
+}
+
+func TestBuilder_Update(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	b := NewBuilder(logger.NewZapLogger(zap.New(core)), Options{})
+	server := gin.New()
+	server.Use(b.Build())
+	server.GET("/hello", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+	assert.Equal(t, 0, logs.Len())
+	// 运行时打开
+	b.Update(Options{Enabled: true})
+	assert.True(t, b.Options().Enabled)
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+	assert.Equal(t, 1, logs.Len())
+}
@@ -0,0 +1,162 @@
+package accesslog
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// requestOnlyKeys 只在请求体里算敏感字段，响应里的 code 是错误码，要原样记下来
+var requestOnlyKeys = map[string]bool{
+	"code": true,
+}
+
+// sensitive 判断和日志字段的一样，见 logger.IsSensitive，只是响应体放过 requestOnlyKeys
+func sensitive(key string, resp bool) bool {
+	if resp && requestOnlyKeys[strings.ToLower(key)] {
+		return false
+	}
+	return logger.IsSensitive(key)
+}
+
+// redactBody resp 表示是不是响应体
+func redactBody(contentType string, body []byte, resp bool) string {
+	s := string(body)
+	switch {
+	case strings.Contains(contentType, "json"):
+		return redactJSON(s, resp)
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		vals, err := url.ParseQuery(s)
+		if err != nil {
+			// 截断了解析不了，宁可不打
+			return "<unparsable form>"
+		}
+		keys := make([]string, 0, len(vals))
+		for k := range vals {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var sb strings.Builder
+		for _, k := range keys {
+			for _, v := range vals[k] {
+				if sb.Len() > 0 {
+					sb.WriteByte('&')
+				}
+				sb.WriteString(url.QueryEscape(k))
+				sb.WriteByte('=')
+				// 脱敏过的不转义，* 转义了反而看不清
+				switch {
+				case sensitive(k, resp):
+					sb.WriteString("***")
+				case strings.EqualFold(k, "phone"):
+					sb.WriteString(logger.MaskPhone(v))
+				default:
+					sb.WriteString(url.QueryEscape(v))
+				}
+			}
+		}
+		return sb.String()
+	default:
+		// textual 之外的类型不会走到这里，万一走到了也不打原文
+		return "<unsupported content type>"
+	}
+}
+
+// redactJSON body 可能被截断了，所以不完整解析 JSON，只是按字符扫描
+// 找到 "key": 之后，敏感字段把整个值换成 "***"，值是数组、对象的也一样
+func redactJSON(s string, resp bool) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); {
+		if s[i] != '"' {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := jsonStringEnd(s, i)
+		colon := skipSpace(s, end)
+		if colon >= len(s) || s[colon] != ':' {
+			// 不是 key，原样输出
+			sb.WriteString(s[i:end])
+			i = end
+			continue
+		}
+		key := s[i+1 : end-1]
+		start := skipSpace(s, colon+1)
+		sb.WriteString(s[i:start])
+		val := s[start:jsonValueEnd(s, start)]
+		switch {
+		case sensitive(key, resp):
+			sb.WriteString(`"***"`)
+		case strings.EqualFold(key, "phone") && strings.HasPrefix(val, `"`):
+			sb.WriteString(`"` + logger.MaskPhone(strings.Trim(val, `"`)) + `"`)
+		default:
+			// 值是对象的话里面还有 key，接着往里扫
+			i = start
+			continue
+		}
+		i = start + len(val)
+	}
+	return sb.String()
+}
+
+// jsonStringEnd start 是左引号，返回右引号的下一个位置，截断了没有右引号的返回 len(s)
+func jsonStringEnd(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// jsonValueEnd 返回从 start 开始的值的结束位置，数组、对象按括号配对，跳过字符串里的括号
+func jsonValueEnd(s string, start int) int {
+	if start >= len(s) {
+		return start
+	}
+	switch s[start] {
+	case '"':
+		return jsonStringEnd(s, start)
+	case '{', '[':
+		depth := 0
+		for i := start; i < len(s); i++ {
+			switch s[i] {
+			case '"':
+				i = jsonStringEnd(s, i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(s)
+	default:
+		// 数字、true、false、null
+		i := start
+		for i < len(s) && !strings.ContainsRune(",}] \t\r\n", rune(s[i])) {
+			i++
+		}
+		return i
+	}
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i])) {
+		i++
+	}
+	return i
+}
+
+// textual 只记能脱敏的，图片、文件不记；text/* 没有字段，脱敏不了，也不记
+func textual(contentType string) bool {
+	return strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "x-www-form-urlencoded")
+}
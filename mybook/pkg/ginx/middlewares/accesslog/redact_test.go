@@ -0,0 +1,86 @@
+package accesslog
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		resp        bool
+
+		want string
+	}{
+		{
+			name:        "字符串和数字",
+			contentType: "application/json",
+			body:        `{"phone": "13511112222", "code": 123456, "token":null}`,
+			want:        `{"phone": "135****2222", "code": "***", "token":"***"}`,
+		},
+		{
+			name:        "值是数组",
+			contentType: "application/json",
+			body:        `{"tokens":["a,b","c]"],"password":[1, 2],"ok":true}`,
+			want:        `{"tokens":"***","password":"***","ok":true}`,
+		},
+		{
+			name:        "值是对象",
+			contentType: "application/json",
+			body:        `{"secret":{"key":"a}b","n":{"m":1}},"email":"a@b.com"}`,
+			want:        `{"secret":"***","email":"a@b.com"}`,
+		},
+		{
+			name:        "嵌套在对象里面的",
+			contentType: "application/json",
+			body:        `{"user":{"phone":"13511112222","password":"x"},"list":[{"token":"y"}]}`,
+			want:        `{"user":{"phone":"135****2222","password":"***"},"list":[{"token":"***"}]}`,
+		},
+		{
+			name:        "值里面有转义的引号",
+			contentType: "application/json",
+			body:        `{"msg":"say \"password\": hi","password":"a\"b"}`,
+			want:        `{"msg":"say \"password\": hi","password":"***"}`,
+		},
+		{
+			name:        "截断在敏感字段的值里面",
+			contentType: "application/json",
+			body:        `{"email":"a@b.com","password":["hello`,
+			want:        `{"email":"a@b.com","password":"***"`,
+		},
+		{
+			name:        "响应里的 code 是错误码，不脱敏",
+			contentType: "application/json",
+			body:        `{"code":100002,"msg":"参数错误","data":{"token":"x","phone":"13511112222"}}`,
+			resp:        true,
+			want:        `{"code":100002,"msg":"参数错误","data":{"token":"***","phone":"135****2222"}}`,
+		},
+		{
+			name:        "表单",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "phone=13511112222&code=123456&biz=login",
+			want:        "biz=login&code=***&phone=135****2222",
+		},
+		{
+			name:        "text 不打原文",
+			contentType: "text/plain",
+			body:        "password=123",
+			want:        "<unsupported content type>",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, redactBody(tc.contentType, []byte(tc.body), tc.resp))
+		})
+	}
+}
+
+func TestTextual(t *testing.T) {
+	assert.True(t, textual("application/json; charset=utf-8"))
+	assert.True(t, textual("application/x-www-form-urlencoded"))
+	// 没法按字段脱敏的都不记
+	assert.False(t, textual("text/plain; charset=utf-8"))
+	assert.False(t, textual("image/png"))
+}
@@ -0,0 +1,51 @@
+// Package metrics HTTP 请求的 Prometheus 指标
+package metrics
+
+import (
+	"basic-go/mybook/pkg/metricsx"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"time"
+)
+
+type Builder struct {
+	namespace string
+	subsystem string
+}
+
+func NewBuilder(namespace, subsystem string) *Builder {
+	return &Builder{namespace: namespace, subsystem: subsystem}
+}
+
+// Build route 用注册的路由模板，不用真实路径，不然 /users/:id 这种 label 会爆炸
+// 没匹配上路由的（404）都算到 unknown 里
+func (b *Builder) Build() gin.HandlerFunc {
+	duration := metricsx.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: b.namespace,
+		Subsystem: b.subsystem,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求的处理时间",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"}))
+	inFlight := metricsx.Register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: b.namespace,
+		Subsystem: b.subsystem,
+		Name:      "http_requests_in_flight",
+		Help:      "正在处理的 HTTP 请求数",
+	}))
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		inFlight.Inc()
+		defer func() {
+			inFlight.Dec()
+			route := ctx.FullPath()
+			if route == "" {
+				route = "unknown"
+			}
+			duration.WithLabelValues(ctx.Request.Method, route,
+				strconv.Itoa(ctx.Writer.Status())).Observe(time.Since(start).Seconds())
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	server := gin.New()
+	server.Use(NewBuilder("test", "http").Build())
+	server.GET("/users/:id", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	for _, path := range []string{"/users/1", "/users/2", "/not-found"} {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	counts := map[string]uint64{}
+	for _, f := range families {
+		if f.GetName() != "test_http_http_request_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			var route string
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "route" {
+					route = l.GetValue()
+				}
+			}
+			counts[route] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	// 用的是路由模板，两个请求算在一起
+	assert.Equal(t, map[string]uint64{"/users/:id": 2, "unknown": 1}, counts)
+}
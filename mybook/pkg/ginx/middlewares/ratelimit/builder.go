@@ -1,61 +1,136 @@
 package ratelimit
 
 import (
-	_ "embed"
-	"fmt"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/limiter"
+	"basic-go/mybook/pkg/logger"
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
-	"log"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+// Builder 一个请求要匹配到的每条规则都放行才能过
 type Builder struct {
-	prefix   string
-	cmd      redis.Cmdable
-	interval time.Duration
-	// 阈值
-	rate int
+	prefix string
+	rules  []rule
+	l      logger.Logger
 }
 
-//go:embed slide_window.lua
-var luaScript string
+type rule struct {
+	name     string
+	patterns []ginx.PathPattern
+	key      KeyFunc
+	limiter  limiter.Limiter
+}
+
+// NewBuilder 默认一条规则：所有路由按 IP 限流
+func NewBuilder(l limiter.Limiter) *Builder {
+	return NewRuleBuilder().Rule("ip", l, KeyByIP())
+}
 
-func NewBuilder(cmd redis.Cmdable, interval time.Duration, rate int) *Builder {
+// NewRuleBuilder 没有任何规则，自己用 Rule 加
+func NewRuleBuilder() *Builder {
 	return &Builder{
-		cmd:      cmd,
-		prefix:   "ip-limiter",
-		interval: interval,
-		rate:     rate,
+		prefix: "ip-limiter",
+		l:      logger.NewNopLogger(),
 	}
 }
 
+func (b *Builder) Logger(l logger.Logger) *Builder {
+	b.l = l
+	return b
+}
+
 func (b *Builder) Prefix(prefix string) *Builder {
 	b.prefix = prefix
 	return b
 }
 
+// Rule name 会拼到 Redis 的 key 里面，不同规则不要重名
+// patterns 格式见 ginx.PathPattern，不传就是所有路由
+func (b *Builder) Rule(name string, l limiter.Limiter, key KeyFunc, patterns ...string) *Builder {
+	r := rule{name: name, key: key, limiter: l}
+	for _, p := range patterns {
+		r.patterns = append(r.patterns, ginx.ParsePathPattern(p))
+	}
+	b.rules = append(b.rules, r)
+	return b
+}
+
+// Build 响应头里的额度用匹配到的规则里面最紧的那个
+// 限流器出错的时候返回 500，要降级就用 limiter.DegradeLimiter 包一层
 func (b *Builder) Build() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		limited, err := b.limit(ctx)
-		if err != nil {
-			log.Println(err)
-			// 这一步很有意思，就是如果这边出错了
-			// 要怎么办？
-			ctx.AbortWithStatus(http.StatusInternalServerError)
-			return
+		var (
+			tightest limiter.Result
+			found    bool
+		)
+		for _, r := range b.rules {
+			if !r.match(ctx) {
+				continue
+			}
+			key, ok := r.key(ctx)
+			if !ok {
+				continue
+			}
+			res, err := r.limiter.Limit(ctx, b.prefix+":"+r.name+":"+key)
+			if err != nil {
+				b.l.WithContext(ctx).Error("限流器出错", logger.String("rule", r.name), logger.Error(err))
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, ginx.SystemErrResult)
+				return
+			}
+			if res.Limited {
+				b.l.WithContext(ctx).Warn("触发限流", logger.String("rule", r.name), logger.String("key", key))
+				setHeaders(ctx, res)
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, ginx.TooManyRequestsResult)
+				return
+			}
+			if res.Limit > 0 && (!found || res.Remaining < tightest.Remaining) {
+				tightest, found = res, true
+			}
 		}
-		if limited {
-			log.Println(err)
-			ctx.AbortWithStatus(http.StatusTooManyRequests)
-			return
+		if found && tighter(ctx, tightest) {
+			setHeaders(ctx, tightest)
 		}
 		ctx.Next()
 	}
 }
 
-func (b *Builder) limit(ctx *gin.Context) (bool, error) {
-	key := fmt.Sprintf("%s:%s", b.prefix, ctx.ClientIP())
-	return b.cmd.Eval(ctx, luaScript, []string{key},
-		b.interval.Milliseconds(), b.rate, time.Now().UnixMilli()).Bool()
+// setHeaders 时间都是秒数，向上取整
+func setHeaders(ctx *gin.Context, res limiter.Result) {
+	if res.Limit > 0 {
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		ctx.Header("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+	}
+	if res.Limited {
+		retry := ceilSeconds(res.RetryAfter)
+		if retry < 1 {
+			retry = 1
+		}
+		ctx.Header("Retry-After", strconv.FormatInt(retry, 10))
+	}
+}
+
+// tighter 登录前后可能各有一个限流 middleware，前面那个已经写了响应头的话，更紧的才覆盖
+func tighter(ctx *gin.Context, res limiter.Result) bool {
+	prev, err := strconv.Atoi(ctx.Writer.Header().Get("X-RateLimit-Remaining"))
+	return err != nil || res.Remaining < prev
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+func (r rule) match(ctx *gin.Context) bool {
+	if len(r.patterns) == 0 {
+		return true
+	}
+	for _, p := range r.patterns {
+		if p.Match(ctx.Request.Method, ctx.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"basic-go/mybook/pkg/limiter"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type limitFunc func(ctx context.Context, key string) (limiter.Result, error)
+
+func (f limitFunc) Limit(ctx context.Context, key string) (limiter.Result, error) {
+	return f(ctx, key)
+}
+
+func TestBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name       string
+		limiter    limiter.Limiter
+		wantCode   int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:     "放行",
+			limiter:  limiter.NewLocalFixedWindowLimiter(time.Second, 1),
+			wantCode: http.StatusOK,
+			wantHeader: map[string]string{
+				"X-RateLimit-Limit":     "1",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "1",
+				"Retry-After":           "",
+			},
+		},
+		{
+			name: "限流",
+			limiter: limitFunc(func(ctx context.Context, key string) (limiter.Result, error) {
+				return limiter.Result{Limited: true, Limit: 10,
+					RetryAfter: time.Millisecond * 1500, Reset: time.Second * 3}, nil
+			}),
+			wantCode: http.StatusTooManyRequests,
+			wantBody: `{"code":4,"msg":"请求太频繁，请稍后重试","data":null}`,
+			wantHeader: map[string]string{
+				"X-RateLimit-Limit":     "10",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "3",
+				"Retry-After":           "2",
+			},
+		},
+		{
+			name: "降级之后全部限流，没有额度信息",
+			limiter: limitFunc(func(ctx context.Context, key string) (limiter.Result, error) {
+				return limiter.Result{Limited: true}, nil
+			}),
+			wantCode: http.StatusTooManyRequests,
+			wantHeader: map[string]string{
+				"X-RateLimit-Limit": "",
+				"Retry-After":       "1",
+			},
+		},
+		{
+			name: "限流器出错",
+			limiter: limitFunc(func(ctx context.Context, key string) (limiter.Result, error) {
+				return limiter.Result{}, errors.New("mock redis error")
+			}),
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"code":5,"msg":"系统错误","data":null}`,
+		},
+		{
+			name: "按 IP 限流",
+			limiter: limitFunc(func(ctx context.Context, key string) (limiter.Result, error) {
+				return limiter.Result{Limited: key != "my-limiter:ip:10.0.0.1"}, nil
+			}),
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(NewBuilder(tc.limiter).Prefix("my-limiter").Build())
+			server.GET("/hello", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+			req.RemoteAddr = "10.0.0.1:12345"
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, resp.Body.String())
+			}
+			for k, v := range tc.wantHeader {
+				assert.Equal(t, v, resp.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestBuilder_Rules(t *testing.T) {
+	server := gin.New()
+	server.Use(NewRuleBuilder().
+		Rule("ip", limiter.NewLocalFixedWindowLimiter(time.Hour, 3), KeyByIP()).
+		Rule("sms", limiter.NewLocalFixedWindowLimiter(time.Hour, 1), KeyByIP(), "POST /users/login_sms/code/send").
+		Build())
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	server.POST("/users/login_sms/code/send", ok)
+	server.GET("/users/profile", ok)
+
+	testCases := []struct {
+		method   string
+		path     string
+		wantCode int
+	}{
+		{method: http.MethodPost, path: "/users/login_sms/code/send", wantCode: http.StatusOK},
+		// sms 的规则不让过
+		{method: http.MethodPost, path: "/users/login_sms/code/send", wantCode: http.StatusTooManyRequests},
+		// 别的接口只有 ip 的规则，前面两次都算了
+		{method: http.MethodGet, path: "/users/profile", wantCode: http.StatusOK},
+		{method: http.MethodGet, path: "/users/profile", wantCode: http.StatusTooManyRequests},
+	}
+	for i, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.RemoteAddr = "10.0.0.1:12345"
+		resp := httptest.NewRecorder()
+		server.ServeHTTP(resp, req)
+		assert.Equal(t, tc.wantCode, resp.Code, "第 %d 个请求", i)
+	}
+}
+
+func TestBuilder_TightestHeaders(t *testing.T) {
+	server := gin.New()
+	server.Use(NewRuleBuilder().
+		Rule("ip", limiter.NewLocalFixedWindowLimiter(time.Hour, 100), KeyByIP()).
+		Rule("route", limiter.NewLocalFixedWindowLimiter(time.Minute, 5), KeyByRoute()).
+		Build())
+	server.GET("/hello", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	// 剩得最少的是 route 那条
+	assert.Equal(t, "5", resp.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "4", resp.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header().Get("X-RateLimit-Reset"))
+}
+
+func TestBuilder_TightestHeadersAcrossBuilders(t *testing.T) {
+	server := gin.New()
+	server.Use(NewRuleBuilder().
+		Rule("route", limiter.NewLocalFixedWindowLimiter(time.Minute, 5), KeyByRoute()).
+		Build())
+	// 后面那个更松，不能把前面的响应头盖掉
+	server.Use(NewRuleBuilder().
+		Rule("ip", limiter.NewLocalFixedWindowLimiter(time.Hour, 100), KeyByIP()).
+		Build())
+	server.GET("/hello", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "5", resp.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "4", resp.Header().Get("X-RateLimit-Remaining"))
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// KeyFunc 从请求里拿限流对象，拿不到的时候返回 false，这条规则就不管这个请求
+type KeyFunc func(ctx *gin.Context) (string, bool)
+
+func KeyByIP() KeyFunc {
+	return func(ctx *gin.Context) (string, bool) {
+		return ctx.ClientIP(), true
+	}
+}
+
+// KeyByClaims 按登录用户限流，要放在登录校验的后面，没登录的请求不管
+func KeyByClaims[C any](fn func(claims C) string) KeyFunc {
+	return func(ctx *gin.Context) (string, bool) {
+		val, ok := ctx.Get(ginx.ClaimsKey)
+		if !ok {
+			return "", false
+		}
+		claims, ok := val.(C)
+		if !ok {
+			return "", false
+		}
+		key := fn(claims)
+		return key, key != ""
+	}
+}
+
+// KeyByRoute 用路由模板，例如 "GET /users/:id"，所有 id 算一个
+// 匹配不到路由的请求不管，不然随便拼个路径就是一个新的 key
+func KeyByRoute() KeyFunc {
+	return func(ctx *gin.Context) (string, bool) {
+		key := ginx.RouteKey(ctx)
+		return key, key != ""
+	}
+}
+
+// KeyByHeader 例如按 API key 限流，没带这个头的请求不管
+func KeyByHeader(name string) KeyFunc {
+	return func(ctx *gin.Context) (string, bool) {
+		val := ctx.GetHeader(name)
+		return val, val != ""
+	}
+}
+
+// KeyCombine 例如 IP + 路由，每个 IP 在每个接口上单独算，有一个拿不到就不管
+func KeyCombine(fns ...KeyFunc) KeyFunc {
+	return func(ctx *gin.Context) (string, bool) {
+		parts := make([]string, 0, len(fns))
+		for _, fn := range fns {
+			part, ok := fn(ctx)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ":"), true
+	}
+}
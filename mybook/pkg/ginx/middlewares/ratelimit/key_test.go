@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type testClaims struct {
+	Uid int64
+}
+
+func TestKeyFunc(t *testing.T) {
+	byUid := KeyByClaims(func(c *testClaims) string {
+		return strconv.FormatInt(c.Uid, 10)
+	})
+	testCases := []struct {
+		name   string
+		key    KeyFunc
+		path   string
+		before func(ctx *gin.Context)
+
+		wantKey string
+		wantOk  bool
+	}{
+		{name: "IP", key: KeyByIP(), path: "/users/123", wantKey: "10.0.0.1", wantOk: true},
+		{
+			name: "用户", key: byUid, path: "/users/123",
+			before:  func(ctx *gin.Context) { ctx.Set(ginx.ClaimsKey, &testClaims{Uid: 123}) },
+			wantKey: "123", wantOk: true,
+		},
+		{name: "没登录", key: byUid, path: "/users/123"},
+		{
+			name: "claims 类型不对", key: byUid, path: "/users/123",
+			before: func(ctx *gin.Context) { ctx.Set(ginx.ClaimsKey, "123") },
+		},
+		{name: "路由模板", key: KeyByRoute(), path: "/users/123", wantKey: "GET /users/:id", wantOk: true},
+		{name: "匹配不到路由", key: KeyByRoute(), path: "/not_found"},
+		{name: "请求头", key: KeyByHeader("X-Api-Key"), path: "/users/123", wantKey: "k-1", wantOk: true},
+		{name: "没带请求头", key: KeyByHeader("X-Device-Id"), path: "/users/123"},
+		{
+			name: "组合", key: KeyCombine(KeyByIP(), KeyByRoute()), path: "/users/123",
+			wantKey: "10.0.0.1:GET /users/:id", wantOk: true,
+		},
+		{name: "组合有一个拿不到", key: KeyCombine(KeyByIP(), byUid), path: "/users/123"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				key string
+				ok  bool
+			)
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.before != nil {
+					tc.before(ctx)
+				}
+				key, ok = tc.key(ctx)
+			})
+			server.GET("/users/:id", func(ctx *gin.Context) {})
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.RemoteAddr = "10.0.0.1:12345"
+			req.Header.Set("X-Api-Key", "k-1")
+			server.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.wantKey, key)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Package recovery 处理请求的时候 panic 了，打日志、记监控，返回统一的 Result
+package recovery
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"basic-go/mybook/pkg/metricsx"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+type Builder struct {
+	l       logger.Logger
+	counter *prometheus.CounterVec
+}
+
+func NewBuilder(namespace string, l logger.Logger) *Builder {
+	return &Builder{
+		l: l,
+		counter: metricsx.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_panics_total",
+			Help:      "处理请求的时候 panic 的次数",
+		}, []string{"route"})),
+	}
+}
+
+// Build 返回 500 和 ginx.SystemErrResult，已经写了响应的就只能断掉了
+// 放在 request id、trace 后面，日志才能带上它们；放在访问日志、监控后面，它们才能看到 500
+func (b *Builder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// net/http 约定用它来中断响应，要继续往上抛
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			route := ctx.FullPath()
+			if route == "" {
+				route = "unknown"
+			}
+			l := b.l.WithContext(ctx).With(
+				logger.String("method", ctx.Request.Method),
+				logger.String("path", ctx.Request.URL.Path),
+				logger.String("route", route),
+			)
+			err := panicError(r)
+			// 客户端已经断开了，不是代码的问题，也写不了响应
+			if brokenPipe(err) {
+				l.Warn("客户端断开连接", logger.Error(err))
+				_ = ctx.Error(err)
+				ctx.Abort()
+				return
+			}
+			b.counter.WithLabelValues(route).Inc()
+			l.Error("处理请求 panic", logger.Error(err), logger.String("stack", string(debug.Stack())))
+			span := trace.SpanFromContext(ctx.Request.Context())
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "panic")
+			if ctx.Writer.Written() {
+				ctx.Abort()
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, ginx.SystemErrResult)
+		}()
+		ctx.Next()
+	}
+}
+
+func panicError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// brokenPipe 和 gin.Recovery 的判断一样
+func brokenPipe(err error) bool {
+	var ne *net.OpError
+	if !errors.As(err, &ne) {
+		return false
+	}
+	var se *os.SyscallError
+	if !errors.As(ne, &se) {
+		return false
+	}
+	msg := strings.ToLower(se.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
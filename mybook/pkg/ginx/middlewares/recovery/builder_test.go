@@ -0,0 +1,139 @@
+package recovery
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler gin.HandlerFunc
+
+		wantCode  int
+		wantBody  *ginx.Result
+		wantPanic bool
+		wantLog   bool
+		wantLevel zapcore.Level
+	}{
+		{
+			name: "panic 字符串",
+			handler: func(ctx *gin.Context) {
+				panic("mock panic")
+			},
+			wantCode:  http.StatusInternalServerError,
+			wantBody:  &ginx.SystemErrResult,
+			wantPanic: true,
+			wantLog:   true,
+			wantLevel: zapcore.ErrorLevel,
+		},
+		{
+			name: "panic error",
+			handler: func(ctx *gin.Context) {
+				panic(errors.New("mock error"))
+			},
+			wantCode:  http.StatusInternalServerError,
+			wantBody:  &ginx.SystemErrResult,
+			wantPanic: true,
+			wantLog:   true,
+			wantLevel: zapcore.ErrorLevel,
+		},
+		{
+			name: "已经写了响应",
+			handler: func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "hello")
+				panic("mock panic")
+			},
+			wantCode:  http.StatusOK,
+			wantPanic: true,
+			wantLog:   true,
+			wantLevel: zapcore.ErrorLevel,
+		},
+		{
+			name: "客户端断开",
+			handler: func(ctx *gin.Context) {
+				panic(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)})
+			},
+			wantCode:  http.StatusOK,
+			wantLog:   true,
+			wantLevel: zapcore.WarnLevel,
+		},
+		{
+			name: "没有 panic",
+			handler: func(ctx *gin.Context) {
+				ctx.Status(http.StatusNoContent)
+			},
+			wantCode: http.StatusNoContent,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			b := NewBuilder("test", logger.NewZapLogger(zap.New(core)))
+			server := gin.New()
+			// 和 ioc.InitGin 一样，日志字段只在 Request 的 context 上
+			server.ContextWithFallback = true
+			server.Use(func(ctx *gin.Context) {
+				ginx.AddLogFields(ctx, logger.String("request_id", "abc"))
+			}, b.Build())
+			server.GET("/users/:id", tc.handler)
+			before := testutil.ToFloat64(b.counter.WithLabelValues("/users/:id"))
+
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != nil {
+				var res ginx.Result
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+				assert.Equal(t, *tc.wantBody, res)
+			}
+			wantCnt := before
+			if tc.wantPanic {
+				wantCnt++
+			}
+			assert.Equal(t, wantCnt, testutil.ToFloat64(b.counter.WithLabelValues("/users/:id")))
+
+			entries := logs.AllUntimed()
+			if !tc.wantLog {
+				assert.Empty(t, entries)
+				return
+			}
+			require.Len(t, entries, 1)
+			assert.Equal(t, tc.wantLevel, entries[0].Level)
+			fields := entries[0].ContextMap()
+			assert.Equal(t, "abc", fields["request_id"])
+			assert.Equal(t, "/users/:id", fields["route"])
+			if tc.wantPanic {
+				assert.Contains(t, fields["stack"], "builder_test.go")
+			}
+		})
+	}
+}
+
+func TestBuilder_AbortHandler(t *testing.T) {
+	server := gin.New()
+	server.Use(NewBuilder("test", logger.NewNopLogger()).Build())
+	server.GET("/", func(ctx *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+	// 要交给 net/http 处理
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,49 @@
+// Package requestid 给每个请求一个 id，放在响应头和日志里，排查问题的时候按 id 串起来
+package requestid
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultHeader = "X-Request-Id"
+	// ctxKey 用 Get 拿
+	ctxKey = "request_id"
+	// 上游传过来的太长就不要了，免得日志被撑爆
+	maxLen = 64
+)
+
+type Builder struct {
+	header string
+}
+
+func NewBuilder() *Builder {
+	return &Builder{header: DefaultHeader}
+}
+
+func (b *Builder) Header(header string) *Builder {
+	b.header = header
+	return b
+}
+
+// Build 上游（网关）带了就沿用，没带就生成一个
+func (b *Builder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(b.header)
+		if id == "" || len(id) > maxLen {
+			id = uuid.NewString()
+		}
+		ctx.Set(ctxKey, id)
+		ctx.Header(b.header, id)
+		ginx.AddLogFields(ctx, logger.String("request_id", id))
+		ctx.Next()
+	}
+}
+
+// Get 没经过这个 middleware 的返回空字符串
+func Get(ctx *gin.Context) string {
+	return ctx.GetString(ctxKey)
+}
@@ -0,0 +1,63 @@
+package requestid
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+
+		wantId func(t *testing.T, id string)
+	}{
+		{
+			name:   "沿用上游的",
+			header: "abc-123",
+			wantId: func(t *testing.T, id string) {
+				assert.Equal(t, "abc-123", id)
+			},
+		},
+		{
+			name: "没带就生成一个",
+			wantId: func(t *testing.T, id string) {
+				assert.Len(t, id, 36)
+			},
+		},
+		{
+			name:   "太长了不要",
+			header: strings.Repeat("a", 100),
+			wantId: func(t *testing.T, id string) {
+				assert.Len(t, id, 36)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var id string
+			var fields []logger.Field
+			server := gin.New()
+			server.Use(NewBuilder().Build())
+			server.GET("/hello", func(ctx *gin.Context) {
+				id = Get(ctx)
+				fields = logger.FieldsFromContext(ctx.Request.Context())
+				ctx.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+			if tc.header != "" {
+				req.Header.Set(DefaultHeader, tc.header)
+			}
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			tc.wantId(t, id)
+			assert.Equal(t, id, resp.Header().Get(DefaultHeader))
+			assert.Equal(t, []logger.Field{logger.String("request_id", id)}, fields)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package shedding 过载保护，和限流的固定额度不同，看的是正在处理的请求数和路由最近的 p99
+package shedding
+
+import (
+	"basic-go/mybook/pkg/degrade"
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"basic-go/mybook/pkg/metricsx"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type level int
+
+const (
+	levelNormal level = iota
+	// 快到阈值了，放行但是打上降级标记
+	levelBusy
+	// 超过阈值，低优先级的直接拒掉
+	levelOverloaded
+)
+
+// Builder 超过阈值的时候低优先级的请求返回 503，高优先级的放行但是打上降级标记
+// 到了阈值的 degradeRatio 就开始给所有请求打降级标记
+type Builder struct {
+	maxInFlight  int64
+	maxLatency   time.Duration
+	degradeRatio float64
+	window       time.Duration
+	samples      int
+	minSamples   int
+	high         []ginx.PathPattern
+
+	inFlight atomic.Int64
+	routes   sync.Map
+	// action 是 shed 的是拒掉的请求，degraded 是打了降级标记放过去的请求
+	counter *prometheus.CounterVec
+	now     func() time.Time
+	l       logger.Logger
+}
+
+// NewBuilder maxInFlight 是这个实例同时处理的请求数，maxLatency 是每个路由的 p99
+// 哪个为 0 就不看哪个
+func NewBuilder(namespace string, maxInFlight int, maxLatency time.Duration) *Builder {
+	return &Builder{
+		maxInFlight:  int64(maxInFlight),
+		maxLatency:   maxLatency,
+		degradeRatio: 0.8,
+		window:       time.Second * 10,
+		samples:      1000,
+		minSamples:   100,
+		counter: metricsx.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_load_shedding_total",
+			Help:      "过载保护拒掉和降级的请求数",
+		}, []string{"action"})),
+		now: time.Now,
+		l:   logger.NewNopLogger(),
+	}
+}
+
+func (b *Builder) Logger(l logger.Logger) *Builder {
+	b.l = l
+	return b
+}
+
+func (b *Builder) DegradeRatio(ratio float64) *Builder {
+	b.degradeRatio = ratio
+	return b
+}
+
+// Window p99 只看最近 window 之内的请求，每个路由最多保留 samples 个
+func (b *Builder) Window(window time.Duration, samples int) *Builder {
+	b.window = window
+	b.samples = samples
+	return b
+}
+
+// MinSamples 样本太少的时候不按延迟判断
+func (b *Builder) MinSamples(n int) *Builder {
+	b.minSamples = n
+	return b
+}
+
+// HighPriority 过载的时候也不拒的路由，格式见 ginx.PathPattern
+func (b *Builder) HighPriority(patterns ...string) *Builder {
+	for _, p := range patterns {
+		b.high = append(b.high, ginx.ParsePathPattern(p))
+	}
+	return b
+}
+
+func (b *Builder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ginx.RouteKey(ctx)
+		w := b.latencyWindow(route)
+		inFlight := b.inFlight.Add(1)
+		defer b.inFlight.Add(-1)
+
+		switch b.level(inFlight, w.percentile99(b.now(), b.minSamples)) {
+		case levelOverloaded:
+			if !b.highPriority(ctx) {
+				b.counter.WithLabelValues("shed").Inc()
+				b.l.WithContext(ctx).Warn("过载保护拒绝请求", logger.String("route", route), logger.Int64("in_flight", inFlight))
+				ctx.Header("Retry-After", "1")
+				ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, ginx.ServiceUnavailableResult)
+				return
+			}
+			b.markDegraded(ctx)
+		case levelBusy:
+			b.markDegraded(ctx)
+		}
+
+		start := b.now()
+		ctx.Next()
+		end := b.now()
+		w.add(end.Sub(start), end)
+	}
+}
+
+func (b *Builder) level(inFlight int64, p99 time.Duration) level {
+	if (b.maxInFlight > 0 && inFlight > b.maxInFlight) ||
+		(b.maxLatency > 0 && p99 > b.maxLatency) {
+		return levelOverloaded
+	}
+	if (b.maxInFlight > 0 && float64(inFlight) > float64(b.maxInFlight)*b.degradeRatio) ||
+		(b.maxLatency > 0 && float64(p99) > float64(b.maxLatency)*b.degradeRatio) {
+		return levelBusy
+	}
+	return levelNormal
+}
+
+func (b *Builder) latencyWindow(route string) *latencyWindow {
+	if w, ok := b.routes.Load(route); ok {
+		return w.(*latencyWindow)
+	}
+	w, _ := b.routes.LoadOrStore(route, newLatencyWindow(b.samples, b.window))
+	return w.(*latencyWindow)
+}
+
+func (b *Builder) highPriority(ctx *gin.Context) bool {
+	for _, p := range b.high {
+		if p.Match(ctx.Request.Method, ctx.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// markDegraded 只标记在 Request 的 context 上，gin.Context 靠 ContextWithFallback 找过去
+func (b *Builder) markDegraded(ctx *gin.Context) {
+	b.counter.WithLabelValues("degraded").Inc()
+	ctx.Request = ctx.Request.WithContext(degrade.With(ctx.Request.Context()))
+}
@@ -0,0 +1,138 @@
+package shedding
+
+import (
+	"basic-go/mybook/pkg/degrade"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBuilder_InFlight(t *testing.T) {
+	testCases := []struct {
+		name string
+		// 除了这个请求之外，正在处理的请求数
+		inFlight int64
+		path     string
+
+		wantCode     int
+		wantDegraded bool
+	}{
+		{
+			name:     "正常",
+			inFlight: 7,
+			path:     "/articles",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:         "快到阈值了，打上降级标记",
+			inFlight:     8,
+			path:         "/articles",
+			wantCode:     http.StatusOK,
+			wantDegraded: true,
+		},
+		{
+			name:     "过载，低优先级的拒掉",
+			inFlight: 10,
+			path:     "/articles",
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "过载，高优先级的放行",
+			inFlight:     10,
+			path:         "/users/login_sms",
+			wantCode:     http.StatusOK,
+			wantDegraded: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBuilder("test", 10, 0).HighPriority("/users/login_sms")
+			b.inFlight.Store(tc.inFlight)
+			shed, degradedCnt := testutil.ToFloat64(b.counter.WithLabelValues("shed")),
+				testutil.ToFloat64(b.counter.WithLabelValues("degraded"))
+			var degraded, reqDegraded bool
+			server := gin.New()
+			server.ContextWithFallback = true
+			server.Use(b.Build())
+			server.Any("/*path", func(ctx *gin.Context) {
+				degraded = degrade.IsDegraded(ctx)
+				reqDegraded = degrade.IsDegraded(ctx.Request.Context())
+				ctx.Status(http.StatusOK)
+			})
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, tc.path, nil))
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantCode == http.StatusServiceUnavailable {
+				assert.JSONEq(t, `{"code":5,"msg":"系统繁忙，请稍后重试","data":null}`, resp.Body.String())
+			}
+			assert.Equal(t, tc.wantDegraded, degraded)
+			assert.Equal(t, tc.wantDegraded, reqDegraded)
+			// 处理完了要减回去
+			assert.Equal(t, tc.inFlight, b.inFlight.Load())
+			wantShed, wantDegraded := 0.0, 0.0
+			if tc.wantCode == http.StatusServiceUnavailable {
+				wantShed = 1
+			}
+			if tc.wantDegraded {
+				wantDegraded = 1
+			}
+			assert.Equal(t, shed+wantShed, testutil.ToFloat64(b.counter.WithLabelValues("shed")))
+			assert.Equal(t, degradedCnt+wantDegraded, testutil.ToFloat64(b.counter.WithLabelValues("degraded")))
+		})
+	}
+}
+
+func TestBuilder_Latency(t *testing.T) {
+	now := time.UnixMilli(1000)
+	b := NewBuilder("test", 0, time.Millisecond*100).Window(time.Second*10, 100).MinSamples(10)
+	b.now = func() time.Time { return now }
+	// 每个请求处理 latency 这么久
+	var latency time.Duration
+	server := gin.New()
+	server.Use(b.Build())
+	server.GET("/slow", func(ctx *gin.Context) {
+		now = now.Add(latency)
+		ctx.Status(http.StatusOK)
+	})
+	server.GET("/fast", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	get := func(path string) int {
+		resp := httptest.NewRecorder()
+		server.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp.Code
+	}
+
+	latency = time.Millisecond * 200
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, get("/slow"))
+	}
+	// p99 是缓存的，过一秒才会重新算
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/slow"))
+	// 别的路由不受影响
+	assert.Equal(t, http.StatusOK, get("/fast"))
+
+	// 慢的样本都出了窗口，又可以放行了
+	now = now.Add(time.Second * 11)
+	assert.Equal(t, http.StatusOK, get("/slow"))
+}
+
+func TestLatencyWindow(t *testing.T) {
+	now := time.UnixMilli(1000)
+	w := newLatencyWindow(100, time.Second*10)
+	for i := 1; i <= 200; i++ {
+		w.add(time.Millisecond*time.Duration(i), now)
+	}
+	// 只留了最新的 100 个，101ms ~ 200ms
+	assert.Equal(t, time.Millisecond*199, w.percentile99(now, 1))
+	assert.Equal(t, time.Duration(0), newLatencyWindow(100, time.Second).percentile99(now, 1))
+	// 样本不够
+	w = newLatencyWindow(100, time.Second*10)
+	w.add(time.Second, now)
+	assert.Equal(t, time.Duration(0), w.percentile99(now, 2))
+}
@@ -0,0 +1,73 @@
+package shedding
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow 一个路由最近的延迟，最多保留 size 个，超过 window 的不算
+// 被拒掉的请求不会进来，所以过了 window 之后 p99 会自然回落，不会一直拒下去
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []sample
+	next    int
+	window  time.Duration
+
+	// p99 排序比较贵，refresh 之内用上一次的结果
+	p99        time.Duration
+	computedAt time.Time
+	refresh    time.Duration
+}
+
+type sample struct {
+	latency time.Duration
+	at      time.Time
+}
+
+func newLatencyWindow(size int, window time.Duration) *latencyWindow {
+	return &latencyWindow{
+		samples: make([]sample, 0, size),
+		window:  window,
+		refresh: time.Second,
+	}
+}
+
+func (w *latencyWindow) add(latency time.Duration, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := sample{latency: latency, at: now}
+	if len(w.samples) < cap(w.samples) {
+		w.samples = append(w.samples, s)
+		return
+	}
+	w.samples[w.next] = s
+	w.next = (w.next + 1) % len(w.samples)
+}
+
+// percentile99 样本不够 minSamples 的时候返回 0，免得几个慢请求就把路由拒掉了
+func (w *latencyWindow) percentile99(now time.Time, minSamples int) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.computedAt.IsZero() && now.Sub(w.computedAt) < w.refresh {
+		return w.p99
+	}
+	w.computedAt = now
+	latencies := make([]time.Duration, 0, len(w.samples))
+	for _, s := range w.samples {
+		if now.Sub(s.at) <= w.window {
+			latencies = append(latencies, s.latency)
+		}
+	}
+	if len(latencies) == 0 || len(latencies) < minSamples {
+		w.p99 = 0
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	// 向上取整，100 个样本就是第 99 个
+	idx := (len(latencies)*99+99)/100 - 1
+	w.p99 = latencies[idx]
+	return w.p99
+}
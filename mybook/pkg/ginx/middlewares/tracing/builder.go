@@ -0,0 +1,91 @@
+// Package tracing 给每个请求创建一个 server span，上游带了 traceparent 的接到上游的链路上
+package tracing
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+const instrumentationName = "basic-go/mybook/pkg/ginx/middlewares/tracing"
+
+type Builder struct {
+	tp         trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// NewBuilder 默认用 otel 全局的 TracerProvider 和 propagator
+func NewBuilder() *Builder {
+	return &Builder{
+		tp:         otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+}
+
+func (b *Builder) TracerProvider(tp trace.TracerProvider) *Builder {
+	b.tp = tp
+	return b
+}
+
+func (b *Builder) Propagator(p propagation.TextMapPropagator) *Builder {
+	b.propagator = p
+	return b
+}
+
+// Build span 挂在 ctx.Request 的 context 上
+// handler 直接把 gin.Context 传下去的，要打开 gin.Engine 的 ContextWithFallback 才拿得到
+func (b *Builder) Build() gin.HandlerFunc {
+	tracer := b.tp.Tracer(instrumentationName)
+	return func(ctx *gin.Context) {
+		reqCtx := b.propagator.Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+		// 这个时候还没匹配路由，先用 method 占个位，结束的时候再改
+		reqCtx, span := tracer.Start(reqCtx, ctx.Request.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(ctx.Request.Method),
+				semconv.HTTPTarget(ctx.Request.URL.Path),
+				semconv.HTTPScheme(scheme(ctx.Request)),
+				semconv.NetHostName(ctx.Request.Host),
+				semconv.HTTPClientIP(ctx.ClientIP()),
+			))
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		if sc := span.SpanContext(); sc.IsValid() {
+			// 日志里带上 trace_id，方便从日志跳到链路
+			ginx.AddLogFields(ctx, logger.String("trace_id", sc.TraceID().String()))
+		}
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unknown"
+		} else {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetName(fmt.Sprintf("%s %s", ctx.Request.Method, route))
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(ctx.Errors) > 0 {
+			span.SetAttributes(attribute.String("gin.errors", ctx.Errors.String()))
+		}
+	}
+}
+
+func scheme(req *http.Request) string {
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name   string
+		path   string
+		header http.Header
+
+		wantName    string
+		wantStatus  codes.Code
+		wantTraceId string
+		wantRemote  bool
+	}{
+		{
+			name:     "新的链路",
+			path:     "/users/123",
+			wantName: "GET /users/:id",
+		},
+		{
+			name: "接上游的链路",
+			path: "/users/123",
+			header: http.Header{
+				"Traceparent": []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			},
+			wantName:    "GET /users/:id",
+			wantTraceId: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantRemote:  true,
+		},
+		{
+			name:       "服务端出错",
+			path:       "/error",
+			wantName:   "GET /error",
+			wantStatus: codes.Error,
+		},
+		{
+			name:     "没匹配上路由",
+			path:     "/not-found",
+			wantName: "GET unknown",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			server := gin.New()
+			server.ContextWithFallback = true
+			server.Use(NewBuilder().TracerProvider(tp).Propagator(propagation.TraceContext{}).Build())
+			var handlerSpan trace.SpanContext
+			server.GET("/users/:id", func(ctx *gin.Context) {
+				// handler 里用 gin.Context 也能拿到 span
+				handlerSpan = trace.SpanContextFromContext(ctx)
+				ctx.Status(http.StatusOK)
+			})
+			server.GET("/error", func(ctx *gin.Context) {
+				ctx.Status(http.StatusInternalServerError)
+			})
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			server.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, tc.wantName, span.Name())
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+			assert.Equal(t, tc.wantStatus, span.Status().Code)
+			assert.Equal(t, tc.wantRemote, span.Parent().IsRemote())
+			if tc.wantTraceId != "" {
+				assert.Equal(t, tc.wantTraceId, span.SpanContext().TraceID().String())
+			}
+			if handlerSpan.IsValid() {
+				assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+				assert.Contains(t, span.Attributes(), semconv.HTTPRoute("/users/:id"))
+			}
+		})
+	}
+}
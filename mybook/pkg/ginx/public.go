@@ -0,0 +1,123 @@
+package ginx
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Public 注册路由的时候放在最前面，表示这个路由不需要登录，例如
+//
+//	ug.POST("login", ginx.Public(), ginx.WrapBody(u.Login))
+//
+// 本身什么都不做，登录校验的 middleware 通过 IsPublic 判断
+func Public() gin.HandlerFunc {
+	return publicMarker
+}
+
+func publicMarker(*gin.Context) {}
+
+// gin 的 HandlerNames 也是这么拿名字的
+var publicMarkerName = runtime.FuncForPC(reflect.ValueOf(publicMarker).Pointer()).Name()
+
+// IsPublic 匹配到的路由上有没有 Public()，匹配不到路由的时候是 false
+// 每次都要翻一遍 handler 链，调用的地方可以按 RouteKey 缓存
+func IsPublic(ctx *gin.Context) bool {
+	if ctx.FullPath() == "" {
+		return false
+	}
+	for _, name := range ctx.HandlerNames() {
+		if name == publicMarkerName {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteKey method + 路由模板，例如 "GET /users/:id"，匹配不到路由的时候是空字符串
+func RouteKey(ctx *gin.Context) string {
+	fullPath := ctx.FullPath()
+	if fullPath == "" {
+		return ""
+	}
+	return ctx.Request.Method + " " + fullPath
+}
+
+// PathPattern 形如 "GET /users/*/profile" 或者 "/pub/**"，不带方法的匹配所有方法
+// 每一段用 path.Match 匹配，** 匹配剩下的任意多段（包括零段）
+type PathPattern struct {
+	method   string
+	segments []string
+}
+
+// ParsePathPattern 方法不认识的时候 panic，都是启动时候写死的配置
+func ParsePathPattern(pattern string) PathPattern {
+	p, err := TryParsePathPattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// TryParsePathPattern 运行时传进来的模式用这个，不对的时候返回 error
+func TryParsePathPattern(pattern string) (PathPattern, error) {
+	var p PathPattern
+	if method, rest, ok := strings.Cut(strings.TrimSpace(pattern), " "); ok {
+		p.method = strings.ToUpper(method)
+		if !validMethod(p.method) {
+			return PathPattern{}, fmt.Errorf("ginx: 不认识的 HTTP 方法 %s", method)
+		}
+		pattern = strings.TrimSpace(rest)
+	}
+	p.segments = strings.Split(strings.Trim(pattern, "/"), "/")
+	for _, seg := range p.segments {
+		if _, err := path.Match(seg, ""); err != nil {
+			return PathPattern{}, fmt.Errorf("ginx: 路径模式不对 %s", pattern)
+		}
+	}
+	return p, nil
+}
+
+func (p PathPattern) Match(method, urlPath string) bool {
+	if p.method != "" && p.method != method {
+		return false
+	}
+	return matchSegments(p.segments, strings.Split(strings.Trim(urlPath, "/"), "/"))
+}
+
+func matchSegments(pattern, segs []string) bool {
+	for i, pat := range pattern {
+		if pat == "**" {
+			// ** 放在最后是最常见的情况，直接匹配
+			if i == len(pattern)-1 {
+				return true
+			}
+			for j := i; j <= len(segs); j++ {
+				if matchSegments(pattern[i+1:], segs[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i >= len(segs) {
+			return false
+		}
+		if ok, _ := path.Match(pat, segs[i]); !ok {
+			return false
+		}
+	}
+	return len(pattern) == len(segs)
+}
+
+func validMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
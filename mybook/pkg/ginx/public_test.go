@@ -0,0 +1,81 @@
+package ginx
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathPattern_Match(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		method  string
+		path    string
+		want    bool
+	}{
+		{pattern: "/users/login", method: http.MethodPost, path: "/users/login", want: true},
+		{pattern: "/users/login", method: http.MethodPost, path: "/users/login/", want: true},
+		{pattern: "/users/login", method: http.MethodPost, path: "/users/login_sms", want: false},
+		{pattern: "GET /hello", method: http.MethodGet, path: "/hello", want: true},
+		{pattern: "get /hello", method: http.MethodGet, path: "/hello", want: true},
+		{pattern: "GET /hello", method: http.MethodPost, path: "/hello", want: false},
+		{pattern: "/users/*/profile", method: http.MethodGet, path: "/users/123/profile", want: true},
+		{pattern: "/users/*/profile", method: http.MethodGet, path: "/users/1/2/profile", want: false},
+		{pattern: "/users/login*", method: http.MethodPost, path: "/users/login_sms", want: true},
+		{pattern: "/pub/**", method: http.MethodGet, path: "/pub", want: true},
+		{pattern: "/pub/**", method: http.MethodGet, path: "/pub/a/b/c", want: true},
+		{pattern: "/pub/**", method: http.MethodGet, path: "/public", want: false},
+		{pattern: "/**/avatar", method: http.MethodGet, path: "/a/b/avatar", want: true},
+		{pattern: "/**/avatar", method: http.MethodGet, path: "/avatar", want: true},
+		{pattern: "/**/avatar", method: http.MethodGet, path: "/a/b/avatar/x", want: false},
+		{pattern: "/", method: http.MethodGet, path: "/", want: true},
+		{pattern: "/", method: http.MethodGet, path: "/hello", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.method+" "+tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.want, ParsePathPattern(tc.pattern).Match(tc.method, tc.path))
+		})
+	}
+}
+
+func TestParsePathPattern_Invalid(t *testing.T) {
+	assert.Panics(t, func() { ParsePathPattern("FETCH /hello") })
+	assert.Panics(t, func() { ParsePathPattern("/users/[") })
+	for _, pattern := range []string{"FOO /x", "[", "GET /users/[a"} {
+		_, err := TryParsePathPattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	server := gin.New()
+	var public bool
+	server.Use(func(ctx *gin.Context) {
+		public = IsPublic(ctx)
+	})
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	server.GET("/hello", Public(), ok)
+	server.POST("/hello", ok)
+	server.GET("/users/:id", Public(), ok)
+
+	testCases := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{method: http.MethodGet, path: "/hello", want: true},
+		// 同一个路径，不同的方法分开算
+		{method: http.MethodPost, path: "/hello", want: false},
+		{method: http.MethodGet, path: "/users/123", want: true},
+		{method: http.MethodGet, path: "/not_found", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			public = false
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.want, public)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package ginx
+
+// Result 所有接口统一的返回格式
+// Code 为 0 表示成功
+type Result struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data any    `json:"data"`
+}
@@ -0,0 +1,270 @@
+// Package validation 请求参数校验
+// 在 gin 自带的 validator 上注册自定义的校验规则和中英文的错误信息
+// 校验规则写在请求结构体的 binding tag 里，ShouldBind 的时候就会校验
+package validation
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	zhtranslations "github.com/go-playground/validator/v10/translations/zh"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	LangZh = "zh"
+	LangEn = "en"
+)
+
+var (
+	once     sync.Once
+	initErr  error
+	validate *validator.Validate
+	uni      *ut.UniversalTranslator
+	// L 校验规则的参数写错了的时候打日志，在 ioc 里换成真正的实现
+	L = logger.NewNopLogger()
+)
+
+// Init 可以重复调用，只会初始化一次
+func Init() error {
+	once.Do(func() {
+		initErr = setup()
+	})
+	return initErr
+}
+
+func setup() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin 的 validator 不是 go-playground/validator")
+	}
+	v.RegisterTagNameFunc(fieldName)
+	// 默认中文
+	uni = ut.New(zh.New(), zh.New(), en.New())
+	zhTrans, _ := uni.GetTranslator(LangZh)
+	enTrans, _ := uni.GetTranslator(LangEn)
+	if err := zhtranslations.RegisterDefaultTranslations(v, zhTrans); err != nil {
+		return err
+	}
+	if err := entranslations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		return err
+	}
+	validate = v
+	return registerBuiltin()
+}
+
+// fieldName 错误信息里用 json 或者 form 的字段名，前端认识的是这个
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// Register 注册自定义的校验规则，messages 的 key 是语言，value 是错误信息
+// 错误信息里 {0} 是字段名，{1} 是 tag 的参数
+func Register(tag string, fn validator.Func, messages map[string]string) error {
+	if err := Init(); err != nil {
+		return err
+	}
+	return register(tag, fn, messages)
+}
+
+func register(tag string, fn validator.Func, messages map[string]string) error {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	for lang, msg := range messages {
+		trans, ok := uni.GetTranslator(lang)
+		if !ok {
+			return errors.New("不支持的语言 " + lang)
+		}
+		if err := registerTranslation(tag, trans, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func registerTranslation(tag string, trans ut.Translator, msg string) error {
+	return validate.RegisterTranslation(tag, trans, func(trans ut.Translator) error {
+		return trans.Add(tag, msg, true)
+	}, func(trans ut.Translator, fe validator.FieldError) string {
+		t, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return t
+	})
+}
+
+// registerBuiltin 和业务无关的规则
+func registerBuiltin() error {
+	rules := []struct {
+		tag      string
+		fn       validator.Func
+		messages map[string]string
+	}{
+		{
+			// 真实存在的日期，2023-02-30 这种过不了
+			tag: "date",
+			fn: func(fl validator.FieldLevel) bool {
+				_, err := time.Parse(time.DateOnly, fl.Field().String())
+				return err == nil
+			},
+			messages: map[string]string{
+				LangZh: "{0}必须是一个有效的日期，格式为YYYY-MM-DD",
+				LangEn: "{0} must be a valid date in YYYY-MM-DD format",
+			},
+		},
+		{
+			// 按字符算长度，一个汉字算一个
+			tag: "runemin",
+			fn: func(fl validator.FieldLevel) bool {
+				n, ok := intParam(fl)
+				return ok && utf8.RuneCountInString(fl.Field().String()) >= n
+			},
+			messages: map[string]string{
+				LangZh: "{0}长度不能少于{1}个字",
+				LangEn: "{0} must be at least {1} characters",
+			},
+		},
+		{
+			tag: "runemax",
+			fn: func(fl validator.FieldLevel) bool {
+				n, ok := intParam(fl)
+				return ok && utf8.RuneCountInString(fl.Field().String()) <= n
+			},
+			messages: map[string]string{
+				LangZh: "{0}长度不能超过{1}个字",
+				LangEn: "{0} must be at most {1} characters",
+			},
+		},
+	}
+	for _, r := range rules {
+		if err := register(r.tag, r.fn, r.messages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// intParam 参数是在校验的时候才拿到的，这时候已经在处理请求了，不能 panic
+// 参数写错了就当成校验不通过，打日志提醒改代码
+func intParam(fl validator.FieldLevel) (int, bool) {
+	n, err := strconv.Atoi(fl.Param())
+	if err != nil || n < 0 {
+		L.Error("校验规则的参数必须是非负整数", logger.String("tag", fl.GetTag()),
+			logger.String("param", fl.Param()), logger.String("field", fl.StructFieldName()))
+		return 0, false
+	}
+	return n, true
+}
+
+// Translate 把校验失败的错误翻译成 Accept-Language 对应的语言，req 是校验的那个请求
+// 返回第一个错误和每个字段的错误，不是校验失败的错误 ok 返回 false
+func Translate(err error, req any, acceptLanguage string) (msg string, fields map[string]string, ok bool) {
+	var ves validator.ValidationErrors
+	if uni == nil || !errors.As(err, &ves) {
+		return "", nil, false
+	}
+	trans := translator(acceptLanguage)
+	fields = make(map[string]string, len(ves))
+	for i, fe := range ves {
+		m := translate(trans, req, fe)
+		if i == 0 {
+			msg = m
+		}
+		fields[fe.Field()] = m
+	}
+	return msg, fields, true
+}
+
+// crossFieldTags 参数是同一个结构体里另一个字段的 Go 字段名
+var crossFieldTags = map[string]bool{
+	"eqfield": true, "nefield": true,
+	"gtfield": true, "gtefield": true,
+	"ltfield": true, "ltefield": true,
+}
+
+// translate 跨字段的规则把参数换成 json 的字段名，不然会翻译成 confirmPassword必须等于Password
+func translate(trans ut.Translator, req any, fe validator.FieldError) string {
+	if !crossFieldTags[fe.Tag()] {
+		return fe.Translate(trans)
+	}
+	m, err := trans.T(fe.Tag(), fe.Field(), paramFieldName(req, fe))
+	if err != nil {
+		return fe.Translate(trans)
+	}
+	return m
+}
+
+// paramFieldName 顺着 StructNamespace 找到字段所在的结构体，找不到就用原来的参数
+func paramFieldName(req any, fe validator.FieldError) string {
+	segs := strings.Split(fe.StructNamespace(), ".")
+	if len(segs) < 2 {
+		return fe.Param()
+	}
+	// 第一段是类型名，最后一段是字段自己
+	t := reflect.TypeOf(req)
+	for _, seg := range segs[1 : len(segs)-1] {
+		name, _, _ := strings.Cut(seg, "[")
+		f, ok := structField(t, name)
+		if !ok {
+			return fe.Param()
+		}
+		t = f.Type
+	}
+	f, ok := structField(t, fe.Param())
+	if !ok {
+		return fe.Param()
+	}
+	if name := fieldName(f); name != "" {
+		return name
+	}
+	return fe.Param()
+}
+
+// structField 指针、切片、map 都往里找到结构体
+func structField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for t != nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			return t.FieldByName(name)
+		default:
+			return reflect.StructField{}, false
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// translator 按 Accept-Language 的顺序找支持的语言，例如 en-US,en;q=0.9,zh;q=0.8
+// 没有指定 q 的时候浏览器本身就是按优先级排的，这里不再按 q 排序
+func translator(acceptLanguage string) ut.Translator {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		lang = strings.ToLower(strings.SplitN(lang, "-", 2)[0])
+		if trans, ok := uni.GetTranslator(lang); ok {
+			return trans
+		}
+	}
+	return uni.GetFallback()
+}
@@ -0,0 +1,125 @@
+package validation
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	require.NoError(t, Init())
+	type Req struct {
+		NickName string `json:"nickName" binding:"omitempty,runemin=2,runemax=4"`
+		Birthday string `json:"birthday" binding:"omitempty,date"`
+	}
+	testCases := []struct {
+		name           string
+		req            Req
+		acceptLanguage string
+		wantFields     map[string]string
+	}{
+		{
+			name: "按字符算长度",
+			req:  Req{NickName: "大明明明", Birthday: "2000-02-29"},
+		},
+		{
+			name:       "昵称太长",
+			req:        Req{NickName: "大明明明明"},
+			wantFields: map[string]string{"nickName": "nickName长度不能超过4个字"},
+		},
+		{
+			name:       "日期不存在",
+			req:        Req{Birthday: "2023-02-30"},
+			wantFields: map[string]string{"birthday": "birthday必须是一个有效的日期，格式为YYYY-MM-DD"},
+		},
+		{
+			name:           "英文",
+			req:            Req{NickName: "大", Birthday: "2023-02-30"},
+			acceptLanguage: "en-US,en;q=0.9",
+			wantFields: map[string]string{
+				"nickName": "nickName must be at least 2 characters",
+				"birthday": "birthday must be a valid date in YYYY-MM-DD format",
+			},
+		},
+		{
+			name:           "不支持的语言用中文",
+			req:            Req{NickName: "大"},
+			acceptLanguage: "ja",
+			wantFields:     map[string]string{"nickName": "nickName长度不能少于2个字"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := binding.Validator.ValidateStruct(tc.req)
+			if tc.wantFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			_, fields, ok := Translate(err, tc.req, tc.acceptLanguage)
+			require.True(t, ok)
+			assert.Equal(t, tc.wantFields, fields)
+		})
+	}
+}
+
+func TestTranslate_CrossField(t *testing.T) {
+	require.NoError(t, Init())
+	type Password struct {
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword" binding:"eqfield=Password"`
+	}
+	type Req struct {
+		Password
+		Items []*Password `json:"items" binding:"dive"`
+	}
+	testCases := []struct {
+		name           string
+		req            any
+		acceptLanguage string
+		wantFields     map[string]string
+	}{
+		{
+			name:       "参数用 json 的字段名",
+			req:        &Password{Password: "a", ConfirmPassword: "b"},
+			wantFields: map[string]string{"confirmPassword": "confirmPassword必须等于password"},
+		},
+		{
+			name:           "英文",
+			req:            Password{Password: "a", ConfirmPassword: "b"},
+			acceptLanguage: "en",
+			wantFields:     map[string]string{"confirmPassword": "confirmPassword must be equal to password"},
+		},
+		{
+			name: "嵌套的结构体",
+			req: &Req{
+				Password: Password{Password: "a", ConfirmPassword: "a"},
+				Items:    []*Password{{Password: "a", ConfirmPassword: "b"}},
+			},
+			wantFields: map[string]string{"confirmPassword": "confirmPassword必须等于password"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := binding.Validator.ValidateStruct(tc.req)
+			_, fields, ok := Translate(err, tc.req, tc.acceptLanguage)
+			require.True(t, ok)
+			assert.Equal(t, tc.wantFields, fields)
+		})
+	}
+}
+
+// TestBadParam 规则的参数写错了，校验不通过，不能 panic
+func TestBadParam(t *testing.T) {
+	require.NoError(t, Init())
+	type Req struct {
+		NickName string `json:"nickName" binding:"runemax=abc"`
+	}
+	var err error
+	assert.NotPanics(t, func() {
+		err = binding.Validator.ValidateStruct(Req{NickName: "大明"})
+	})
+	_, fields, ok := Translate(err, Req{}, "")
+	require.True(t, ok)
+	assert.Contains(t, fields, "nickName")
+}
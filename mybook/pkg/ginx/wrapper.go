@@ -0,0 +1,130 @@
+package ginx
+
+import (
+	"basic-go/mybook/pkg/ginx/validation"
+	"basic-go/mybook/pkg/logger"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ClaimsKey 登录校验的 middleware 把 claims 放在 ctx 的这个 key 下面
+const ClaimsKey = "claims"
+
+var (
+	// BindErrResult 请求解析或者校验失败的时候返回，HTTP 状态码是 400
+	// 校验失败的时候 Msg 换成第一个字段的错误，Data 是每个字段的错误
+	BindErrResult = Result{Code: 4, Msg: "参数错误"}
+	// UnauthorizedResult 拿不到 claims 的时候返回，HTTP 状态码是 401
+	UnauthorizedResult = Result{Code: 4, Msg: "未登录"}
+	// ForbiddenResult 登录了但是没有权限的时候返回，HTTP 状态码是 403
+	ForbiddenResult = Result{Code: 4, Msg: "没有权限"}
+	// TooManyRequestsResult 被限流的时候返回，HTTP 状态码是 429
+	TooManyRequestsResult = Result{Code: 4, Msg: "请求太频繁，请稍后重试"}
+	// SystemErrResult 处理请求的时候 panic 了返回，HTTP 状态码是 500
+	SystemErrResult = Result{Code: 5, Msg: "系统错误"}
+	// ServiceUnavailableResult 过载保护拒掉请求的时候返回，HTTP 状态码是 503
+	ServiceUnavailableResult = Result{Code: 5, Msg: "系统繁忙，请稍后重试"}
+	// L Wrap 系列打日志用的，在 ioc 里换成真正的实现
+	L = logger.NewNopLogger()
+)
+
+// Wrap 业务函数返回 error 的时候只打日志，返回给前端的永远是 Result
+func Wrap(fn func(ctx *gin.Context) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		res, err := fn(ctx)
+		write(ctx, res, err)
+	}
+}
+
+// WrapBody 先把请求解析到 Req 里面，解析失败直接返回 400
+func WrapBody[Req any](fn func(ctx *gin.Context, req Req) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req Req
+		if !bind(ctx, &req) {
+			return
+		}
+		res, err := fn(ctx, req)
+		write(ctx, res, err)
+	}
+}
+
+// WrapClaims 从 ctx 里面拿登录信息，C 一般是 claims 的指针
+func WrapClaims[C any](fn func(ctx *gin.Context, uc C) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		uc, ok := claims[C](ctx)
+		if !ok {
+			return
+		}
+		res, err := fn(ctx, uc)
+		write(ctx, res, err)
+	}
+}
+
+func WrapBodyAndClaims[Req any, C any](fn func(ctx *gin.Context, req Req, uc C) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req Req
+		if !bind(ctx, &req) {
+			return
+		}
+		uc, ok := claims[C](ctx)
+		if !ok {
+			return
+		}
+		res, err := fn(ctx, req, uc)
+		write(ctx, res, err)
+	}
+}
+
+func bind(ctx *gin.Context, req any) bool {
+	// ShouldBind 会根据 Content-Type 来解析，GET 请求解析的是 query
+	// 解析完了会按 binding tag 校验
+	if err := ctx.ShouldBind(req); err != nil {
+		res := BindErrResult
+		if msg, fields, ok := validation.Translate(err, req, ctx.GetHeader("Accept-Language")); ok {
+			res.Msg = msg
+			res.Data = fields
+		} else {
+			L.WithContext(ctx).Warn("解析请求失败", logger.String("path", ctx.Request.URL.Path), logger.Error(err))
+		}
+		ctx.JSON(http.StatusBadRequest, res)
+		return false
+	}
+	return true
+}
+
+func claims[C any](ctx *gin.Context) (C, bool) {
+	var zero C
+	val, ok := ctx.Get(ClaimsKey)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, UnauthorizedResult)
+		return zero, false
+	}
+	uc, ok := val.(C)
+	if !ok {
+		// 一般是 middleware 和 handler 用的 claims 类型对不上
+		L.WithContext(ctx).Error("claims 类型不对", logger.String("type", fmt.Sprintf("%T", val)))
+		ctx.JSON(http.StatusUnauthorized, UnauthorizedResult)
+		return zero, false
+	}
+	return uc, true
+}
+
+// write 注册过错误码的是业务错误，例如验证码不对，打 Warn；映射成 SystemErrResult 的才打 Error
+func write(ctx *gin.Context, res Result, err error) {
+	if err != nil {
+		fields := []logger.Field{logger.String("path", ctx.Request.URL.Path), logger.Error(err)}
+		if res.Code == SystemErrResult.Code {
+			L.WithContext(ctx).Error("处理请求失败", fields...)
+		} else {
+			L.WithContext(ctx).Warn("处理请求失败", fields...)
+		}
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// AddLogFields 之后 logger.WithContext 打的日志都会带上这些字段
+// 只放在 Request 的 context 上，gin.Context 要打开 ContextWithFallback 才能找过去
+func AddLogFields(ctx *gin.Context, fields ...logger.Field) {
+	ctx.Request = ctx.Request.WithContext(logger.ContextWithFields(ctx.Request.Context(), fields...))
+}
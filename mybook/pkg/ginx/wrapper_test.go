@@ -0,0 +1,130 @@
+package ginx
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testReq struct {
+	Name string `json:"name"`
+}
+
+type testClaims struct {
+	Uid int64
+}
+
+func TestWrapBodyAndClaims(t *testing.T) {
+	testCases := []struct {
+		name     string
+		claims   any
+		reqBody  string
+		fn       func(ctx *gin.Context, req testReq, uc *testClaims) (Result, error)
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:    "成功",
+			claims:  &testClaims{Uid: 1},
+			reqBody: `{"name":"大明"}`,
+			fn: func(ctx *gin.Context, req testReq, uc *testClaims) (Result, error) {
+				return Result{Data: gin.H{"name": req.Name, "uid": uc.Uid}}, nil
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"","data":{"name":"大明","uid":1}}`,
+		},
+		{
+			name:     "bind 失败",
+			claims:   &testClaims{Uid: 1},
+			reqBody:  `{"name":`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"code":4,"msg":"参数错误","data":null}`,
+		},
+		{
+			name:     "没有 claims",
+			reqBody:  `{"name":"大明"}`,
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"code":4,"msg":"未登录","data":null}`,
+		},
+		{
+			name:     "claims 类型不对",
+			claims:   testClaims{Uid: 1},
+			reqBody:  `{"name":"大明"}`,
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"code":4,"msg":"未登录","data":null}`,
+		},
+		{
+			name:    "业务出错，返回业务给的 Result",
+			claims:  &testClaims{Uid: 1},
+			reqBody: `{"name":"大明"}`,
+			fn: func(ctx *gin.Context, req testReq, uc *testClaims) (Result, error) {
+				return Result{Code: 5, Msg: "系统错误"}, errors.New("db 错误")
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"code":5,"msg":"系统错误","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.claims != nil {
+					ctx.Set(ClaimsKey, tc.claims)
+				}
+			})
+			server.POST("/test", WrapBodyAndClaims(tc.fn))
+			req, err := http.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestWrap_LogLevel(t *testing.T) {
+	testCases := []struct {
+		name      string
+		res       Result
+		wantLevel zapcore.Level
+	}{
+		{
+			name:      "业务错误",
+			res:       Result{Code: 101001, Msg: "验证码不对"},
+			wantLevel: zapcore.WarnLevel,
+		},
+		{
+			name:      "系统错误",
+			res:       SystemErrResult,
+			wantLevel: zapcore.ErrorLevel,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			old := L
+			L = logger.NewZapLogger(zap.New(core))
+			defer func() { L = old }()
+			server := gin.New()
+			server.GET("/test", Wrap(func(ctx *gin.Context) (Result, error) {
+				return tc.res, errors.New("出错了")
+			}))
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+			entries := logs.All()
+			require.Len(t, entries, 1)
+			assert.Equal(t, tc.wantLevel, entries[0].Level)
+		})
+	}
+}
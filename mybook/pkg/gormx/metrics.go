@@ -0,0 +1,92 @@
+// Package gormx GORM 的插件
+package gormx
+
+import (
+	"basic-go/mybook/pkg/metricsx"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+	"time"
+)
+
+const startTimeKey = "metrics:start_time"
+
+// MetricsPlugin 用 GORM 的 callback 统计每个表、每种操作的耗时
+type MetricsPlugin struct {
+	vector *prometheus.HistogramVec
+}
+
+func NewMetricsPlugin(namespace, subsystem string) *MetricsPlugin {
+	return &MetricsPlugin{
+		vector: metricsx.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "db_query_duration_seconds",
+			Help:      "GORM 查询的耗时",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+		}, []string{"table", "type"})),
+	}
+}
+
+func (p *MetricsPlugin) Name() string {
+	return "prometheus_metrics"
+}
+
+func (p *MetricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("*").Register("metrics:before_create", p.before); err != nil {
+		return err
+	}
+	if err := cb.Create().After("*").Register("metrics:after_create", p.after("create")); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("*").Register("metrics:before_query", p.before); err != nil {
+		return err
+	}
+	if err := cb.Query().After("*").Register("metrics:after_query", p.after("query")); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("*").Register("metrics:before_update", p.before); err != nil {
+		return err
+	}
+	if err := cb.Update().After("*").Register("metrics:after_update", p.after("update")); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("*").Register("metrics:before_delete", p.before); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("*").Register("metrics:after_delete", p.after("delete")); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("*").Register("metrics:before_row", p.before); err != nil {
+		return err
+	}
+	if err := cb.Row().After("*").Register("metrics:after_row", p.after("row")); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("*").Register("metrics:before_raw", p.before); err != nil {
+		return err
+	}
+	return cb.Raw().After("*").Register("metrics:after_raw", p.after("raw"))
+}
+
+func (p *MetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func (p *MetricsPlugin) after(typ string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		val, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := val.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		p.vector.WithLabelValues(table, typ).Observe(time.Since(start).Seconds())
+	}
+}
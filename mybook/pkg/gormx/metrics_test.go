@@ -0,0 +1,52 @@
+package gormx
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type User struct {
+	Id   int64
+	Name string
+}
+
+func TestMetricsPlugin(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT .* FROM `users`.*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+	mock.ExpectExec("INSERT INTO `users` .*").WillReturnResult(sqlmock.NewResult(2, 1))
+
+	db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+		Conn:                      mockDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	p := NewMetricsPlugin("test", "gorm")
+	require.NoError(t, db.Use(p))
+
+	var u User
+	require.NoError(t, db.First(&u, 1).Error)
+	require.NoError(t, db.Create(&User{Name: "Jerry"}).Error)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(p.vector))
+	assert.Equal(t, uint64(1), sampleCount(t, p.vector, "users", "query"))
+	assert.Equal(t, uint64(1), sampleCount(t, p.vector, "users", "create"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func sampleCount(t *testing.T, vector *prometheus.HistogramVec, lvs ...string) uint64 {
+	var m dto.Metric
+	require.NoError(t, vector.WithLabelValues(lvs...).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
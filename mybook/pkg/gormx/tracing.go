@@ -0,0 +1,104 @@
+package gormx
+
+import (
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	instrumentationName = "basic-go/mybook/pkg/gormx"
+	spanKey             = "tracing:span"
+)
+
+// TracingPlugin 每条 SQL 一个 span，SQL 里只有占位符，不会把参数记下来
+type TracingPlugin struct {
+	tracer trace.Tracer
+}
+
+func NewTracingPlugin(tp trace.TracerProvider) *TracingPlugin {
+	return &TracingPlugin{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (p *TracingPlugin) Name() string {
+	return "opentelemetry_tracing"
+}
+
+func (p *TracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("*").Register("tracing:before_create", p.before("create")); err != nil {
+		return err
+	}
+	if err := cb.Create().After("*").Register("tracing:after_create", p.after("create")); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("*").Register("tracing:before_query", p.before("query")); err != nil {
+		return err
+	}
+	if err := cb.Query().After("*").Register("tracing:after_query", p.after("query")); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("*").Register("tracing:before_update", p.before("update")); err != nil {
+		return err
+	}
+	if err := cb.Update().After("*").Register("tracing:after_update", p.after("update")); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("*").Register("tracing:before_delete", p.before("delete")); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("*").Register("tracing:after_delete", p.after("delete")); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("*").Register("tracing:before_row", p.before("row")); err != nil {
+		return err
+	}
+	if err := cb.Row().After("*").Register("tracing:after_row", p.after("row")); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("*").Register("tracing:before_raw", p.before("raw")); err != nil {
+		return err
+	}
+	return cb.Raw().After("*").Register("tracing:after_raw", p.after("raw"))
+}
+
+func (p *TracingPlugin) before(typ string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		// 表名要等 callback 解析完 model 才有，这里先用操作类型
+		ctx, span := p.tracer.Start(db.Statement.Context, "gorm:"+typ,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperation(typ)))
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func (p *TracingPlugin) after(typ string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		val, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span, ok := val.(trace.Span)
+		if !ok {
+			return
+		}
+		defer span.End()
+		if table := db.Statement.Table; table != "" {
+			span.SetName("gorm:" + typ + " " + table)
+			span.SetAttributes(semconv.DBSQLTable(table))
+		}
+		span.SetAttributes(
+			semconv.DBStatement(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		// 没找到数据是业务上的结果，不算出错
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestTracingPlugin(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(mock sqlmock.Sqlmock)
+		exec func(db *gorm.DB) error
+
+		wantName   string
+		wantStatus codes.Code
+	}{
+		{
+			name: "查询成功",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT .* FROM `users`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+			},
+			exec: func(db *gorm.DB) error {
+				var u User
+				return db.First(&u, 1).Error
+			},
+			wantName: "gorm:query users",
+		},
+		{
+			name: "没有数据不算出错",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT .* FROM `users`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+			},
+			exec: func(db *gorm.DB) error {
+				var u User
+				err := db.First(&u, 1).Error
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			},
+			wantName: "gorm:query users",
+		},
+		{
+			name: "数据库出错",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO `users` .*").WillReturnError(errors.New("mock db error"))
+			},
+			exec: func(db *gorm.DB) error {
+				err := db.Create(&User{Name: "Jerry"}).Error
+				assert.Error(t, err)
+				return nil
+			},
+			wantName:   "gorm:create users",
+			wantStatus: codes.Error,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			tc.mock(mock)
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      mockDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			require.NoError(t, db.Use(NewTracingPlugin(tp)))
+
+			ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+			require.NoError(t, tc.exec(db.WithContext(ctx)))
+			parent.End()
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 2)
+			span := spans[0]
+			assert.Equal(t, tc.wantName, span.Name())
+			assert.Equal(t, tc.wantStatus, span.Status().Code)
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+			assert.Contains(t, span.Attributes(), semconv.DBSQLTable("users"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
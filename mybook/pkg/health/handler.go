@@ -0,0 +1,125 @@
+// Package health 给 k8s 的存活检查和就绪检查
+package health
+
+import (
+	"basic-go/mybook/pkg/ginx"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusDegraded 只有非关键的依赖挂了，还能接流量
+	StatusDegraded     = "degraded"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Checker 检查一个依赖，超时由 Handler 控制
+type Checker func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   Checker
+	// optional 挂了只在结果里报出来，不影响就绪
+	optional bool
+}
+
+type Result struct {
+	Status string `json:"status"`
+	// 每个依赖的检查结果，具体的错误只打日志，不返回出去
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Handler /healthz 只要进程还能处理请求就是好的，依赖挂了重启也没用
+// /readyz 检查依赖，失败了 k8s 不往这个 pod 转发请求
+type Handler struct {
+	checks       []check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+	l            logger.Logger
+}
+
+// NewHandler timeout 是每个依赖检查的超时时间
+func NewHandler(timeout time.Duration, l logger.Logger) *Handler {
+	return &Handler{timeout: timeout, l: l}
+}
+
+func (h *Handler) Add(name string, fn Checker) *Handler {
+	h.checks = append(h.checks, check{name: name, fn: fn})
+	return h
+}
+
+// AddOptional 有降级手段的依赖用这个，挂了 /readyz 还是 200，状态是 degraded
+// 不然这个依赖一挂，所有 pod 都被摘掉，降级就没有意义了
+func (h *Handler) AddOptional(name string, fn Checker) *Handler {
+	h.checks = append(h.checks, check{name: name, fn: fn, optional: true})
+	return h
+}
+
+func (h *Handler) RegisterRoutes(server gin.IRoutes) {
+	server.GET("/healthz", ginx.Public(), h.Liveness)
+	server.GET("/readyz", ginx.Public(), h.Readiness)
+}
+
+// ShutDown 开始退出之后 /readyz 一直返回 503，k8s 把这个 pod 摘掉之后再停 server
+func (h *Handler) ShutDown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *Handler) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, Result{Status: StatusOK})
+}
+
+func (h *Handler) Readiness(ctx *gin.Context) {
+	if h.shuttingDown.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, Result{Status: StatusShuttingDown})
+		return
+	}
+	res := h.Check(ctx.Request.Context())
+	code := http.StatusOK
+	if res.Status == StatusFail {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, res)
+}
+
+// Check 并发检查所有依赖，一个慢的不会拖慢别的
+func (h *Handler) Check(ctx context.Context) Result {
+	res := Result{Status: StatusOK, Checks: make(map[string]string, len(h.checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range h.checks {
+		c := c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			err := c.fn(cctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				h.l.WithContext(ctx).Warn("就绪检查失败", logger.String("check", c.name), logger.Error(err))
+				res.Checks[c.name] = StatusFail
+				switch {
+				case !c.optional:
+					res.Status = StatusFail
+				case res.Status == StatusOK:
+					res.Status = StatusDegraded
+				}
+				return
+			}
+			res.Checks[c.name] = StatusOK
+		}()
+	}
+	wg.Wait()
+	return res
+}
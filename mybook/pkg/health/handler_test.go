@@ -0,0 +1,101 @@
+package health
+
+import (
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	testCases := []struct {
+		name     string
+		handler  func() *Handler
+		path     string
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "存活检查不看依赖",
+			handler: func() *Handler {
+				return NewHandler(time.Second, logger.NewNopLogger()).
+					Add("mysql", func(ctx context.Context) error { return errors.New("mock db error") })
+			},
+			path:     "/healthz",
+			wantCode: http.StatusOK,
+			wantRes:  Result{Status: StatusOK},
+		},
+		{
+			name: "依赖都正常",
+			handler: func() *Handler {
+				return NewHandler(time.Second, logger.NewNopLogger()).Add("mysql", ok).Add("redis", ok)
+			},
+			path:     "/readyz",
+			wantCode: http.StatusOK,
+			wantRes:  Result{Status: StatusOK, Checks: map[string]string{"mysql": StatusOK, "redis": StatusOK}},
+		},
+		{
+			name: "依赖超时",
+			handler: func() *Handler {
+				return NewHandler(time.Millisecond*10, logger.NewNopLogger()).Add("mysql", ok).Add("redis", slow)
+			},
+			path:     "/readyz",
+			wantCode: http.StatusServiceUnavailable,
+			wantRes:  Result{Status: StatusFail, Checks: map[string]string{"mysql": StatusOK, "redis": StatusFail}},
+		},
+		{
+			name: "非关键依赖挂了还是就绪",
+			handler: func() *Handler {
+				return NewHandler(time.Millisecond*10, logger.NewNopLogger()).Add("mysql", ok).AddOptional("redis", slow)
+			},
+			path:     "/readyz",
+			wantCode: http.StatusOK,
+			wantRes:  Result{Status: StatusDegraded, Checks: map[string]string{"mysql": StatusOK, "redis": StatusFail}},
+		},
+		{
+			name: "关键依赖挂了，非关键的也挂了",
+			handler: func() *Handler {
+				return NewHandler(time.Millisecond*10, logger.NewNopLogger()).AddOptional("redis", slow).Add("mysql", slow)
+			},
+			path:     "/readyz",
+			wantCode: http.StatusServiceUnavailable,
+			wantRes:  Result{Status: StatusFail, Checks: map[string]string{"mysql": StatusFail, "redis": StatusFail}},
+		},
+		{
+			name: "正在退出",
+			handler: func() *Handler {
+				h := NewHandler(time.Second, logger.NewNopLogger()).Add("mysql", ok)
+				h.ShutDown()
+				return h
+			},
+			path:     "/readyz",
+			wantCode: http.StatusServiceUnavailable,
+			wantRes:  Result{Status: StatusShuttingDown},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			tc.handler().RegisterRoutes(server)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			var res Result
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package limiter
+
+import (
+	"basic-go/mybook/pkg/breaker"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"time"
+)
+
+const (
+	// FailOpen Redis 出问题的时候直接放行
+	FailOpen = "open"
+	// FailClosed Redis 出问题的时候全部限流
+	FailClosed = "closed"
+	// FailLocal Redis 出问题的时候换成单机限流
+	FailLocal = "local"
+)
+
+// DegradeLimiter 包一层 Redis 的限流器，出错或者熔断的时候按 policy 降级
+// 熔断期间不会再去调 Redis，冷却之后放一个请求试探，成功了就切回来
+type DegradeLimiter struct {
+	primary Limiter
+	// FailLocal 的时候用
+	fallback Limiter
+	policy   string
+	breaker  *breaker.Breaker
+	l        logger.Logger
+}
+
+func NewFailOpenLimiter(primary Limiter, br *breaker.Breaker, l logger.Logger) Limiter {
+	return &DegradeLimiter{primary: primary, policy: FailOpen, breaker: br, l: l}
+}
+
+func NewFailClosedLimiter(primary Limiter, br *breaker.Breaker, l logger.Logger) Limiter {
+	return &DegradeLimiter{primary: primary, policy: FailClosed, breaker: br, l: l}
+}
+
+// NewFallbackLimiter fallback 一般是 Local 的，额度要按实例数量缩小
+func NewFallbackLimiter(primary, fallback Limiter, br *breaker.Breaker, l logger.Logger) Limiter {
+	return &DegradeLimiter{primary: primary, fallback: fallback, policy: FailLocal, breaker: br, l: l}
+}
+
+func (d *DegradeLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	if !d.breaker.Allow() {
+		return d.degrade(ctx, key)
+	}
+	res, err := d.primary.Limit(ctx, key)
+	// 拿到了结果就说明 Redis 没问题，漏桶排队的时候请求被取消了也是这样
+	if err == nil || res.Limit > 0 {
+		d.breaker.Success()
+		return res, err
+	}
+	d.breaker.Failure()
+	d.l.WithContext(ctx).Error("限流器出错，降级", logger.String("policy", d.policy), logger.Error(err))
+	return d.degrade(ctx, key)
+}
+
+func (d *DegradeLimiter) degrade(ctx context.Context, key string) (Result, error) {
+	switch d.policy {
+	case FailLocal:
+		return d.fallback.Limit(ctx, key)
+	case FailClosed:
+		return Result{Limited: true, RetryAfter: time.Second}, nil
+	default:
+		return Result{}, nil
+	}
+}
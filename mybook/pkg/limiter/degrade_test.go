@@ -0,0 +1,93 @@
+package limiter
+
+import (
+	"basic-go/mybook/pkg/breaker"
+	"basic-go/mybook/pkg/logger"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type limitFunc func(ctx context.Context, key string) (Result, error)
+
+func (f limitFunc) Limit(ctx context.Context, key string) (Result, error) {
+	return f(ctx, key)
+}
+
+func TestDegradeLimiter(t *testing.T) {
+	redisErr := limitFunc(func(ctx context.Context, key string) (Result, error) {
+		return Result{}, errors.New("mock redis error")
+	})
+	local := limitFunc(func(ctx context.Context, key string) (Result, error) {
+		return Result{Limited: true, Limit: 1, RetryAfter: time.Second}, nil
+	})
+	testCases := []struct {
+		name string
+		l    Limiter
+
+		wantRes Result
+		wantErr error
+	}{
+		{
+			name: "Redis 正常",
+			l: NewFailClosedLimiter(limitFunc(func(ctx context.Context, key string) (Result, error) {
+				return Result{Limit: 100, Remaining: 99}, nil
+			}), breaker.NewBreaker(3, time.Second), logger.NewNopLogger()),
+			wantRes: Result{Limit: 100, Remaining: 99},
+		},
+		{
+			name:    "出错直接放行",
+			l:       NewFailOpenLimiter(redisErr, breaker.NewBreaker(3, time.Second), logger.NewNopLogger()),
+			wantRes: Result{},
+		},
+		{
+			name:    "出错全部限流",
+			l:       NewFailClosedLimiter(redisErr, breaker.NewBreaker(3, time.Second), logger.NewNopLogger()),
+			wantRes: Result{Limited: true, RetryAfter: time.Second},
+		},
+		{
+			name:    "出错换成单机限流",
+			l:       NewFallbackLimiter(redisErr, local, breaker.NewBreaker(3, time.Second), logger.NewNopLogger()),
+			wantRes: Result{Limited: true, Limit: 1, RetryAfter: time.Second},
+		},
+		{
+			name: "漏桶排队的时候请求取消了，不算 Redis 出错",
+			l: NewFailOpenLimiter(limitFunc(func(ctx context.Context, key string) (Result, error) {
+				return Result{Limit: 6, Remaining: 4}, context.Canceled
+			}), breaker.NewBreaker(1, time.Second), logger.NewNopLogger()),
+			wantRes: Result{Limit: 6, Remaining: 4},
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.l.Limit(context.Background(), "key")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestDegradeLimiter_Breaker(t *testing.T) {
+	var calls int
+	primary := limitFunc(func(ctx context.Context, key string) (Result, error) {
+		calls++
+		return Result{}, errors.New("mock redis error")
+	})
+	br := breaker.NewBreaker(2, time.Hour)
+	l := NewFallbackLimiter(primary, NewLocalFixedWindowLimiter(time.Hour, 3), br, logger.NewNopLogger())
+	var passed int
+	for i := 0; i < 5; i++ {
+		res, err := l.Limit(context.Background(), "key")
+		assert.NoError(t, err)
+		if !res.Limited {
+			passed++
+		}
+	}
+	// 熔断之后就不再调 Redis 了
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, breaker.StateOpen, br.State())
+	assert.Equal(t, 3, passed)
+}
@@ -0,0 +1,27 @@
+-- 限流对象
+local key = KEYS[1]
+-- 窗口大小，毫秒
+local window = tonumber(ARGV[1])
+-- 阈值
+local threshold = tonumber(ARGV[2])
+
+local cnt = tonumber(redis.call('GET', key) or '0')
+local limited = 1
+if cnt < threshold then
+    limited = 0
+    cnt = redis.call('INCR', key)
+    if cnt == 1 then
+        -- 窗口里的第一个请求，窗口从现在开始
+        redis.call('PEXPIRE', key, window)
+    end
+end
+local ttl = redis.call('PTTL', key)
+if ttl < 0 then
+    ttl = window
+end
+-- 新窗口开始之前都不会有额度
+local retry = 0
+if limited == 1 then
+    retry = ttl
+end
+return {limited, threshold - cnt, retry, ttl}
@@ -0,0 +1,23 @@
+-- 限流对象
+local key = KEYS[1]
+-- 最多允许多少个请求在前面排队
+local capacity = tonumber(ARGV[1])
+-- 两个请求之间隔多少毫秒，可以是小数
+local gap = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+-- 下一个请求可以漏出去的时间
+local next = tonumber(redis.call('GET', key) or '0')
+if next < now then
+    next = now
+end
+local wait = next - now
+-- 前面排着的请求太多了，等到队列空出一个位置再来
+if wait / gap > capacity + 1e-9 then
+    return {1, 0, math.ceil(wait - capacity * gap), math.ceil(wait), 0}
+end
+redis.call('SET', key, next + gap, 'PX', math.ceil(wait + gap))
+-- 这个请求排上之后，后面还能再排几个
+local remaining = math.floor(capacity - wait / gap + 1e-9)
+-- 最后一个是这个请求要等多少毫秒
+return {0, remaining, 0, math.ceil(wait + gap), math.ceil(wait)}
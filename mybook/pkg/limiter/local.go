@@ -0,0 +1,52 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// keyedState Local 限流器共用的，按 key 存状态
+// 没有后台 goroutine，每隔 idle 在请求里顺便把 idle 这么久没用过的 key 清掉
+type keyedState[T any] struct {
+	mu        sync.Mutex
+	states    map[string]*entry[T]
+	idle      time.Duration
+	lastSweep time.Time
+}
+
+type entry[T any] struct {
+	state    T
+	lastSeen time.Time
+}
+
+func newKeyedState[T any](idle time.Duration) *keyedState[T] {
+	return &keyedState[T]{
+		states: make(map[string]*entry[T]),
+		idle:   idle,
+	}
+}
+
+// do 在锁里面执行 fn，拿到的 state 可以直接改
+func (k *keyedState[T]) do(key string, now time.Time, fn func(state *T) Result) Result {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now.Sub(k.lastSweep) >= k.idle {
+		k.sweep(now)
+	}
+	e, ok := k.states[key]
+	if !ok {
+		e = &entry[T]{}
+		k.states[key] = e
+	}
+	e.lastSeen = now
+	return fn(&e.state)
+}
+
+func (k *keyedState[T]) sweep(now time.Time) {
+	for key, e := range k.states {
+		if now.Sub(e.lastSeen) >= k.idle {
+			delete(k.states, key)
+		}
+	}
+	k.lastSweep = now
+}
@@ -0,0 +1,46 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// LocalFixedWindowLimiter 单机的固定窗口
+type LocalFixedWindowLimiter struct {
+	interval time.Duration
+	rate     int
+	states   *keyedState[fixedWindow]
+	now      func() time.Time
+}
+
+type fixedWindow struct {
+	start time.Time
+	cnt   int
+}
+
+func NewLocalFixedWindowLimiter(interval time.Duration, rate int) Limiter {
+	return &LocalFixedWindowLimiter{
+		interval: interval,
+		rate:     rate,
+		states:   newKeyedState[fixedWindow](interval),
+		now:      time.Now,
+	}
+}
+
+func (l *LocalFixedWindowLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	now := l.now()
+	return l.states.do(key, now, func(w *fixedWindow) Result {
+		if now.Sub(w.start) >= l.interval {
+			w.start, w.cnt = now, 0
+		}
+		res := Result{Limit: l.rate, Reset: w.start.Add(l.interval).Sub(now)}
+		if w.cnt >= l.rate {
+			res.Limited = true
+			res.RetryAfter = res.Reset
+			return res
+		}
+		w.cnt++
+		res.Remaining = l.rate - w.cnt
+		return res
+	}), nil
+}
@@ -0,0 +1,63 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// LocalLeakyBucketLimiter 单机的漏桶，和 RedisLeakyBucketLimiter 一样，排上的请求在 Limit 里面等
+type LocalLeakyBucketLimiter struct {
+	interval time.Duration
+	rate     int
+	capacity int
+	states   *keyedState[leakyBucket]
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+type leakyBucket struct {
+	// 下一个请求可以漏出去的时间
+	next time.Time
+}
+
+func NewLocalLeakyBucketLimiter(interval time.Duration, rate, capacity int) Limiter {
+	return &LocalLeakyBucketLimiter{
+		interval: interval,
+		rate:     rate,
+		capacity: capacity,
+		// 排满的队列要这么久才能漏完
+		states: newKeyedState[leakyBucket](fillTime(capacity+1, rate, interval)),
+		now:    time.Now,
+		sleep:  sleep,
+	}
+}
+
+func (l *LocalLeakyBucketLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	now := l.now()
+	gap := l.interval / time.Duration(l.rate)
+	var wait time.Duration
+	// 正在漏的那个加上排队的
+	res := l.states.do(key, now, func(b *leakyBucket) Result {
+		next := b.next
+		if next.Before(now) {
+			next = now
+		}
+		wait = next.Sub(now)
+		queue := gap * time.Duration(l.capacity)
+		// 前面排着的请求太多了，等到队列空出一个位置再来
+		if wait > queue {
+			return Result{Limited: true, Limit: l.capacity + 1, RetryAfter: wait - queue, Reset: wait}
+		}
+		b.next = next.Add(gap)
+		return Result{
+			Limit: l.capacity + 1,
+			// 这个请求排上之后，后面还能再排几个
+			Remaining: int((queue - wait) / gap),
+			Reset:     wait + gap,
+		}
+	})
+	if res.Limited {
+		return res, nil
+	}
+	return res, l.sleep(ctx, wait)
+}
@@ -0,0 +1,55 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// LocalSlidingWindowLimiter 单机的滑动窗口，每个 key 最多记 rate 个时间
+type LocalSlidingWindowLimiter struct {
+	interval time.Duration
+	rate     int
+	states   *keyedState[slidingWindow]
+	now      func() time.Time
+}
+
+type slidingWindow struct {
+	// 按时间排好序的，窗口里放行的请求
+	times []time.Time
+}
+
+func NewLocalSlidingWindowLimiter(interval time.Duration, rate int) Limiter {
+	return &LocalSlidingWindowLimiter{
+		interval: interval,
+		rate:     rate,
+		states:   newKeyedState[slidingWindow](interval),
+		now:      time.Now,
+	}
+}
+
+func (l *LocalSlidingWindowLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	now := l.now()
+	start := now.Add(-l.interval)
+	return l.states.do(key, now, func(w *slidingWindow) Result {
+		i := 0
+		for i < len(w.times) && !w.times[i].After(start) {
+			i++
+		}
+		w.times = w.times[i:]
+		res := Result{Limit: l.rate}
+		if len(w.times) >= l.rate {
+			res.Limited = true
+			if len(w.times) > 0 {
+				// 最早的请求出了窗口就有额度了
+				res.RetryAfter = w.times[0].Add(l.interval).Sub(now)
+			}
+		} else {
+			w.times = append(w.times, now)
+			res.Remaining = l.rate - len(w.times)
+		}
+		if len(w.times) > 0 {
+			res.Reset = w.times[len(w.times)-1].Add(l.interval).Sub(now)
+		}
+		return res
+	}), nil
+}
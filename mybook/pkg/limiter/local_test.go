@@ -0,0 +1,227 @@
+package limiter
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 测试用的时钟，只有调用 add 的时候才会走
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// step 过了 after 这么久之后来一个请求，期望是不是被限流
+type step struct {
+	after   time.Duration
+	limited bool
+}
+
+func runSteps(t *testing.T, l Limiter, clock *fakeClock, steps []step) {
+	for i, s := range steps {
+		clock.add(s.after)
+		res, err := l.Limit(context.Background(), "ip-limiter:127.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, s.limited, res.Limited, "第 %d 个请求", i)
+	}
+}
+
+func TestLocalFixedWindowLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.UnixMilli(1000)}
+	l := NewLocalFixedWindowLimiter(time.Second, 2).(*LocalFixedWindowLimiter)
+	l.now = clock.Now
+	runSteps(t, l, clock, []step{
+		{limited: false},
+		{after: time.Millisecond * 100, limited: false},
+		{after: time.Millisecond * 100, limited: true},
+		// 新的窗口
+		{after: time.Millisecond * 800, limited: false},
+		{limited: false},
+		{limited: true},
+	})
+}
+
+func TestLocalSlidingWindowLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.UnixMilli(1000)}
+	l := NewLocalSlidingWindowLimiter(time.Second, 2).(*LocalSlidingWindowLimiter)
+	l.now = clock.Now
+	runSteps(t, l, clock, []step{
+		// 同一毫秒的两个请求都要算上
+		{limited: false},
+		{limited: false},
+		{limited: true},
+		// 第一个请求还在窗口里
+		{after: time.Millisecond * 999, limited: true},
+		// 前两个都出去了
+		{after: time.Millisecond, limited: false},
+		{limited: false},
+		{limited: true},
+	})
+}
+
+func TestLocalTokenBucketLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.UnixMilli(1000)}
+	// 每秒 10 个，最多突发 3 个
+	l := NewLocalTokenBucketLimiter(time.Second, 10, 3).(*LocalTokenBucketLimiter)
+	l.now = clock.Now
+	runSteps(t, l, clock, []step{
+		{limited: false},
+		{limited: false},
+		{limited: false},
+		{limited: true},
+		// 100ms 放一个
+		{after: time.Millisecond * 50, limited: true},
+		{after: time.Millisecond * 50, limited: false},
+		{limited: true},
+		// 很久没请求，最多也就 3 个
+		{after: time.Hour, limited: false},
+		{limited: false},
+		{limited: false},
+		{limited: true},
+	})
+}
+
+func TestLocalLeakyBucketLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.UnixMilli(1000)}
+	// 每秒 10 个，也就是 100ms 一个，最多排 2 个
+	l := NewLocalLeakyBucketLimiter(time.Second, 10, 2).(*LocalLeakyBucketLimiter)
+	l.now = clock.Now
+	var waits []time.Duration
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	runSteps(t, l, clock, []step{
+		{limited: false},
+		{limited: false},
+		{limited: false},
+		// 前面已经排了两个
+		{limited: true},
+		{after: time.Millisecond * 100, limited: false},
+	})
+	// 不允许突发，一个一个漏出去
+	assert.Equal(t, []time.Duration{0, time.Millisecond * 100, time.Millisecond * 200, time.Millisecond * 200}, waits)
+}
+
+func TestLocalLimiter_Result(t *testing.T) {
+	clock := &fakeClock{now: time.UnixMilli(1000)}
+	fixed := NewLocalFixedWindowLimiter(time.Second, 2).(*LocalFixedWindowLimiter)
+	fixed.now = clock.Now
+	token := NewLocalTokenBucketLimiter(time.Second, 10, 2).(*LocalTokenBucketLimiter)
+	token.now = clock.Now
+	leaky := NewLocalLeakyBucketLimiter(time.Second, 10, 1).(*LocalLeakyBucketLimiter)
+	leaky.now = clock.Now
+	leaky.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	testCases := []struct {
+		name  string
+		l     Limiter
+		wants []Result
+	}{
+		{
+			name: "固定窗口",
+			l:    fixed,
+			wants: []Result{
+				{Limit: 2, Remaining: 1, Reset: time.Second},
+				{Limit: 2, Remaining: 0, Reset: time.Second},
+				{Limited: true, Limit: 2, RetryAfter: time.Second, Reset: time.Second},
+			},
+		},
+		{
+			name: "令牌桶",
+			l:    token,
+			wants: []Result{
+				{Limit: 2, Remaining: 1, Reset: time.Millisecond * 100},
+				{Limit: 2, Remaining: 0, Reset: time.Millisecond * 200},
+				{Limited: true, Limit: 2, RetryAfter: time.Millisecond * 100, Reset: time.Millisecond * 200},
+			},
+		},
+		{
+			name: "漏桶",
+			l:    leaky,
+			wants: []Result{
+				{Limit: 2, Remaining: 1, Reset: time.Millisecond * 100},
+				{Limit: 2, Remaining: 0, Reset: time.Millisecond * 200},
+				{Limited: true, Limit: 2, RetryAfter: time.Millisecond * 100, Reset: time.Millisecond * 200},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i, want := range tc.wants {
+				res, err := tc.l.Limit(context.Background(), tc.name)
+				require.NoError(t, err)
+				assert.Equal(t, want, res, "第 %d 个请求", i)
+			}
+		})
+	}
+}
+
+func TestLocalLeakyBucketLimiter_Cancel(t *testing.T) {
+	l := NewLocalLeakyBucketLimiter(time.Second, 1, 1)
+	_, err := l.Limit(context.Background(), "key")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	// 要等一秒，等不到了
+	_, err = l.Limit(ctx, "key")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLocalLimiter_Concurrent(t *testing.T) {
+	limiters := map[string]Limiter{
+		"固定窗口": NewLocalFixedWindowLimiter(time.Hour, 100),
+		"滑动窗口": NewLocalSlidingWindowLimiter(time.Hour, 100),
+		"令牌桶":  NewLocalTokenBucketLimiter(time.Hour, 1, 100),
+	}
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			var (
+				wg     sync.WaitGroup
+				mu     sync.Mutex
+				passed int
+			)
+			for i := 0; i < 300; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					res, err := l.Limit(context.Background(), "key")
+					assert.NoError(t, err)
+					if !res.Limited {
+						mu.Lock()
+						passed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, 100, passed)
+		})
+	}
+}
+
+func TestKeyedState_Sweep(t *testing.T) {
+	clock := &fakeClock{now: time.UnixMilli(1000)}
+	l := NewLocalFixedWindowLimiter(time.Second, 1).(*LocalFixedWindowLimiter)
+	l.now = clock.Now
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := l.Limit(context.Background(), key)
+		require.NoError(t, err)
+	}
+	assert.Len(t, l.states.states, 3)
+	clock.add(time.Second)
+	_, err := l.Limit(context.Background(), "d")
+	require.NoError(t, err)
+	// 没用的 key 都清掉了
+	assert.Len(t, l.states.states, 1)
+}
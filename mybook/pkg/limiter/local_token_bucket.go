@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// LocalTokenBucketLimiter 单机的令牌桶
+type LocalTokenBucketLimiter struct {
+	interval time.Duration
+	rate     int
+	capacity int
+	states   *keyedState[tokenBucket]
+	now      func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+func NewLocalTokenBucketLimiter(interval time.Duration, rate, capacity int) Limiter {
+	return &LocalTokenBucketLimiter{
+		interval: interval,
+		rate:     rate,
+		capacity: capacity,
+		// 这么久没请求，桶肯定是满的
+		states: newKeyedState[tokenBucket](fillTime(capacity, rate, interval)),
+		now:    time.Now,
+	}
+}
+
+func (l *LocalTokenBucketLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	now := l.now()
+	capacity := float64(l.capacity)
+	return l.states.do(key, now, func(b *tokenBucket) Result {
+		if b.ts.IsZero() {
+			b.tokens, b.ts = capacity, now
+		}
+		if now.After(b.ts) {
+			b.tokens += float64(now.Sub(b.ts)) / float64(l.interval) * float64(l.rate)
+			if b.tokens > capacity {
+				b.tokens = capacity
+			}
+			b.ts = now
+		}
+		res := Result{Limit: l.capacity}
+		if b.tokens < 1 {
+			res.Limited = true
+			// 等到攒够一个令牌
+			res.RetryAfter = l.tokensTime(1 - b.tokens)
+		} else {
+			b.tokens--
+		}
+		res.Remaining = int(b.tokens)
+		res.Reset = l.tokensTime(capacity - b.tokens)
+		return res
+	}), nil
+}
+
+// tokensTime 攒够 n 个令牌要多久
+func (l *LocalTokenBucketLimiter) tokensTime(n float64) time.Duration {
+	return time.Duration(n / float64(l.rate) * float64(l.interval))
+}
+
+// fillTime 空桶放满或者满桶漏空要多久
+func fillTime(capacity, rate int, interval time.Duration) time.Duration {
+	d := interval * time.Duration(capacity) / time.Duration(rate)
+	if d < interval {
+		return interval
+	}
+	return d
+}
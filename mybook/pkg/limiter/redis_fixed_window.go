@@ -0,0 +1,34 @@
+package limiter
+
+import (
+	"context"
+	_ "embed"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed fixed_window.lua
+var luaFixedWindow string
+
+// RedisFixedWindowLimiter 固定窗口，窗口从第一个请求开始算
+// 实现最简单，但是两个窗口交界的地方可能会放过两倍的请求
+type RedisFixedWindowLimiter struct {
+	cmd      redis.Cmdable
+	interval time.Duration
+	// 阈值
+	rate int
+}
+
+func NewRedisFixedWindowLimiter(cmd redis.Cmdable, interval time.Duration, rate int) Limiter {
+	return &RedisFixedWindowLimiter{
+		cmd:      cmd,
+		interval: interval,
+		rate:     rate,
+	}
+}
+
+func (r *RedisFixedWindowLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	res, _, err := evalResult(r.cmd.Eval(ctx, luaFixedWindow, []string{key},
+		r.interval.Milliseconds(), r.rate), r.rate)
+	return res, err
+}
@@ -0,0 +1,62 @@
+package limiter
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed leaky_bucket.lua
+var luaLeakyBucket string
+
+// RedisLeakyBucketLimiter 漏桶，每个 interval 漏出去 rate 个请求，请求之间的间隔是固定的
+// 和令牌桶不一样，它不允许突发：排不上的请求在 Limit 里面等，最多排 capacity 个，再多就限流
+// 所以 Limit 最多会阻塞 capacity * interval / rate 这么久
+type RedisLeakyBucketLimiter struct {
+	cmd      redis.Cmdable
+	interval time.Duration
+	rate     int
+	capacity int
+	now      func() time.Time
+}
+
+func NewRedisLeakyBucketLimiter(cmd redis.Cmdable, interval time.Duration, rate, capacity int) Limiter {
+	return &RedisLeakyBucketLimiter{
+		cmd:      cmd,
+		interval: interval,
+		rate:     rate,
+		capacity: capacity,
+		now:      time.Now,
+	}
+}
+
+func (r *RedisLeakyBucketLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	gap := float64(r.interval.Milliseconds()) / float64(r.rate)
+	// 正在漏的那个加上排队的
+	res, nums, err := evalResult(r.cmd.Eval(ctx, luaLeakyBucket, []string{key},
+		r.capacity, gap, r.now().UnixMilli()), r.capacity+1)
+	if err != nil || res.Limited {
+		return res, err
+	}
+	if len(nums) < 5 {
+		return Result{}, fmt.Errorf("limiter: 漏桶脚本没有返回等待时间 %v", nums)
+	}
+	return res, sleep(ctx, time.Duration(nums[4])*time.Millisecond)
+}
+
+// sleep 等到轮到这个请求，请求被取消了就不等了
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,37 @@
+package limiter
+
+import (
+	"context"
+	_ "embed"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed slide_window.lua
+var luaSlideWindow string
+
+// RedisSlidingWindowLimiter 滑动窗口，用 ZSET 记下窗口里每个请求的时间
+// 最准，但是每个放行的请求都要占一个 member
+type RedisSlidingWindowLimiter struct {
+	cmd      redis.Cmdable
+	interval time.Duration
+	// 阈值
+	rate int
+	now  func() time.Time
+}
+
+func NewRedisSlidingWindowLimiter(cmd redis.Cmdable, interval time.Duration, rate int) Limiter {
+	return &RedisSlidingWindowLimiter{
+		cmd:      cmd,
+		interval: interval,
+		rate:     rate,
+		now:      time.Now,
+	}
+}
+
+func (r *RedisSlidingWindowLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	res, _, err := evalResult(r.cmd.Eval(ctx, luaSlideWindow, []string{key},
+		r.interval.Milliseconds(), r.rate, r.now().UnixMilli(), uuid.New().String()), r.rate)
+	return res, err
+}
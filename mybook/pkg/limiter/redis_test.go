@@ -0,0 +1,142 @@
+package limiter
+
+import (
+	"basic-go/mybook/internal/repository/cache/redismocks"
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func cmdResult(val any, err error) *redis.Cmd {
+	res := redis.NewCmd(context.Background())
+	if err != nil {
+		res.SetErr(err)
+	} else {
+		res.SetVal(val)
+	}
+	return res
+}
+
+func TestRedisLimiter(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) Limiter
+
+		wantRes Result
+		wantErr error
+	}{
+		{
+			name: "固定窗口，放行",
+			mock: func(ctrl *gomock.Controller) Limiter {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaFixedWindow, []string{"ip-limiter:127.0.0.1"},
+					int64(1000), 100).Return(cmdResult([]any{int64(0), int64(99), int64(0), int64(600)}, nil))
+				return NewRedisFixedWindowLimiter(cmd, time.Second, 100)
+			},
+			wantRes: Result{Limit: 100, Remaining: 99, Reset: time.Millisecond * 600},
+		},
+		{
+			name: "滑动窗口，限流",
+			mock: func(ctrl *gomock.Controller) Limiter {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaSlideWindow, []string{"ip-limiter:127.0.0.1"},
+					int64(1000), 100, now.UnixMilli(), gomock.Any()).Return(cmdResult([]any{int64(1), int64(0), int64(300), int64(1000)}, nil))
+				l := NewRedisSlidingWindowLimiter(cmd, time.Second, 100).(*RedisSlidingWindowLimiter)
+				l.now = func() time.Time { return now }
+				return l
+			},
+			wantRes: Result{Limited: true, Limit: 100, RetryAfter: time.Millisecond * 300, Reset: time.Second},
+		},
+		{
+			name: "令牌桶，每毫秒放 0.1 个",
+			mock: func(ctrl *gomock.Controller) Limiter {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaTokenBucket, []string{"ip-limiter:127.0.0.1"},
+					20, 0.1, now.UnixMilli()).Return(cmdResult([]any{int64(0), int64(19), int64(0), int64(10)}, nil))
+				l := NewRedisTokenBucketLimiter(cmd, time.Second, 100, 20).(*RedisTokenBucketLimiter)
+				l.now = func() time.Time { return now }
+				return l
+			},
+			wantRes: Result{Limit: 20, Remaining: 19, Reset: time.Millisecond * 10},
+		},
+		{
+			name: "漏桶，排满了",
+			mock: func(ctrl *gomock.Controller) Limiter {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaLeakyBucket, []string{"ip-limiter:127.0.0.1"},
+					5, 10.0, now.UnixMilli()).Return(cmdResult([]any{int64(1), int64(0), int64(5), int64(55), int64(0)}, nil))
+				l := NewRedisLeakyBucketLimiter(cmd, time.Second, 100, 5).(*RedisLeakyBucketLimiter)
+				l.now = func() time.Time { return now }
+				return l
+			},
+			wantRes: Result{Limited: true, Limit: 6, RetryAfter: time.Millisecond * 5, Reset: time.Millisecond * 55},
+		},
+		{
+			name: "漏桶，排上了",
+			mock: func(ctrl *gomock.Controller) Limiter {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaLeakyBucket, []string{"ip-limiter:127.0.0.1"},
+					5, 10.0, now.UnixMilli()).Return(cmdResult([]any{int64(0), int64(4), int64(0), int64(20), int64(0)}, nil))
+				l := NewRedisLeakyBucketLimiter(cmd, time.Second, 100, 5).(*RedisLeakyBucketLimiter)
+				l.now = func() time.Time { return now }
+				return l
+			},
+			wantRes: Result{Limit: 6, Remaining: 4, Reset: time.Millisecond * 20},
+		},
+		{
+			name: "Redis 出错",
+			mock: func(ctrl *gomock.Controller) Limiter {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaFixedWindow, []string{"ip-limiter:127.0.0.1"},
+					int64(1000), 100).Return(cmdResult(nil, errors.New("mock redis error")))
+				return NewRedisFixedWindowLimiter(cmd, time.Second, 100)
+			},
+			wantErr: errors.New("mock redis error"),
+		},
+		{
+			name: "脚本返回值不对",
+			mock: func(ctrl *gomock.Controller) Limiter {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaFixedWindow, []string{"ip-limiter:127.0.0.1"},
+					int64(1000), 100).Return(cmdResult("false", nil))
+				return NewRedisFixedWindowLimiter(cmd, time.Second, 100)
+			},
+			wantErr: errors.New("limiter: 脚本返回值不对 false"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			res, err := tc.mock(ctrl).Limit(context.Background(), "ip-limiter:127.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+// 同一毫秒的请求 member 不能一样，不然会被当成一个
+func TestRedisSlidingWindowLimiter_UniqueMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismocks.NewMockCmdable(ctrl)
+	members := map[any]bool{}
+	cmd.EXPECT().Eval(gomock.Any(), luaSlideWindow, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd {
+			members[args[3]] = true
+			return cmdResult([]any{int64(0), int64(99), int64(0), int64(1000)}, nil)
+		}).Times(3)
+	l := NewRedisSlidingWindowLimiter(cmd, time.Second, 100).(*RedisSlidingWindowLimiter)
+	now := time.UnixMilli(1700000000000)
+	l.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		_, err := l.Limit(context.Background(), "key")
+		assert.NoError(t, err)
+	}
+	assert.Len(t, members, 3)
+}
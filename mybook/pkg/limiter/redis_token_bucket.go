@@ -0,0 +1,42 @@
+package limiter
+
+import (
+	"context"
+	_ "embed"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed token_bucket.lua
+var luaTokenBucket string
+
+// RedisTokenBucketLimiter 令牌桶，每个 interval 放 rate 个令牌，桶最多装 capacity 个
+// 桶是满的时候允许 capacity 个请求的突发
+type RedisTokenBucketLimiter struct {
+	cmd      redis.Cmdable
+	interval time.Duration
+	rate     int
+	capacity int
+	now      func() time.Time
+}
+
+func NewRedisTokenBucketLimiter(cmd redis.Cmdable, interval time.Duration, rate, capacity int) Limiter {
+	return &RedisTokenBucketLimiter{
+		cmd:      cmd,
+		interval: interval,
+		rate:     rate,
+		capacity: capacity,
+		now:      time.Now,
+	}
+}
+
+func (r *RedisTokenBucketLimiter) Limit(ctx context.Context, key string) (Result, error) {
+	res, _, err := evalResult(r.cmd.Eval(ctx, luaTokenBucket, []string{key},
+		r.capacity, perMilli(r.rate, r.interval), r.now().UnixMilli()), r.capacity)
+	return res, err
+}
+
+// perMilli 每毫秒多少个
+func perMilli(rate int, interval time.Duration) float64 {
+	return float64(rate) / float64(interval.Milliseconds())
+}
@@ -0,0 +1,34 @@
+-- 限流对象
+local key = KEYS[1]
+-- 窗口大小，毫秒
+local window = tonumber(ARGV[1])
+-- 阈值
+local threshold = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+-- 每个请求一个唯一的 member，同一毫秒里的请求不会互相覆盖
+local member = ARGV[4]
+-- 窗口的起始时间
+local min = now - window
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', min)
+local cnt = redis.call('ZCARD', key)
+local limited = 1
+if cnt < threshold then
+    limited = 0
+    redis.call('ZADD', key, now, member)
+    redis.call('PEXPIRE', key, window)
+    cnt = cnt + 1
+end
+
+local retry = 0
+local reset = 0
+-- 最早的请求出了窗口就有额度了，最晚的出了窗口额度就满了
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local newest = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+if limited == 1 and oldest[2] then
+    retry = tonumber(oldest[2]) + window - now
+end
+if newest[2] then
+    reset = tonumber(newest[2]) + window - now
+end
+return {limited, threshold - cnt, retry, reset}
@@ -0,0 +1,34 @@
+-- 限流对象
+local key = KEYS[1]
+-- 桶的容量，也就是最多允许多少突发
+local capacity = tonumber(ARGV[1])
+-- 每毫秒放多少个令牌
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+    -- 新的桶是满的
+    tokens = capacity
+    ts = now
+end
+if now > ts then
+    tokens = math.min(capacity, tokens + (now - ts) * rate)
+    ts = now
+end
+
+local limited = 1
+local retry = 0
+if tokens >= 1 then
+    limited = 0
+    tokens = tokens - 1
+else
+    -- 等到攒够一个令牌
+    retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', key, 'tokens', tokens, 'ts', ts)
+-- 过了这么久桶肯定是满的，和没有这个 key 一样
+redis.call('PEXPIRE', key, math.ceil(capacity / rate))
+return {limited, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
@@ -0,0 +1,60 @@
+// Package limiter 限流算法，Redis 的实现给多实例用，Local 的给单机和测试用
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Limiter 限流器
+type Limiter interface {
+	// Limit key 是限流对象，例如 IP，Result.Limited 为 true 表示要限流
+	Limit(ctx context.Context, key string) (Result, error)
+}
+
+// Result 除了限不限流，还有额度的信息，用来设置 X-RateLimit-* 响应头
+// Limit 为 0 表示没有额度信息，例如降级之后直接放行
+type Result struct {
+	Limited bool
+	// 窗口的阈值或者桶的容量
+	Limit int
+	// 还剩多少额度
+	Remaining int
+	// 被限流的时候，多久之后可以再试
+	RetryAfter time.Duration
+	// 多久之后额度恢复满
+	Reset time.Duration
+}
+
+// evalResult Lua 脚本统一返回 {limited, remaining, retry_ms, reset_ms, ...}
+func evalResult(cmd *redis.Cmd, limit int) (Result, []int64, error) {
+	val, err := cmd.Result()
+	if err != nil {
+		return Result{}, nil, err
+	}
+	arr, ok := val.([]any)
+	if !ok || len(arr) < 4 {
+		return Result{}, nil, fmt.Errorf("limiter: 脚本返回值不对 %v", val)
+	}
+	nums := make([]int64, len(arr))
+	for i, v := range arr {
+		n, ok := v.(int64)
+		if !ok {
+			return Result{}, nil, fmt.Errorf("limiter: 脚本返回值不对 %v", val)
+		}
+		nums[i] = n
+	}
+	remaining := int(nums[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{
+		Limited:    nums[0] == 1,
+		Limit:      limit,
+		Remaining:  remaining,
+		RetryAfter: time.Duration(nums[2]) * time.Millisecond,
+		Reset:      time.Duration(nums[3]) * time.Millisecond,
+	}, nums, nil
+}
@@ -0,0 +1,21 @@
+package logger
+
+import "context"
+
+type contextKey struct{}
+
+// ContextWithFields 在 ctx 已有的字段后面追加，WithContext 的时候会带上
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	old := FieldsFromContext(ctx)
+	res := make([]Field, 0, len(old)+len(fields))
+	res = append(append(res, old...), fields...)
+	return context.WithValue(ctx, contextKey{}, res)
+}
+
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fs, _ := ctx.Value(contextKey{}).([]Field)
+	return fs
+}
@@ -0,0 +1,26 @@
+package logger
+
+import "context"
+
+// NopLogger 什么都不打，测试和没有注入 Logger 的地方用
+type NopLogger struct{}
+
+func NewNopLogger() Logger {
+	return NopLogger{}
+}
+
+func (NopLogger) Debug(string, ...Field) {}
+
+func (NopLogger) Info(string, ...Field) {}
+
+func (NopLogger) Warn(string, ...Field) {}
+
+func (NopLogger) Error(string, ...Field) {}
+
+func (n NopLogger) With(...Field) Logger {
+	return n
+}
+
+func (n NopLogger) WithContext(context.Context) Logger {
+	return n
+}
@@ -0,0 +1,49 @@
+package logger
+
+import "strings"
+
+const redacted = "***"
+
+// sensitiveWords key 里包含这些词的都要脱敏，不区分大小写
+var sensitiveWords = []string{"password", "token", "secret", "authorization", "cookie"}
+
+// sensitiveKeys 只有完全一样才脱敏，code 这种词太常见了，不能按包含算
+var sensitiveKeys = map[string]bool{
+	"code":     true,
+	"sms_code": true,
+	"smscode":  true,
+}
+
+// IsSensitive 访问日志记录请求体的时候也用这个判断
+func IsSensitive(key string) bool {
+	k := strings.ToLower(key)
+	if sensitiveKeys[k] {
+		return true
+	}
+	for _, w := range sensitiveWords {
+		if strings.Contains(k, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact 敏感字段整个换掉，手机号只留前三后四
+func Redact(key string, val any) any {
+	if IsSensitive(key) {
+		return redacted
+	}
+	if strings.EqualFold(key, "phone") {
+		if s, ok := val.(string); ok {
+			return MaskPhone(s)
+		}
+	}
+	return val
+}
+
+func MaskPhone(phone string) string {
+	if len(phone) < 7 {
+		return redacted
+	}
+	return phone[:3] + "****" + phone[len(phone)-4:]
+}
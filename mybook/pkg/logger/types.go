@@ -0,0 +1,53 @@
+// Package logger 统一的日志接口，业务代码只依赖这里，具体用 zap 还是别的在 ioc 里决定
+package logger
+
+import (
+	"context"
+	"time"
+)
+
+type Logger interface {
+	Debug(msg string, args ...Field)
+	Info(msg string, args ...Field)
+	Warn(msg string, args ...Field)
+	Error(msg string, args ...Field)
+	// With 返回的 Logger 每条日志都会带上 args
+	With(args ...Field) Logger
+	// WithContext 带上 context 里的 request_id、uid 之类的字段，见 ContextWithFields
+	WithContext(ctx context.Context) Logger
+}
+
+// Field Key 命中 IsSensitive 的会被脱敏
+type Field struct {
+	Key   string
+	Value any
+}
+
+func String(key, val string) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Int(key string, val int) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Bool(key string, val bool) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Error(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Any 不要直接传 domain.User 这种带密码的结构体
+func Any(key string, val any) Field {
+	return Field{Key: key, Value: val}
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"go.uber.org/zap"
+)
+
+type ZapLogger struct {
+	l *zap.Logger
+}
+
+func NewZapLogger(l *zap.Logger) Logger {
+	return &ZapLogger{l: l}
+}
+
+func (z *ZapLogger) Debug(msg string, args ...Field) {
+	z.l.Debug(msg, toZapFields(args)...)
+}
+
+func (z *ZapLogger) Info(msg string, args ...Field) {
+	z.l.Info(msg, toZapFields(args)...)
+}
+
+func (z *ZapLogger) Warn(msg string, args ...Field) {
+	z.l.Warn(msg, toZapFields(args)...)
+}
+
+func (z *ZapLogger) Error(msg string, args ...Field) {
+	z.l.Error(msg, toZapFields(args)...)
+}
+
+func (z *ZapLogger) With(args ...Field) Logger {
+	if len(args) == 0 {
+		return z
+	}
+	return &ZapLogger{l: z.l.With(toZapFields(args)...)}
+}
+
+func (z *ZapLogger) WithContext(ctx context.Context) Logger {
+	return z.With(FieldsFromContext(ctx)...)
+}
+
+func toZapFields(args []Field) []zap.Field {
+	res := make([]zap.Field, 0, len(args))
+	for _, arg := range args {
+		res = append(res, zap.Any(arg.Key, Redact(arg.Key, arg.Value)))
+	}
+	return res
+}
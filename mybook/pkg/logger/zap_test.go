@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+)
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := NewZapLogger(zap.New(core))
+
+	ctx := ContextWithFields(context.Background(), String("request_id", "abc"))
+	ctx = ContextWithFields(ctx, Int64("uid", 123))
+	l.WithContext(ctx).Info("登录成功",
+		String("phone", "13511112222"),
+		String("password", "hello#world123"),
+		String("code", "123456"),
+		String("x-jwt-token", "eyJ..."),
+		Error(errors.New("mock error")))
+	// 级别不够的不打
+	l.Debug("调试")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "登录成功", entries[0].Message)
+	assert.Equal(t, map[string]any{
+		"request_id":  "abc",
+		"uid":         int64(123),
+		"phone":       "135****2222",
+		"password":    "***",
+		"code":        "***",
+		"x-jwt-token": "***",
+		"error":       "mock error",
+	}, entries[0].ContextMap())
+}
+
+func TestIsSensitive(t *testing.T) {
+	for _, key := range []string{"password", "confirmPassword", "Authorization", "code", "refresh_token", "Cookie"} {
+		assert.True(t, IsSensitive(key), key)
+	}
+	for _, key := range []string{"phone", "email", "errcode", "uid"} {
+		assert.False(t, IsSensitive(key), key)
+	}
+}
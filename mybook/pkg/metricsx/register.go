@@ -0,0 +1,31 @@
+// Package metricsx Prometheus 的一些公共代码
+package metricsx
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Register 注册到默认的 Registerer，同名的已经注册过了就用已经注册的那个
+// 测试里会多次创建同一个 middleware、装饰器，直接 MustRegister 会 panic
+func Register[T prometheus.Collector](c T) T {
+	err := prometheus.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	panic(err)
+}
+
+// Status 把 error 变成 label，不要直接用 err.Error()，不然 label 的值没法控制
+func Status(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return "error"
+}
@@ -0,0 +1,338 @@
+// Package migrator 版本化的 SQL 迁移
+// 迁移文件命名为 {version}_{name}.up.sql / {version}_{name}.down.sql，
+// 按 version 从小到大执行，执行记录保存在 schema_migrations 表里
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLockTimeout = errors.New("获取迁移锁超时，可能有其他实例正在迁移")
+	ErrDirty       = errors.New("存在执行失败的迁移，需要人工修复后再执行")
+)
+
+const (
+	defaultTable       = "schema_migrations"
+	defaultLockName    = "mybook:schema_migrations"
+	defaultLockTimeout = time.Minute
+)
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status 某个版本的执行状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db          *sql.DB
+	fsys        fs.FS
+	table       string
+	lockName    string
+	lockTimeout time.Duration
+}
+
+// New fsys 一般是 embed.FS，迁移文件直接编译进二进制
+func New(db *sql.DB, fsys fs.FS) *Migrator {
+	return &Migrator{
+		db:          db,
+		fsys:        fsys,
+		table:       defaultTable,
+		lockName:    defaultLockName,
+		lockTimeout: defaultLockTimeout,
+	}
+}
+
+func (m *Migrator) LockTimeout(timeout time.Duration) *Migrator {
+	m.lockTimeout = timeout
+	return m
+}
+
+// Up 执行所有还没有执行过的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, st := range applied {
+			if st.Dirty {
+				return fmt.Errorf("%w: version %d", ErrDirty, st.Version)
+			}
+		}
+		for _, mg := range migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if err = m.apply(ctx, conn, mg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		// 倒着回滚
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			mg := migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if err = m.rollback(ctx, conn, mg); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status 返回所有迁移的执行状态，按 version 排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Status, 0, len(migrations))
+	for _, mg := range migrations {
+		st, ok := applied[mg.Version]
+		if !ok {
+			st = Status{Version: mg.Version}
+		}
+		st.Name = mg.Name
+		res = append(res, st)
+	}
+	return res, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mg Migration) error {
+	// MySQL 的 DDL 会隐式提交，没办法放到事务里
+	// 所以先标记 dirty，执行成功之后再清掉
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO `%s` (`version`, `name`, `dirty`, `applied_at`) VALUES (?, ?, ?, ?)", m.table),
+		mg.Version, mg.Name, true, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitStatements(mg.Up) {
+		if _, err = conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("执行迁移 %d_%s 失败: %w", mg.Version, mg.Name, err)
+		}
+	}
+	_, err = conn.ExecContext(ctx, fmt.Sprintf(
+		"UPDATE `%s` SET `dirty` = ? WHERE `version` = ?", m.table), false, mg.Version)
+	return err
+}
+
+func (m *Migrator) rollback(ctx context.Context, conn *sql.Conn, mg Migration) error {
+	if mg.Down == "" {
+		return fmt.Errorf("迁移 %d_%s 没有 down 文件，不能回滚", mg.Version, mg.Name)
+	}
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"UPDATE `%s` SET `dirty` = ? WHERE `version` = ?", m.table), true, mg.Version)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitStatements(mg.Down) {
+		if _, err = conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("回滚迁移 %d_%s 失败: %w", mg.Version, mg.Name, err)
+		}
+	}
+	_, err = conn.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM `%s` WHERE `version` = ?", m.table), mg.Version)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]Status, error) {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` ("+
+		"`version` BIGINT NOT NULL PRIMARY KEY,"+
+		"`name` VARCHAR(255) NOT NULL,"+
+		"`dirty` TINYINT(1) NOT NULL DEFAULT 0,"+
+		"`applied_at` BIGINT NOT NULL)", m.table))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(
+		"SELECT `version`, `name`, `dirty`, `applied_at` FROM `%s`", m.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make(map[int64]Status)
+	for rows.Next() {
+		var (
+			st        Status
+			appliedAt int64
+		)
+		if err = rows.Scan(&st.Version, &st.Name, &st.Dirty, &appliedAt); err != nil {
+			return nil, err
+		}
+		st.Applied = true
+		st.AppliedAt = time.UnixMilli(appliedAt)
+		res[st.Version] = st
+	}
+	return res, rows.Err()
+}
+
+// withLock 用 MySQL 的 GET_LOCK 做 advisory lock，避免多个 pod 同时启动的时候一起迁移
+// GET_LOCK 是连接级别的，所以加锁、迁移、释放锁都要在同一个连接上
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var locked sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)",
+		m.lockName, int(m.lockTimeout.Seconds())).Scan(&locked)
+	if err != nil {
+		return err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	defer func() {
+		// 用一个新的 ctx，避免 ctx 已经取消导致锁释放不掉
+		_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.lockName)
+	}()
+	return fn(conn)
+}
+
+// load 读取并校验所有的迁移文件
+func (m *Migrator) load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version, name, direction, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(m.fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: name}
+			byVersion[version] = mg
+		}
+		if mg.Name != name {
+			return nil, fmt.Errorf("迁移版本 %d 的名字不一致: %s, %s", version, mg.Name, name)
+		}
+		if direction == "up" {
+			mg.Up = string(content)
+		} else {
+			mg.Down = string(content)
+		}
+	}
+	res := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" {
+			return nil, fmt.Errorf("迁移 %d_%s 缺少 up 文件", mg.Version, mg.Name)
+		}
+		res = append(res, *mg)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	return res, nil
+}
+
+// parseFileName 0001_create_users.up.sql => 1, create_users, up
+func parseFileName(fileName string) (int64, string, string, error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+	var direction string
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("非法的迁移文件名 %s", fileName)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+	versionStr, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", "", fmt.Errorf("非法的迁移文件名 %s", fileName)
+	}
+	version, err := strconv.ParseInt(versionStr, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("非法的迁移文件名 %s", fileName)
+	}
+	return version, name, direction, nil
+}
+
+// splitStatements 按行尾的分号切分语句，忽略 -- 开头的注释行
+// 这样就不需要在 DSN 上打开 multiStatements
+func splitStatements(content string) []string {
+	var (
+		res []string
+		sb  strings.Builder
+	)
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			res = append(res, strings.TrimSuffix(strings.TrimSpace(sb.String()), ";"))
+			sb.Reset()
+		}
+	}
+	if last := strings.TrimSpace(sb.String()); last != "" {
+		res = append(res, last)
+	}
+	return res
+}
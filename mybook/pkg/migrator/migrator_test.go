@@ -0,0 +1,121 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrator_Up(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users(id BIGINT);")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"0002_add_status.up.sql": {Data: []byte(`-- 加个字段
+ALTER TABLE users ADD COLUMN status TINYINT;
+CREATE INDEX idx_status ON users (status);`)},
+	}
+	testCases := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		wantErr error
+	}{
+		{
+			name: "只执行没执行过的迁移",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT GET_LOCK").
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+				mock.ExpectExec("CREATE TABLE IF NOT EXISTS `schema_migrations`").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT `version`, `name`, `dirty`, `applied_at` FROM `schema_migrations`").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "name", "dirty", "applied_at"}).
+						AddRow(1, "create_users", false, 1000))
+				mock.ExpectExec("INSERT INTO `schema_migrations`").
+					WithArgs(int64(2), "add_status", true, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("ALTER TABLE users ADD COLUMN status TINYINT").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE INDEX idx_status ON users").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE `schema_migrations` SET `dirty`").
+					WithArgs(false, int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("SELECT RELEASE_LOCK").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return db
+			},
+		},
+		{
+			name: "拿不到锁",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT GET_LOCK").
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+				return db
+			},
+			wantErr: ErrLockTimeout,
+		},
+		{
+			name: "存在 dirty 的迁移",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT GET_LOCK").
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+				mock.ExpectExec("CREATE TABLE IF NOT EXISTS `schema_migrations`").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT `version`, `name`, `dirty`, `applied_at` FROM `schema_migrations`").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "name", "dirty", "applied_at"}).
+						AddRow(1, "create_users", true, 1000))
+				mock.ExpectExec("SELECT RELEASE_LOCK").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return db
+			},
+			wantErr: ErrDirty,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(tc.mock(t), fsys)
+			err := m.Up(context.Background())
+			assert.True(t, errors.Is(err, tc.wantErr), err)
+		})
+	}
+}
+
+func TestParseFileName(t *testing.T) {
+	version, name, direction, err := parseFileName("0003_add_user_role.down.sql")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+	assert.Equal(t, "add_user_role", name)
+	assert.Equal(t, "down", direction)
+
+	_, _, _, err = parseFileName("add_user_role.up.sql")
+	assert.Error(t, err)
+	_, _, _, err = parseFileName("0003_add_user_role.sql")
+	assert.Error(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	stmts := splitStatements(`-- 注释
+CREATE TABLE a
+(
+    id BIGINT
+);
+
+ALTER TABLE a ADD COLUMN b INT;
+DROP TABLE c`)
+	assert.Equal(t, []string{
+		"CREATE TABLE a\n(\n    id BIGINT\n)",
+		"ALTER TABLE a ADD COLUMN b INT",
+		"DROP TABLE c",
+	}, stmts)
+}
@@ -0,0 +1,61 @@
+// Package redisx go-redis 的 hook
+package redisx
+
+import (
+	"basic-go/mybook/pkg/metricsx"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"time"
+)
+
+// MetricsHook 统计每个命令的耗时，pipeline 按 pipeline 算一次
+type MetricsHook struct {
+	vector *prometheus.HistogramVec
+}
+
+func NewMetricsHook(namespace, subsystem string) *MetricsHook {
+	return &MetricsHook{
+		vector: metricsx.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Redis 命令的耗时",
+			Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5},
+		}, []string{"cmd", "status"})),
+	}
+}
+
+func (h *MetricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *MetricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.vector.WithLabelValues(cmd.Name(), status(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (h *MetricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.vector.WithLabelValues("pipeline", status(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// status key 不存在不算出错
+func status(err error) string {
+	if errors.Is(err, redis.Nil) {
+		return "nil"
+	}
+	return metricsx.Status(err)
+}
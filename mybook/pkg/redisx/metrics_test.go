@@ -0,0 +1,43 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMetricsHook(t *testing.T) {
+	h := NewMetricsHook("test", "redis")
+	testCases := []struct {
+		name    string
+		cmd     redis.Cmder
+		err     error
+		wantCmd string
+		status  string
+	}{
+		{name: "成功", cmd: redis.NewStringCmd(context.Background(), "get", "key"), wantCmd: "get", status: "ok"},
+		{name: "key 不存在", cmd: redis.NewStringCmd(context.Background(), "get", "key"), err: redis.Nil, wantCmd: "get", status: "nil"},
+		{name: "出错", cmd: redis.NewCmd(context.Background(), "evalsha", "sha", 1, "key"), err: errors.New("mock error"), wantCmd: "evalsha", status: "error"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			process := h.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+				return tc.err
+			})
+			err := process(context.Background(), tc.cmd)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, uint64(1), sampleCount(t, h.vector, tc.wantCmd, tc.status))
+		})
+	}
+}
+
+func sampleCount(t *testing.T, vector *prometheus.HistogramVec, lvs ...string) uint64 {
+	var m dto.Metric
+	require.NoError(t, vector.WithLabelValues(lvs...).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
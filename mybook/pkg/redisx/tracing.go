@@ -0,0 +1,96 @@
+package redisx
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net"
+	"strings"
+)
+
+const instrumentationName = "basic-go/mybook/pkg/redisx"
+
+// TracingHook 每个命令一个 span
+// key 和参数里面有手机号、验证码，只记命令，不记参数
+type TracingHook struct {
+	tracer trace.Tracer
+}
+
+func NewTracingHook(tp trace.TracerProvider) *TracingHook {
+	return &TracingHook{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (h *TracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *TracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		attrs := []attribute.KeyValue{semconv.DBSystemRedis, semconv.DBOperation(cmd.Name())}
+		// 验证码的 Lua 脚本走的是 eval，记下脚本的 sha 才知道是哪个脚本
+		if sha := scriptSha(cmd); sha != "" {
+			attrs = append(attrs, attribute.String("db.redis.script_sha", sha))
+		}
+		ctx, span := h.tracer.Start(ctx, "redis "+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		defer span.End()
+		err := next(ctx, cmd)
+		recordError(span, err)
+		return err
+	}
+}
+
+func (h *TracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+		}
+		ctx, span := h.tracer.Start(ctx, "redis pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis,
+				semconv.DBOperation(strings.Join(names, " ")),
+				attribute.Int("db.redis.num_cmd", len(cmds))))
+		defer span.End()
+		err := next(ctx, cmds)
+		recordError(span, err)
+		return err
+	}
+}
+
+func scriptSha(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return ""
+	}
+	switch cmd.Name() {
+	case "eval", "eval_ro":
+		script, ok := args[1].(string)
+		if !ok {
+			return ""
+		}
+		sum := sha1.Sum([]byte(script))
+		return hex.EncodeToString(sum[:])
+	case "evalsha", "evalsha_ro":
+		sha, _ := args[1].(string)
+		return sha
+	}
+	return ""
+}
+
+// recordError key 不存在不算出错
+func recordError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
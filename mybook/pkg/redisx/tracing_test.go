@@ -0,0 +1,80 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestTracingHook_ProcessHook(t *testing.T) {
+	testCases := []struct {
+		name string
+		cmd  redis.Cmder
+		err  error
+
+		wantName   string
+		wantStatus codes.Code
+		wantSha    string
+	}{
+		{
+			name:     "成功",
+			cmd:      redis.NewStringCmd(context.Background(), "get", "user:info:1"),
+			wantName: "redis get",
+		},
+		{
+			name:     "key 不存在不算出错",
+			cmd:      redis.NewStringCmd(context.Background(), "get", "user:info:1"),
+			err:      redis.Nil,
+			wantName: "redis get",
+		},
+		{
+			name: "Lua 脚本",
+			cmd:  redis.NewCmd(context.Background(), "eval", "return 1", 1, "phoneCode:login:13511112222"),
+			// echo -n "return 1" | sha1sum
+			wantName: "redis eval",
+			wantSha:  "e0e1f9fabfc9d4800c877a703b823ac0578ff8db",
+		},
+		{
+			name:       "出错",
+			cmd:        redis.NewCmd(context.Background(), "evalsha", "abc", 1, "key"),
+			err:        errors.New("mock error"),
+			wantName:   "redis evalsha",
+			wantStatus: codes.Error,
+			wantSha:    "abc",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			process := NewTracingHook(tp).ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+				return tc.err
+			})
+			ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+			err := process(ctx, tc.cmd)
+			parent.End()
+			assert.Equal(t, tc.err, err)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 2)
+			span := spans[0]
+			assert.Equal(t, tc.wantName, span.Name())
+			assert.Equal(t, tc.wantStatus, span.Status().Code)
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+			if tc.wantSha != "" {
+				assert.Contains(t, span.Attributes(), attribute.String("db.redis.script_sha", tc.wantSha))
+			}
+			// 不能把 key 记下来
+			for _, attr := range span.Attributes() {
+				assert.NotContains(t, attr.Value.Emit(), "13511112222")
+			}
+		})
+	}
+}
@@ -1 +1,3 @@
-create database webook
\ No newline at end of file
+create database webook;
+-- 表结构不在这里维护，启动的时候会自动执行 internal/repository/dao/migrations 里面的迁移
+-- 也可以手动执行 mybook migrate up
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"basic-go/mybook/internal/domain"
+	"basic-go/mybook/internal/service"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// exportCommand mybook export -format csv -out users.csv -status 0
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", service.FormatCSV, "csv 或者 jsonl")
+	out := fs.String("out", "", "输出文件，不传就输出到标准输出")
+	status := fs.String("status", "", "只导出这些状态的用户，逗号分隔，例如 0,3")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var filter domain.UserFilter
+	if *status != "" {
+		for _, s := range strings.Split(*status, ",") {
+			st, err := strconv.ParseUint(strings.TrimSpace(s), 10, 8)
+			if err != nil {
+				return fmt.Errorf("非法的状态 %s", s)
+			}
+			filter.Statuses = append(filter.Statuses, domain.UserStatus(st))
+		}
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	cnt, err := InitUserTransferService().Export(context.Background(), w, *format, filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "导出了 %d 个用户\n", cnt)
+	return nil
+}
+
+// importCommand mybook import -format jsonl -in users.jsonl
+// 导入结果（包括每一行的失败原因）以 JSON 输出到标准输出
+func importCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", service.FormatCSV, "csv 或者 jsonl")
+	in := fs.String("in", "", "输入文件，不传就从标准输入读")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	res, err := InitUserTransferService().Import(context.Background(), r, *format)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if er := enc.Encode(res); er != nil {
+		return er
+	}
+	return err
+}
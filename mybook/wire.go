@@ -9,31 +9,54 @@ import (
 	"basic-go/mybook/internal/service"
 	"basic-go/mybook/internal/web"
 	"basic-go/mybook/ioc"
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 )
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
 	wire.Build(
 		//最基础的第三方依赖
-		InitDB, ioc.InitRedis,
+		InitDB, ioc.InitRedis, ioc.InitLogger, ioc.InitTracerProvider,
 		//初始化 dao
 		dao.NewUserDao,
-		cache.NewUserCache,
+		ioc.InitUserCache,
 		//cache.NewCodeCache,
 		cache.NewLocalCodeCache,
 
-		repository.NewUserRepository,
+		ioc.InitUserRepository,
 		repository.NewCodeRepository,
 
-		service.NewUserService,
-		service.NewCodeService,
+		ioc.InitUserService,
+		ioc.InitCodeService,
+		service.NewAvatarService,
 		//基于内存实现
 		ioc.InitSMSService,
+		ioc.InitObjectStorage,
+		ioc.InitSessionStore,
+		ioc.InitTokenHandler,
 		web.NewUserHandler,
+		web.NewAdminUserHandler,
+		web.NewAdminSystemHandler,
 		//
+		ioc.InitHealth,
+		ioc.InitRecovery,
 		ioc.InitGin,
 		ioc.InitMiddleware,
+		ioc.InitAccessLog,
+		ioc.InitJobs,
+
+		wire.Struct(new(App), "*"),
+	)
+	return new(App)
+}
+
+// InitUserTransferService 给 export 和 import 子命令用，不需要启动 web 服务
+func InitUserTransferService() service.UserTransferServicePackage {
+	wire.Build(
+		InitDB, ioc.InitRedis, ioc.InitLogger,
+		dao.NewUserDao,
+		ioc.InitUserCache,
+		ioc.InitUserRepository,
+		service.NewUserTransferService,
 	)
-	return new(gin.Engine)
+	return new(service.UserTransferService)
 }
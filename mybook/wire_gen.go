@@ -13,24 +13,56 @@ import (
 	"basic-go/mybook/internal/service"
 	"basic-go/mybook/internal/web"
 	"basic-go/mybook/ioc"
-	"github.com/gin-gonic/gin"
 )
 
 // Injectors from wire.go:
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
+	logger := ioc.InitLogger()
+	builder := ioc.InitRecovery(logger)
 	cmdable := ioc.InitRedis()
-	v := ioc.InitMiddleware(cmdable)
 	db := InitDB()
+	store := ioc.InitSessionStore(db)
 	userDAO := dao.NewUserDao(db)
-	userCache := cache.NewUserCache(cmdable)
-	userRepository := repository.NewUserRepository(userDAO, userCache)
-	userServicePackage := service.NewUserService(userRepository)
+	userCache := ioc.InitUserCache(cmdable)
+	userRepository := ioc.InitUserRepository(userDAO, userCache, logger)
+	userServicePackage := ioc.InitUserService(userRepository, logger)
+	handler := ioc.InitTokenHandler(cmdable, store, userServicePackage, logger)
+	accesslogBuilder := ioc.InitAccessLog(logger)
+	v := ioc.InitMiddleware(cmdable, handler, logger, accesslogBuilder, builder)
+	healthHandler := ioc.InitHealth(db, cmdable, logger)
 	codeCache := cache.NewLocalCodeCache()
 	codeRepository := repository.NewCodeRepository(codeCache)
-	smsService := ioc.InitSMSService()
-	codeServicePackage := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userServicePackage, codeServicePackage)
-	engine := ioc.InitGin(v, userHandler)
-	return engine
+	smsService := ioc.InitSMSService(logger)
+	codeServicePackage := ioc.InitCodeService(codeRepository, smsService, logger)
+	objectStorage := ioc.InitObjectStorage()
+	avatarServicePackage := service.NewAvatarService(userRepository, objectStorage, logger)
+	userHandler := web.NewUserHandler(userServicePackage, codeServicePackage, avatarServicePackage, handler, logger)
+	adminUserHandler := web.NewAdminUserHandler(userServicePackage, logger)
+	adminSystemHandler := web.NewAdminSystemHandler(accesslogBuilder, logger)
+	engine := ioc.InitGin(builder, v, healthHandler, userHandler, adminUserHandler, adminSystemHandler, objectStorage, handler)
+	v2 := ioc.InitJobs(userServicePackage, store, logger)
+	tracerProvider := ioc.InitTracerProvider(logger)
+	app := &App{
+		Server:         engine,
+		Health:         healthHandler,
+		Jobs:           v2,
+		TracerProvider: tracerProvider,
+		DB:             db,
+		Redis:          cmdable,
+		L:              logger,
+	}
+	return app
+}
+
+// InitUserTransferService 给 export 和 import 子命令用，不需要启动 web 服务
+func InitUserTransferService() service.UserTransferServicePackage {
+	db := InitDB()
+	userDAO := dao.NewUserDao(db)
+	cmdable := ioc.InitRedis()
+	userCache := ioc.InitUserCache(cmdable)
+	logger := ioc.InitLogger()
+	userRepository := ioc.InitUserRepository(userDAO, userCache, logger)
+	userTransferServicePackage := service.NewUserTransferService(userRepository)
+	return userTransferServicePackage
 }